@@ -3,14 +3,17 @@ package awssmfs
 import (
 	"bytes"
 	"context"
+	"crypto/tls"
 	"errors"
 	"fmt"
 	"io"
 	"io/fs"
 	"net/http"
 	"net/url"
+	"os"
 	"path"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 
@@ -33,6 +36,12 @@ type withSMClienter interface {
 // filesystem supports it (i.e. has a WithSMClient method). This can be used for
 // configuring specialized client options.
 //
+// Clients with a BatchGetSecretValue method (such as [*secretsmanager.Client])
+// are used to read directories' secrets in batches, and clients with a
+// ListSecretVersionIds method are needed to list secrets' versions. Clients
+// with a DescribeSecret method are used to read a single secret's metadata
+// when filters are set.
+//
 // Note that this should not be used together with WithHTTPClient. If you wish
 // only to override the HTTP client, use WithHTTPClient alone.
 func WithSMClientFS(smclient SecretsManagerClient, fsys fs.FS) fs.FS {
@@ -43,13 +52,69 @@ func WithSMClientFS(smclient SecretsManagerClient, fsys fs.FS) fs.FS {
 	return fsys
 }
 
+// withMaxConcurrencyer is an fs.FS that can be configured with a concurrency limit.
+type withMaxConcurrencyer interface {
+	WithMaxConcurrency(n int) fs.FS
+}
+
+// WithMaxConcurrencyFS sets the maximum number of BatchGetSecretValue requests
+// made concurrently while listing a directory, if the filesystem supports it.
+// Each request reads up to 20 secrets. Values <= 0 are ignored. The default is
+// controlled by the AWS_SM_MAX_CONCURRENCY environment variable, falling back
+// to 1 (serial) if unset.
+func WithMaxConcurrencyFS(n int, fsys fs.FS) fs.FS {
+	if fsys, ok := fsys.(withMaxConcurrencyer); ok {
+		return fsys.WithMaxConcurrency(n)
+	}
+
+	return fsys
+}
+
+// withTagFilterer is an fs.FS that can be configured to list only secrets
+// with the given tags.
+type withTagFilterer interface {
+	WithTagFilter(tags map[string]string) fs.FS
+}
+
+// WithTagFilterFS restricts the filesystem to secrets with all of the given
+// tags (keys and values), if the filesystem supports it. Other secrets can't
+// be listed or read. The tags are sent to Secrets Manager as ListSecrets
+// filters, so that non-matching secrets aren't listed at all. This replaces
+// any tag filters set with "tag:" URL query parameters.
+func WithTagFilterFS(tags map[string]string, fsys fs.FS) fs.FS {
+	if fsys, ok := fsys.(withTagFilterer); ok {
+		return fsys.WithTagFilter(tags)
+	}
+
+	return fsys
+}
+
 type awssmFS struct {
-	ctx        context.Context
-	base       *url.URL
-	httpclient *http.Client
-	smclient   SecretsManagerClient
-	imdsfs     fs.FS
-	root       string
+	ctx            context.Context
+	base           *url.URL
+	httpclient     *http.Client
+	tlsConfig      *tls.Config
+	smclient       SecretsManagerClient
+	imdsfs         fs.FS
+	tags           map[string]string
+	root           string
+	description    string
+	maxConcurrency int
+	// builtClient is set when smclient was created by getClient (rather than
+	// set with WithSMClient), so must be recreated when the TLS config changes
+	builtClient bool
+}
+
+// defaultMaxConcurrency reads AWS_SM_MAX_CONCURRENCY from the environment,
+// returning 1 if unset or invalid.
+func defaultMaxConcurrency() int {
+	if s := os.Getenv("AWS_SM_MAX_CONCURRENCY"); s != "" {
+		if n, err := strconv.Atoi(s); err == nil && n > 0 {
+			return n
+		}
+	}
+
+	return 1
 }
 
 // New provides a filesystem (an fs.FS) backed by the AWS Secrets Manager,
@@ -68,6 +133,13 @@ func New(u *url.URL) (fs.FS, error) {
 		root = u.Opaque
 	}
 
+	tlsConfig, err := internal.TLSConfigFromURL(u)
+	if err != nil {
+		return nil, fmt.Errorf("invalid TLS configuration: %w", err)
+	}
+
+	tags, description := filtersFromURL(u)
+
 	iu, _ := url.Parse("aws+imds:")
 
 	imdsfs, err := awsimdsfs.New(iu)
@@ -76,10 +148,14 @@ func New(u *url.URL) (fs.FS, error) {
 	}
 
 	return &awssmFS{
-		ctx:    context.Background(),
-		base:   u,
-		root:   root,
-		imdsfs: imdsfs,
+		ctx:            context.Background(),
+		base:           u,
+		root:           root,
+		imdsfs:         imdsfs,
+		tlsConfig:      tlsConfig,
+		tags:           tags,
+		description:    description,
+		maxConcurrency: defaultMaxConcurrency(),
 	}, nil
 }
 
@@ -96,7 +172,10 @@ var (
 	_ internal.WithContexter    = (*awssmFS)(nil)
 	_ internal.WithHTTPClienter = (*awssmFS)(nil)
 	_ withSMClienter            = (*awssmFS)(nil)
+	_ withMaxConcurrencyer      = (*awssmFS)(nil)
+	_ withTagFilterer           = (*awssmFS)(nil)
 	_ internal.WithIMDSFSer     = (*awssmFS)(nil)
+	_ internal.WithTLSConfiger  = (*awssmFS)(nil)
 )
 
 func (f awssmFS) URL() string {
@@ -125,6 +204,22 @@ func (f *awssmFS) WithHTTPClient(client *http.Client) fs.FS {
 	return &fsys
 }
 
+func (f *awssmFS) WithTLSConfig(config *tls.Config) fs.FS {
+	if config == nil {
+		return f
+	}
+
+	fsys := *f
+	fsys.tlsConfig = config
+
+	if fsys.builtClient {
+		fsys.smclient = nil
+		fsys.builtClient = false
+	}
+
+	return &fsys
+}
+
 func (f *awssmFS) WithSMClient(smclient SecretsManagerClient) fs.FS {
 	if smclient == nil {
 		return f
@@ -132,6 +227,25 @@ func (f *awssmFS) WithSMClient(smclient SecretsManagerClient) fs.FS {
 
 	fsys := *f
 	fsys.smclient = smclient
+	fsys.builtClient = false
+
+	return &fsys
+}
+
+func (f *awssmFS) WithMaxConcurrency(n int) fs.FS {
+	if n <= 0 {
+		return f
+	}
+
+	fsys := *f
+	fsys.maxConcurrency = n
+
+	return &fsys
+}
+
+func (f *awssmFS) WithTagFilter(tags map[string]string) fs.FS {
+	fsys := *f
+	fsys.tags = tags
 
 	return &fsys
 }
@@ -152,9 +266,14 @@ func (f *awssmFS) getClient(ctx context.Context) (SecretsManagerClient, error) {
 		return f.smclient, nil
 	}
 
+	httpclient, err := internal.HTTPClientWithTLSConfig(f.httpclient, f.tlsConfig)
+	if err != nil {
+		return nil, err
+	}
+
 	opts := [](func(*config.LoadOptions) error){}
-	if f.httpclient != nil {
-		opts = append(opts, config.WithHTTPClient(f.httpclient))
+	if httpclient != nil {
+		opts = append(opts, config.WithHTTPClient(httpclient))
 	}
 
 	cfg, err := config.LoadDefaultConfig(ctx, opts...)
@@ -182,6 +301,7 @@ func (f *awssmFS) getClient(ctx context.Context) (SecretsManagerClient, error) {
 	}
 
 	f.smclient = secretsmanager.NewFromConfig(cfg, optFns...)
+	f.builtClient = true
 
 	return f.smclient, nil
 }
@@ -211,11 +331,9 @@ func (f *awssmFS) Open(name string) (fs.File, error) {
 		return nil, err
 	}
 
-	file := &awssmFile{
-		ctx:    f.ctx,
-		name:   strings.TrimPrefix(path.Base(name), "."),
-		root:   strings.TrimPrefix(path.Join(f.root, path.Dir(name)), "."),
-		client: smclient,
+	file, err := f.newFile(name, smclient)
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
 	}
 
 	if name == "." {
@@ -227,6 +345,36 @@ func (f *awssmFS) Open(name string) (fs.File, error) {
 	return file, nil
 }
 
+// newFile returns a file for name, which may select a version of the secret
+// with the "stage" or "version" query parameters, and a key to extract from
+// the secret's JSON value with a "#key" suffix
+func (f *awssmFS) newFile(name string, smclient SecretsManagerClient) (*awssmFile, error) {
+	name, jsonKey, _ := strings.Cut(name, keySeparator)
+	name, rawQuery, _ := strings.Cut(name, "?")
+
+	stage, version, err := versionFromQuery(rawQuery)
+	if err != nil {
+		return nil, err
+	}
+
+	file := &awssmFile{
+		ctx:            f.ctx,
+		name:           strings.TrimPrefix(path.Base(name), "."),
+		root:           strings.TrimPrefix(path.Join(f.root, path.Dir(name)), "."),
+		client:         smclient,
+		stage:          stage,
+		version:        version,
+		jsonKey:        jsonKey,
+		tags:           f.tags,
+		description:    f.description,
+		maxConcurrency: f.maxConcurrency,
+	}
+
+	file.setVersions()
+
+	return file, nil
+}
+
 func (f *awssmFS) ReadDir(name string) ([]fs.DirEntry, error) {
 	if !internal.ValidPath(name) {
 		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrInvalid}
@@ -238,13 +386,18 @@ func (f *awssmFS) ReadDir(name string) ([]fs.DirEntry, error) {
 	}
 
 	dir := &awssmFile{
-		ctx:    f.ctx,
-		name:   name,
-		root:   f.root,
-		client: smclient,
-		fi:     internal.DirInfo(name, time.Time{}),
+		ctx:            f.ctx,
+		name:           name,
+		root:           f.root,
+		client:         smclient,
+		fi:             internal.DirInfo(name, time.Time{}),
+		tags:           f.tags,
+		description:    f.description,
+		maxConcurrency: f.maxConcurrency,
 	}
 
+	dir.setVersions()
+
 	des, err := dir.ReadDir(-1)
 	if err != nil {
 		return nil, &fs.PathError{Op: "readDir", Path: name, Err: err}
@@ -267,18 +420,30 @@ func (f *awssmFS) ReadFile(name string) ([]byte, error) {
 		return nil, err
 	}
 
-	secret, err := smclient.GetSecretValue(f.ctx, &secretsmanager.GetSecretValueInput{
-		SecretId: aws.String(path.Join(f.root, name)),
-	})
+	file, err := f.newFile(name, smclient)
+	if err != nil {
+		return nil, &fs.PathError{Op: "readFile", Path: name, Err: err}
+	}
+
+	if file.versions {
+		return nil, &fs.PathError{Op: "readFile", Path: name, Err: fmt.Errorf("%w: is a directory", fs.ErrInvalid)}
+	}
+
+	if err = file.checkVisible(); err != nil {
+		return nil, &fs.PathError{Op: "readFile", Path: name, Err: err}
+	}
+
+	secret, err := smclient.GetSecretValue(f.ctx, file.secretValueInput())
 	if err != nil {
 		return nil, &fs.PathError{Op: "readFile", Path: name, Err: convertAWSError(err)}
 	}
 
-	if secret.SecretString != nil {
-		return []byte(*secret.SecretString), nil
+	body, err := file.secretValue(secret)
+	if err != nil {
+		return nil, &fs.PathError{Op: "readFile", Path: name, Err: err}
 	}
 
-	return secret.SecretBinary, nil
+	return body, nil
 }
 
 type awssmFile struct {
@@ -286,11 +451,39 @@ type awssmFile struct {
 	fi     fs.FileInfo
 	client SecretsManagerClient
 	body   io.Reader
-	name   string
-	root   string
+
+	// tags restricts the visible secrets to those with these tags
+	tags map[string]string
+
+	// info holds the secret's metadata, once known
+	info *SecretInfo
+
+	name string
+	root string
+
+	// secretID is the ID of the secret to read, when it isn't the file's
+	// path (for files in a secret's versions directory)
+	secretID string
+
+	// stage and version select the version of the secret to read
+	stage   string
+	version string
+
+	// jsonKey is the top-level key to read from the secret's JSON value
+	jsonKey string
+
+	// description restricts the visible secrets to those with descriptions
+	// starting with this
+	description string
 
 	children []*awssmFile
 	diroff   int
+
+	// maxConcurrency is the maximum number of concurrent batch reads
+	maxConcurrency int
+
+	// versions is set for the virtual directory listing a secret's versions
+	versions bool
 }
 
 var _ fs.ReadDirFile = (*awssmFile)(nil)
@@ -316,27 +509,38 @@ func (f *awssmFile) Stat() (fs.FileInfo, error) {
 		return f.fi, nil
 	}
 
+	if f.statsFromVersions() {
+		fi, err := f.statVersions()
+		if err != nil {
+			return nil, &fs.PathError{Op: "stat", Path: f.name, Err: err}
+		}
+
+		f.fi = fi
+
+		return f.fi, nil
+	}
+
+	// when filtering, the secret's metadata is read before its value (to check
+	// the filters), so it's also available from Sys
 	err := f.getSecret()
 	if err == nil {
 		return f.fi, nil
 	}
 
-	if !errors.Is(err, fs.ErrNotExist) {
+	// versions and JSON keys are never directories
+	if !errors.Is(err, fs.ErrNotExist) || f.secretID != "" || f.jsonKey != "" {
 		return nil, &fs.PathError{Op: "stat", Path: f.name, Err: err}
 	}
 
 	// may be a directory, so attempt to list one child
 	// no need for special handling for opaque paths, as "." will never hit this
 	// code path (Open sets f.fi to a DirInfo)
-	filters := []smtypes.Filter{{Key: "name", Values: []string{path.Join(f.root, f.name) + "/"}}}
-	params := secretsmanager.ListSecretsInput{MaxResults: aws.Int32(1), Filters: filters}
-
-	secrets, err := f.client.ListSecrets(f.ctx, &params)
+	found, err := f.hasSecrets(path.Join(f.root, f.name) + "/")
 	if err != nil {
 		return nil, &fs.PathError{Op: "stat", Path: f.name, Err: convertAWSError(err)}
 	}
 
-	if len(secrets.SecretList) == 0 {
+	if !found {
 		return nil, &fs.PathError{Op: "stat", Path: f.name, Err: fs.ErrNotExist}
 	}
 
@@ -385,20 +589,27 @@ func convertAWSError(err error) error {
 // and fi. SDK errors will not be leaked, instead they will be converted to more
 // general errors.
 func (f *awssmFile) getSecret() error {
-	fullPath := path.Join(f.root, f.name)
+	if f.versions {
+		return fmt.Errorf("%w: is a directory", fs.ErrInvalid)
+	}
 
-	secret, err := f.client.GetSecretValue(f.ctx, &secretsmanager.GetSecretValueInput{
-		SecretId: &fullPath,
-	})
+	if err := f.checkVisible(); err != nil {
+		return err
+	}
+
+	secret, err := f.client.GetSecretValue(f.ctx, f.secretValueInput())
 	if err != nil {
 		return fmt.Errorf("getSecretValue: %w", convertAWSError(err))
 	}
 
-	body := secret.SecretBinary
+	return f.setSecret(secret)
+}
 
-	// May be a string
-	if secret.SecretString != nil {
-		body = []byte(*secret.SecretString)
+// setSecret populates body and fi from the secret value
+func (f *awssmFile) setSecret(secret *secretsmanager.GetSecretValueOutput) error {
+	body, err := f.secretValue(secret)
+	if err != nil {
+		return err
 	}
 
 	seclen := int64(len(body))
@@ -411,7 +622,23 @@ func (f *awssmFile) getSecret() error {
 		modTime = &time.Time{}
 	}
 
-	// populate fi
+	// populate fi - versions also describe the version they hold
+	if f.secretID != "" {
+		f.fi = internal.FileInfoWithSys(f.name, seclen, 0o444, *modTime, "", &SecretVersion{
+			CreatedDate: *modTime,
+			ID:          aws.ToString(secret.VersionId),
+			Stages:      secret.VersionStages,
+		})
+
+		return nil
+	}
+
+	if f.info != nil {
+		f.fi = internal.FileInfoWithSys(f.name, seclen, 0o444, *modTime, "", f.info)
+
+		return nil
+	}
+
 	f.fi = internal.FileInfo(f.name, seclen, 0o444, *modTime, "")
 
 	return nil
@@ -441,7 +668,7 @@ func (f *awssmFile) listSecrets() ([]smtypes.SecretListEntry, error) {
 
 	for token := (*string)(nil); ; {
 		params := secretsmanager.ListSecretsInput{
-			Filters:   []smtypes.Filter{{Key: "name", Values: []string{filter}}},
+			Filters:   f.listFilters(filter),
 			NextToken: token,
 		}
 
@@ -452,15 +679,19 @@ func (f *awssmFile) listSecrets() ([]smtypes.SecretListEntry, error) {
 
 		// trim the prefix from the names so we can use them as filenames
 		for _, secret := range secrets.SecretList {
+			if !f.visible(secretInfo(&secret)) {
+				continue
+			}
+
 			name := strings.TrimPrefix(*secret.Name, prefix)
 			if prefix != "/" {
 				name = strings.TrimPrefix(name, "/")
 			}
 
 			*secret.Name = name
-		}
 
-		secretList = append(secretList, secrets.SecretList...)
+			secretList = append(secretList, secret)
+		}
 
 		token = secrets.NextToken
 		if token == nil {
@@ -478,6 +709,10 @@ func (f *awssmFile) listSecrets() ([]smtypes.SecretListEntry, error) {
 
 // list assignes a sorted list of the children of this directory to f.children
 func (f *awssmFile) list() error {
+	if f.versions {
+		return f.listVersions()
+	}
+
 	secretList, err := f.listSecrets()
 	if err != nil {
 		return err
@@ -486,6 +721,9 @@ func (f *awssmFile) list() error {
 	// a set of files that we've already seen - we don't want to add duplicates
 	seen := map[string]struct{}{}
 
+	// the secrets to read, as opposed to directories
+	secrets := []*awssmFile{}
+
 	for _, entry := range secretList {
 		parts := strings.SplitN(*entry.Name, "/", 2)
 		name := parts[0]
@@ -497,27 +735,30 @@ func (f *awssmFile) list() error {
 		seen[name] = struct{}{}
 
 		child := awssmFile{
-			ctx:    f.ctx,
-			name:   name,
-			root:   path.Join(f.root, f.name),
-			client: f.client,
+			ctx:            f.ctx,
+			name:           name,
+			root:           path.Join(f.root, f.name),
+			client:         f.client,
+			tags:           f.tags,
+			description:    f.description,
+			maxConcurrency: f.maxConcurrency,
 		}
 
 		if len(parts) > 1 {
 			// given that directories are artificial, they have a zero time
 			child.fi = internal.DirInfo(name, time.Time{})
 		} else {
-			fi, err := child.Stat()
-			if err != nil {
-				return err
-			}
-
-			child.fi = fi
+			child.info = secretInfo(&entry)
+			secrets = append(secrets, &child)
 		}
 
 		f.children = append(f.children, &child)
 	}
 
+	if err := f.loadSecrets(secrets); err != nil {
+		return err
+	}
+
 	// the AWS SDK doesn't sort the list of children, so we do it here
 	sort.Slice(f.children, func(i, j int) bool {
 		return f.children[i].name < f.children[j].name
@@ -1,13 +1,23 @@
 package awssmfs
 
 import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
 	"io"
 	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
 	"testing"
 	"testing/fstest"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	smtypes "github.com/aws/aws-sdk-go-v2/service/secretsmanager/types"
+	"github.com/aws/smithy-go"
+	"github.com/hairyhenderson/go-fsimpl"
 	"github.com/hairyhenderson/go-fsimpl/internal/tests"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -284,3 +294,499 @@ func TestAWSSMFS_ReadDir_Opaque(t *testing.T) {
 	assert.Equal(t, "c", fi.Name())
 	assert.True(t, fi.IsDir())
 }
+
+func setupVersionedAWSSMFsys(t *testing.T) (fs.FS, *fakeClient) {
+	t.Helper()
+
+	fsys, err := New(tests.MustURL("aws+sm:///db/"))
+	require.NoError(t, err)
+
+	created := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	client := clientWithValues(t, map[string]*testVal{
+		"/db/other": vs("other"),
+		"/db/prod": {s: "current", versions: []testVersion{
+			{id: "v1", stages: []string{"AWSPREVIOUS"}, created: created, val: vs("previous")},
+			{id: "v3", stages: []string{"AWSPENDING"}, created: created.Add(2 * time.Hour), val: vs("pending")},
+			{id: "v2", stages: []string{"AWSCURRENT"}, created: created.Add(time.Hour), val: vs("current")},
+		}},
+	})
+
+	return WithSMClientFS(client, fsys), client
+}
+
+func TestAWSSMFS_VersionParams(t *testing.T) {
+	fsys, _ := setupVersionedAWSSMFsys(t)
+
+	b, err := fs.ReadFile(fsys, "prod")
+	require.NoError(t, err)
+	assert.Equal(t, "current", string(b))
+
+	b, err = fs.ReadFile(fsys, "prod?stage=AWSPREVIOUS")
+	require.NoError(t, err)
+	assert.Equal(t, "previous", string(b))
+
+	b, err = fs.ReadFile(fsys, "prod?version=v3")
+	require.NoError(t, err)
+	assert.Equal(t, "pending", string(b))
+
+	f, err := fsys.Open("prod?stage=AWSPENDING")
+	require.NoError(t, err)
+
+	b, err = io.ReadAll(f)
+	require.NoError(t, err)
+	assert.Equal(t, "pending", string(b))
+
+	fi, err := f.Stat()
+	require.NoError(t, err)
+	assert.Equal(t, "prod", fi.Name())
+
+	_, err = fs.ReadFile(fsys, "prod?stage=bogus")
+	require.ErrorIs(t, err, fs.ErrNotExist)
+
+	_, err = fs.ReadFile(fsys, "prod?stage=%zz")
+	require.ErrorIs(t, err, fs.ErrInvalid)
+}
+
+func TestAWSSMFS_Versions(t *testing.T) {
+	fsys, client := setupVersionedAWSSMFsys(t)
+
+	vfsys, err := fs.Sub(fsys, "prod:versions")
+	require.NoError(t, err)
+	require.NoError(t, fstest.TestFS(vfsys, "v1", "v2", "v3"))
+
+	// the versions directory isn't listed with the secrets
+	de, err := fs.ReadDir(fsys, ".")
+	require.NoError(t, err)
+	require.Len(t, de, 2)
+
+	client.getCalls.Store(0)
+
+	de, err = fs.ReadDir(fsys, "prod:versions")
+	require.NoError(t, err)
+	require.Len(t, de, 3)
+
+	for i, id := range []string{"v1", "v2", "v3"} {
+		assert.Equal(t, id, de[i].Name())
+		assert.False(t, de[i].IsDir())
+	}
+
+	fi, err := de[2].Info()
+	require.NoError(t, err)
+
+	ver, ok := fi.Sys().(*SecretVersion)
+	require.True(t, ok)
+	assert.Equal(t, "v3", ver.ID)
+	assert.Equal(t, []string{"AWSPENDING"}, ver.Stages)
+	assert.Equal(t, fi.ModTime(), ver.CreatedDate)
+
+	// the versions' values aren't read to list or stat them, so their sizes
+	// aren't known
+	assert.Equal(t, int64(-1), fi.Size())
+
+	fi, err = fs.Stat(fsys, "prod:versions/v2")
+	require.NoError(t, err)
+	assert.Equal(t, int64(-1), fi.Size())
+	assert.Equal(t, []string{"AWSCURRENT"}, fi.Sys().(*SecretVersion).Stages)
+	assert.Zero(t, client.getCalls.Load())
+
+	b, err := fs.ReadFile(fsys, "prod:versions/v1")
+	require.NoError(t, err)
+	assert.Equal(t, "previous", string(b))
+
+	fi, err = fs.Stat(fsys, "prod:versions")
+	require.NoError(t, err)
+	assert.True(t, fi.IsDir())
+
+	_, err = fs.ReadFile(fsys, "prod:versions")
+	require.ErrorIs(t, err, fs.ErrInvalid)
+
+	_, err = fs.Stat(fsys, "prod:versions/bogus")
+	require.ErrorIs(t, err, fs.ErrNotExist)
+
+	_, err = fs.Stat(fsys, "bogus:versions")
+	require.ErrorIs(t, err, fs.ErrNotExist)
+
+	_, err = fs.ReadDir(fsys, "bogus:versions")
+	require.ErrorIs(t, err, fs.ErrNotExist)
+}
+
+func TestAWSSMFS_MinimalClient(t *testing.T) {
+	fsys, err := New(tests.MustURL("aws+sm:///db/"))
+	require.NoError(t, err)
+
+	// a client with only the methods of SecretsManagerClient
+	fake := clientWithValues(t, map[string]*testVal{
+		"/db/prod": {s: "current", versions: []testVersion{
+			{id: "v1", stages: []string{"AWSCURRENT"}, val: vs("current")},
+		}},
+		"/db/other":  vs("other"),
+		"/db/tagged": {s: "tagged", tags: map[string]string{"team": "payments"}},
+	})
+
+	fsys = WithSMClientFS(struct{ SecretsManagerClient }{fake}, fsys)
+
+	b, err := fs.ReadFile(fsys, "prod")
+	require.NoError(t, err)
+	assert.Equal(t, "current", string(b))
+
+	// secrets are read one at a time
+	de, err := fs.ReadDir(fsys, ".")
+	require.NoError(t, err)
+	require.Len(t, de, 3)
+
+	fi, err := de[1].Info()
+	require.NoError(t, err)
+	assert.Equal(t, int64(len("current")), fi.Size())
+
+	assert.Zero(t, fake.batchCalls.Load())
+	assert.Equal(t, int32(4), fake.getCalls.Load())
+
+	// versions can't be listed, which is reported as unsupported rather than
+	// as a missing directory
+	_, err = fs.ReadDir(fsys, "prod:versions")
+	require.ErrorIs(t, err, errors.ErrUnsupported)
+	require.NotErrorIs(t, err, fs.ErrNotExist)
+
+	_, err = fs.Stat(fsys, "prod:versions")
+	require.ErrorIs(t, err, errors.ErrUnsupported)
+	require.NotErrorIs(t, err, fs.ErrNotExist)
+
+	// but a known version can still be read
+	b, err = fs.ReadFile(fsys, "prod:versions/v1")
+	require.NoError(t, err)
+	assert.Equal(t, "current", string(b))
+
+	// filters are checked by listing the secret
+	fsys = WithTagFilterFS(map[string]string{"team": "payments"}, fsys)
+
+	_, err = fs.ReadFile(fsys, "prod")
+	require.ErrorIs(t, err, fs.ErrNotExist)
+
+	fi, err = fs.Stat(fsys, "tagged")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"team": "payments"}, fi.Sys().(*SecretInfo).Tags)
+
+	assert.Zero(t, fake.describeCalls.Load())
+}
+
+func TestAWSSMFS_JSONKey(t *testing.T) {
+	fsys, err := New(tests.MustURL("aws+sm:///db/"))
+	require.NoError(t, err)
+
+	fsys = WithSMClientFS(clientWithValues(t, map[string]*testVal{
+		"/db/prod": {
+			s: `{"username":"admin","password":"hunter2","port":5432,"ssl":true,"opts":{"a": [1, 2]}}`,
+			versions: []testVersion{
+				{id: "v1", stages: []string{"AWSPREVIOUS"}, val: vs(`{"password":"old"}`)},
+			},
+		},
+		"/db/plain":  vs("not json"),
+		"/db/binary": vb([]byte(`{"password":"bin"}`)),
+	}), fsys)
+
+	testdata := []struct{ name, expected string }{
+		{"prod#password", "hunter2"},
+		{"prod#port", "5432"},
+		{"prod#ssl", "true"},
+		{"prod#opts", `{"a":[1,2]}`},
+		{"prod?stage=AWSPREVIOUS#password", "old"},
+		{"prod:versions/v1#password", "old"},
+		{"binary#password", "bin"},
+	}
+
+	for _, d := range testdata {
+		b, err := fs.ReadFile(fsys, d.name)
+		require.NoError(t, err, d.name)
+		assert.Equal(t, d.expected, string(b), d.name)
+
+		f, err := fsys.Open(d.name)
+		require.NoError(t, err, d.name)
+
+		b, err = io.ReadAll(f)
+		require.NoError(t, err, d.name)
+		assert.Equal(t, d.expected, string(b), d.name)
+
+		fi, err := f.Stat()
+		require.NoError(t, err, d.name)
+		assert.Equal(t, int64(len(d.expected)), fi.Size(), d.name)
+	}
+
+	_, err = fs.ReadFile(fsys, "prod#bogus")
+	require.ErrorIs(t, err, fs.ErrNotExist)
+
+	_, err = fs.Stat(fsys, "prod#bogus")
+	require.ErrorIs(t, err, fs.ErrNotExist)
+
+	_, err = fs.ReadFile(fsys, "plain#password")
+	require.ErrorIs(t, err, fs.ErrInvalid)
+}
+
+func TestAWSSMFS_BatchRead(t *testing.T) {
+	secrets := map[string]*testVal{}
+	expected := []string{}
+
+	for i := range 45 {
+		name := fmt.Sprintf("s%02d", i)
+		secrets["/dir/"+name] = vs("value " + name)
+		expected = append(expected, name)
+	}
+
+	secrets["/dir/sub/nested"] = vs("nested")
+
+	client := clientWithValues(t, secrets)
+
+	fsys, err := New(tests.MustURL("aws+sm:///dir"))
+	require.NoError(t, err)
+
+	fsys = WithSMClientFS(client, fsys)
+	fsys = WithMaxConcurrencyFS(2, fsys)
+
+	de, err := fs.ReadDir(fsys, ".")
+	require.NoError(t, err)
+	require.Len(t, de, 46)
+
+	for i, name := range expected {
+		fi, err := de[i].Info()
+		require.NoError(t, err)
+		assert.Equal(t, name, fi.Name())
+		assert.Equal(t, int64(len("value "+name)), fi.Size())
+	}
+
+	assert.True(t, de[45].IsDir())
+
+	// 45 secrets are read in 3 batches, at most 2 at a time
+	assert.Equal(t, int32(3), client.batchCalls.Load())
+	assert.Equal(t, int32(0), client.getCalls.Load())
+	assert.Equal(t, int32(2), client.maxInFlight.Load())
+
+	// the values are read one at a time when batch reads aren't allowed
+	client = clientWithValues(t, secrets)
+	client.batchErr = &smithy.GenericAPIError{Code: "AccessDeniedException", Message: "not allowed"}
+
+	fsys = WithSMClientFS(client, fsys)
+
+	de, err = fs.ReadDir(fsys, ".")
+	require.NoError(t, err)
+	require.Len(t, de, 46)
+	assert.Equal(t, int32(45), client.getCalls.Load())
+
+	// other errors are returned
+	client = clientWithValues(t, secrets)
+	client.batchErr = &smtypes.DecryptionFailure{Message: aws.String("nope")}
+
+	_, err = fs.ReadDir(WithSMClientFS(client, fsys), ".")
+	require.ErrorIs(t, err, fs.ErrPermission)
+}
+
+func TestAWSSMFS_MaxConcurrency(t *testing.T) {
+	t.Setenv("AWS_SM_MAX_CONCURRENCY", "7")
+	assert.Equal(t, 7, defaultMaxConcurrency())
+
+	t.Setenv("AWS_SM_MAX_CONCURRENCY", "notanumber")
+	assert.Equal(t, 1, defaultMaxConcurrency())
+
+	fsys, err := New(tests.MustURL("aws+sm:///"))
+	require.NoError(t, err)
+
+	assert.Equal(t, 3, WithMaxConcurrencyFS(3, fsys).(*awssmFS).maxConcurrency)
+	assert.Equal(t, 1, WithMaxConcurrencyFS(0, fsys).(*awssmFS).maxConcurrency)
+}
+
+func TestAWSSMFS_TagFilter(t *testing.T) {
+	fsys, err := New(tests.MustURL("aws+sm:///app"))
+	require.NoError(t, err)
+
+	fsys = WithSMClientFS(clientWithValues(t, map[string]*testVal{
+		"/app/payments/key": {s: "p", tags: map[string]string{"team": "payments", "env": "prod"}},
+		"/app/payments/dev": {s: "d", tags: map[string]string{"team": "payments", "env": "dev"}},
+		"/app/search/key":   {s: "s", tags: map[string]string{"team": "search", "env": "prod"}},
+		// matches the tag key and value filters separately
+		"/app/mixed/key": {s: "m", tags: map[string]string{"team": "prod", "env": "payments"}},
+	}), fsys)
+
+	fsys = WithTagFilterFS(map[string]string{"team": "payments", "env": "prod"}, fsys)
+
+	require.NoError(t, fstest.TestFS(fsys, "payments/key"))
+
+	de, err := fs.ReadDir(fsys, ".")
+	require.NoError(t, err)
+	require.Len(t, de, 1)
+	assert.Equal(t, "payments", de[0].Name())
+
+	_, err = fs.Stat(fsys, "search")
+	require.ErrorIs(t, err, fs.ErrNotExist)
+
+	_, err = fs.Stat(fsys, "mixed")
+	require.ErrorIs(t, err, fs.ErrNotExist)
+
+	fi, err := fs.Stat(fsys, "payments")
+	require.NoError(t, err)
+	assert.True(t, fi.IsDir())
+}
+
+func TestAWSSMFS_URLFilters(t *testing.T) {
+	secrets := map[string]*testVal{
+		"/app/payments/key": {
+			s: "p", tags: map[string]string{"team": "payments"},
+			meta: &testMeta{desc: "Payments API key"},
+		},
+		"/app/payments/other": {
+			s: "o", tags: map[string]string{"team": "payments"},
+			meta: &testMeta{desc: "Something else"},
+		},
+		"/app/search/key": {
+			s: "s", tags: map[string]string{"team": "search"},
+			meta: &testMeta{desc: "payments-adjacent"},
+		},
+	}
+
+	fsys, err := New(tests.MustURL("aws+sm:///app?tag:team=payments&description=payments"))
+	require.NoError(t, err)
+
+	fsys = WithSMClientFS(clientWithValues(t, secrets), fsys)
+
+	require.NoError(t, fstest.TestFS(fsys, "payments/key"))
+
+	b, err := fs.ReadFile(fsys, "payments/key")
+	require.NoError(t, err)
+	assert.Equal(t, "p", string(b))
+
+	// secrets that don't match are hidden from direct reads too
+	for _, name := range []string{"payments/other", "search/key"} {
+		_, err = fs.ReadFile(fsys, name)
+		require.ErrorIs(t, err, fs.ErrNotExist, name)
+
+		_, err = fs.Stat(fsys, name)
+		require.ErrorIs(t, err, fs.ErrNotExist, name)
+
+		f, err := fsys.Open(name)
+		require.NoError(t, err)
+
+		_, err = io.ReadAll(f)
+		require.ErrorIs(t, err, fs.ErrNotExist, name)
+	}
+
+	_, err = fs.ReadDir(fsys, "search/key:versions")
+	require.ErrorIs(t, err, fs.ErrNotExist)
+
+	_, err = fs.Stat(fsys, "search/key:versions")
+	require.ErrorIs(t, err, fs.ErrNotExist)
+
+	_, err = fs.Stat(fsys, "search")
+	require.ErrorIs(t, err, fs.ErrNotExist)
+
+	// only tags
+	fsys, err = New(tests.MustURL("aws+sm:///app?tag:team=search"))
+	require.NoError(t, err)
+
+	fsys = WithSMClientFS(clientWithValues(t, secrets), fsys)
+
+	require.NoError(t, fstest.TestFS(fsys, "search/key"))
+
+	// WithTagFilterFS replaces the URL's tag filters
+	fsys = WithTagFilterFS(map[string]string{"team": "payments"}, fsys)
+
+	require.NoError(t, fstest.TestFS(fsys, "payments/key", "payments/other"))
+}
+
+func TestAWSSMFS_SecretInfo(t *testing.T) {
+	rotated := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	client := clientWithValues(t, map[string]*testVal{
+		"/app/key": {
+			s: "value", tags: map[string]string{"team": "payments", "env": "prod"},
+			meta: &testMeta{
+				desc: "API key", kmsKeyID: "alias/app", schedule: "rate(10 days)",
+				rotation: true, lastRotated: rotated,
+			},
+		},
+		"/app/plain": vs("plain"),
+	})
+
+	fsys, err := New(tests.MustURL("aws+sm:///app"))
+	require.NoError(t, err)
+
+	fsys = WithSMClientFS(client, fsys)
+
+	expected := &SecretInfo{
+		Tags:             map[string]string{"team": "payments", "env": "prod"},
+		Description:      "API key",
+		KMSKeyID:         "alias/app",
+		RotationEnabled:  true,
+		RotationSchedule: "rate(10 days)",
+		LastRotatedDate:  rotated,
+	}
+
+	// without filters, stat'ing a secret doesn't describe it, so doesn't
+	// need the DescribeSecret permission
+	client.describeErr = &smithy.GenericAPIError{Code: "AccessDeniedException", Message: "denied"}
+
+	fi, err := fs.Stat(fsys, "key")
+	require.NoError(t, err)
+	assert.Nil(t, fi.Sys())
+	assert.Equal(t, int32(0), client.describeCalls.Load())
+
+	client.describeErr = nil
+
+	// with filters, the metadata read to check them is available
+	filtered := WithTagFilterFS(map[string]string{"team": "payments"}, fsys)
+
+	fi, err = fs.Stat(filtered, "key")
+	require.NoError(t, err)
+	assert.Equal(t, expected, fi.Sys())
+
+	// listings don't need a DescribeSecret request per secret
+	client.describeCalls.Store(0)
+
+	de, err := fs.ReadDir(fsys, ".")
+	require.NoError(t, err)
+	require.Len(t, de, 2)
+
+	fi, err = de[0].Info()
+	require.NoError(t, err)
+	assert.Equal(t, expected, fi.Sys())
+	assert.Equal(t, int32(0), client.describeCalls.Load())
+}
+
+func TestAWSSMFS_TLSConfig(t *testing.T) {
+	var reqs atomic.Int32
+
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reqs.Add(1)
+
+		assert.Equal(t, "secretsmanager.GetSecretValue", r.Header.Get("X-Amz-Target"))
+
+		w.Header().Set("Content-Type", "application/x-amz-json-1.1")
+		_, _ = w.Write([]byte(`{"Name":"foo","SecretString":"bar"}`))
+	}))
+	t.Cleanup(srv.Close)
+
+	t.Setenv("AWS_ENDPOINT_URL_SECRETS_MANAGER", srv.URL)
+	t.Setenv("AWS_REGION", "us-east-1")
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKIAEXAMPLE")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+	// a CA bundle can't be combined with a custom HTTP client
+	t.Setenv("AWS_CA_BUNDLE", "")
+	t.Setenv("AWS_MAX_ATTEMPTS", "1")
+
+	fsys, err := New(tests.MustURL("aws+sm:"))
+	require.NoError(t, err)
+
+	fsys = fsimpl.WithContextFS(t.Context(), fsys)
+
+	// the server's certificate isn't trusted by default
+	_, err = fs.ReadFile(fsys, "foo")
+	require.Error(t, err)
+	assert.Equal(t, int32(0), reqs.Load())
+
+	pool := x509.NewCertPool()
+	pool.AddCert(srv.Certificate())
+
+	fsys = fsimpl.WithTLSConfigFS(&tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}, fsys)
+
+	b, err := fs.ReadFile(fsys, "foo")
+	require.NoError(t, err)
+	assert.Equal(t, "bar", string(b))
+	assert.Equal(t, int32(1), reqs.Load())
+}
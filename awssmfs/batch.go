@@ -0,0 +1,134 @@
+package awssmfs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"slices"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	smtypes "github.com/aws/aws-sdk-go-v2/service/secretsmanager/types"
+	"github.com/aws/smithy-go"
+	"golang.org/x/sync/errgroup"
+)
+
+// batchSize is the maximum number of secrets that can be read with a single
+// BatchGetSecretValue request
+const batchSize = 20
+
+// loadSecrets reads the values of the given files (which must be secrets, not
+// directories) with BatchGetSecretValue, and populates their body and fi.
+// Batches are read concurrently, bounded by maxConcurrency.
+func (f *awssmFile) loadSecrets(files []*awssmFile) error {
+	g, ctx := errgroup.WithContext(f.ctx)
+	g.SetLimit(max(f.maxConcurrency, 1))
+
+	for batch := range slices.Chunk(files, batchSize) {
+		g.Go(func() error {
+			return f.loadBatch(ctx, batch)
+		})
+	}
+
+	return g.Wait()
+}
+
+// loadBatch reads the values of up to batchSize files in one request. If the
+// client doesn't support BatchGetSecretValue, or the caller isn't allowed to
+// use it, the values are read one at a time instead.
+func (f *awssmFile) loadBatch(ctx context.Context, files []*awssmFile) error {
+	getter, ok := f.client.(batchSecretGetter)
+	if !ok {
+		return loadEach(files)
+	}
+
+	byID := make(map[string]*awssmFile, len(files))
+	ids := make([]string, 0, len(files))
+
+	for _, file := range files {
+		id := path.Join(file.root, file.name)
+		byID[id] = file
+		ids = append(ids, id)
+	}
+
+	out, err := getter.BatchGetSecretValue(ctx, &secretsmanager.BatchGetSecretValueInput{
+		SecretIdList: ids,
+	})
+
+	switch {
+	case isAccessDenied(err):
+		return loadEach(files)
+	case err != nil:
+		return fmt.Errorf("batchGetSecretValue: %w", convertAWSError(err))
+	case len(out.Errors) > 0:
+		apiErr := out.Errors[0]
+
+		return fmt.Errorf("batchGetSecretValue %s: %w", aws.ToString(apiErr.SecretId), convertBatchError(apiErr))
+	}
+
+	for _, v := range out.SecretValues {
+		file, ok := byID[aws.ToString(v.Name)]
+		if !ok {
+			continue
+		}
+
+		err = file.setSecret(&secretsmanager.GetSecretValueOutput{
+			ARN:           v.ARN,
+			CreatedDate:   v.CreatedDate,
+			Name:          v.Name,
+			SecretBinary:  v.SecretBinary,
+			SecretString:  v.SecretString,
+			VersionId:     v.VersionId,
+			VersionStages: v.VersionStages,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	// the secret may have been deleted since it was listed
+	for _, id := range ids {
+		if byID[id].fi == nil {
+			return fmt.Errorf("batchGetSecretValue %s: %w", id, fs.ErrNotExist)
+		}
+	}
+
+	return nil
+}
+
+// loadEach reads the values of the files one at a time
+func loadEach(files []*awssmFile) error {
+	for _, file := range files {
+		if err := file.getSecret(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// isAccessDenied returns true if err is an access denied error from AWS
+func isAccessDenied(err error) bool {
+	var apiErr smithy.APIError
+
+	return errors.As(err, &apiErr) && apiErr.ErrorCode() == "AccessDeniedException"
+}
+
+// convertBatchError converts an error for a single secret from
+// BatchGetSecretValue, in the same way as convertAWSError
+func convertBatchError(apiErr smtypes.APIErrorType) error {
+	msg := aws.ToString(apiErr.Message)
+
+	switch aws.ToString(apiErr.ErrorCode) {
+	case "ResourceNotFoundException":
+		return fmt.Errorf("%w: %s", fs.ErrNotExist, msg)
+	case "DecryptionFailure", "AccessDeniedException":
+		return fmt.Errorf("%w: %s: %s", fs.ErrPermission, aws.ToString(apiErr.ErrorCode), msg)
+	case "InvalidParameterException", "InvalidRequestException":
+		return fmt.Errorf("%w: %s", fs.ErrInvalid, msg)
+	default:
+		return fmt.Errorf("%s: %s", aws.ToString(apiErr.ErrorCode), msg)
+	}
+}
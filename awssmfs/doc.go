@@ -23,6 +23,81 @@
 //
 //	aws+sm:prod/bar/
 //
+// # Versions
+//
+// By default, the current version of each secret (the version with the
+// "AWSCURRENT" staging label) is read. To read another version, for example
+// during rotation, add a "stage" or "version" query parameter to the file
+// name, with a staging label or a version ID:
+//
+//	fs.ReadFile(fsys, "db/prod?stage=AWSPREVIOUS")
+//	fs.ReadFile(fsys, "db/prod?version=a1b2c3d4-5678-90ab-cdef-EXAMPLE11111")
+//
+// The versions of a secret can be listed by reading the virtual directory
+// named after the secret with a ":versions" suffix, which contains a file for
+// each version, named by its version ID. These directories aren't included in
+// directory listings. The staging labels of each version are available from
+// the [SecretVersion] returned by the Sys method of the file's [fs.FileInfo]:
+//
+//	entries, err := fs.ReadDir(fsys, "db/prod:versions")
+//
+// A suffix is used rather than a "versions" subdirectory because secret names
+// can contain "/" (so "db/prod/versions" may be a real secret), but can't
+// contain ":". Listing and stat'ing versions doesn't read their values, so
+// their sizes are unknown (-1). Listing versions needs a client with a
+// ListSecretVersionIds method (see [WithSMClientFS]) - with other clients,
+// listing or stat'ing a versions directory fails with an error wrapping
+// [errors.ErrUnsupported], though known versions can still be read.
+//
+// # JSON Keys
+//
+// Secrets holding JSON objects (such as those created by RDS rotation) can
+// have a single top-level key read by suffixing the name with "#" and the key.
+// String values are returned as plain text, without quotes, and other values
+// are returned as JSON. For example, to read the password from the "db/prod"
+// secret:
+//
+//	fs.ReadFile(fsys, "db/prod#password")
+//
+// The key comes after any query parameters, as in a URL:
+//
+//	fs.ReadFile(fsys, "db/prod?stage=AWSPREVIOUS#password")
+//
+// # Filtering
+//
+// To restrict the filesystem to secrets with certain tags, add "tag:<key>"
+// query parameters to the URL, or use [WithTagFilterFS]. To restrict it to
+// secrets with descriptions starting with some text (ignoring case), add a
+// "description" query parameter. For example, for a filesystem that only
+// contains the production secrets of the payments team:
+//
+//	aws+sm:///app/?tag:team=payments&tag:env=prod
+//
+// Secrets that don't match the filters aren't listed, and can't be read. The
+// filters are sent to Secrets Manager with each ListSecrets request, so that
+// only matching secrets are listed. Reading a secret by name with filters set
+// needs an extra DescribeSecret request to check its tags and description.
+//
+// # Metadata
+//
+// Each secret's tags, description, KMS key, rotation settings, and the dates
+// it was last changed, rotated, and accessed are available from the
+// [SecretInfo] returned by the Sys method of the file's [fs.FileInfo]. This is
+// read from the secret's listing, or when filters are set, from the
+// DescribeSecret request that checks them. Files opened directly without
+// filters don't have this metadata, so no DescribeSecret permission is needed.
+//
+// # Listing Secrets
+//
+// Directory listings are read with ListSecrets, filtered by name prefix (and
+// any tag and description filters) on the server. Listing a directory reads
+// the value of each secret in it (to find its size), with BatchGetSecretValue
+// requests reading up to 20 secrets each. The number of concurrent requests
+// can be set with [WithMaxConcurrencyFS] or the AWS_SM_MAX_CONCURRENCY
+// environment variable, and defaults to 1. If the client doesn't support
+// BatchGetSecretValue (see [WithSMClientFS]), or the caller isn't allowed to
+// use it, the secrets are read one at a time instead.
+//
 // # Configuration
 //
 // The AWS Secrets Manager client is configured using the default credential
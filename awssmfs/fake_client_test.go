@@ -2,12 +2,16 @@ package awssmfs
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"math/rand"
+	"slices"
 	"sort"
 	"strconv"
 	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
@@ -15,13 +19,23 @@ import (
 )
 
 type fakeClient struct {
-	t       *testing.T
-	secrets map[string]*testVal
-	getErr  error
-	listErr error
+	t           *testing.T
+	secrets     map[string]*testVal
+	getErr      error
+	listErr     error
+	batchErr    error
+	describeErr error
+
+	// counters for checking how secrets are read
+	getCalls, batchCalls, describeCalls, inFlight, maxInFlight atomic.Int32
 }
 
-var _ SecretsManagerClient = (*fakeClient)(nil)
+var (
+	_ SecretsManagerClient = (*fakeClient)(nil)
+	_ secretVersionLister  = (*fakeClient)(nil)
+	_ batchSecretGetter    = (*fakeClient)(nil)
+	_ secretDescriber      = (*fakeClient)(nil)
+)
 
 func (c *fakeClient) GetSecretValue(
 	_ context.Context,
@@ -30,6 +44,8 @@ func (c *fakeClient) GetSecretValue(
 ) (*secretsmanager.GetSecretValueOutput, error) {
 	c.t.Helper()
 
+	c.getCalls.Add(1)
+
 	if c.getErr != nil {
 		return nil, c.getErr
 	}
@@ -38,6 +54,20 @@ func (c *fakeClient) GetSecretValue(
 	if val, ok := c.secrets[name]; ok {
 		out := secretsmanager.GetSecretValueOutput{Name: aws.String(name)}
 
+		if params.VersionId != nil || params.VersionStage != nil {
+			v := val.version(aws.ToString(params.VersionId), aws.ToString(params.VersionStage))
+			if v == nil {
+				return nil, &types.ResourceNotFoundException{
+					Message: aws.String("Secrets Manager can't find the specified secret value."),
+				}
+			}
+
+			out.VersionId = aws.String(v.id)
+			out.VersionStages = v.stages
+			out.CreatedDate = aws.Time(v.created)
+			val = v.val
+		}
+
 		if val.b != nil {
 			out.SecretBinary = make([]byte, len(val.b))
 			copy(out.SecretBinary, val.b)
@@ -67,11 +97,21 @@ func (c *fakeClient) ListSecrets(
 
 	nameFilter := ""
 
+	var (
+		tagKeys, tagValues []string
+		descFilter         string
+	)
+
 	for _, f := range params.Filters {
-		if f.Key == "name" {
+		switch f.Key {
+		case "name":
 			nameFilter = f.Values[0]
-
-			break
+		case "tag-key":
+			tagKeys = f.Values
+		case "tag-value":
+			tagValues = f.Values
+		case "description":
+			descFilter = f.Values[0]
 		}
 	}
 
@@ -91,10 +131,23 @@ func (c *fakeClient) ListSecrets(
 			cond = !strings.HasPrefix(k, nameFilter[1:])
 		}
 
+		// like Secrets Manager, tag keys and values are matched separately
+		val := c.secrets[k]
+		if tagKeys != nil && !slices.ContainsFunc(tagKeys, val.hasTagKey) {
+			cond = false
+		}
+
+		if tagValues != nil && !slices.ContainsFunc(tagValues, val.hasTagValue) {
+			cond = false
+		}
+
+		if descFilter != "" && (val.meta == nil ||
+			!strings.HasPrefix(strings.ToLower(val.meta.desc), strings.ToLower(descFilter))) {
+			cond = false
+		}
+
 		if cond {
-			secretList = append(secretList, types.SecretListEntry{
-				Name: aws.String(k),
-			})
+			secretList = append(secretList, val.listEntry(k))
 		}
 	}
 
@@ -136,6 +189,149 @@ func (c *fakeClient) ListSecrets(
 	}, nil
 }
 
+func (c *fakeClient) BatchGetSecretValue(
+	ctx context.Context,
+	params *secretsmanager.BatchGetSecretValueInput,
+	_ ...func(*secretsmanager.Options),
+) (*secretsmanager.BatchGetSecretValueOutput, error) {
+	c.t.Helper()
+
+	c.batchCalls.Add(1)
+
+	n := c.inFlight.Add(1)
+	defer c.inFlight.Add(-1)
+
+	for {
+		m := c.maxInFlight.Load()
+		if n <= m || c.maxInFlight.CompareAndSwap(m, n) {
+			break
+		}
+	}
+
+	// give concurrent requests a chance to overlap
+	time.Sleep(5 * time.Millisecond)
+
+	if c.batchErr != nil {
+		return nil, c.batchErr
+	}
+
+	if len(params.SecretIdList) > 20 {
+		return nil, &types.InvalidParameterException{Message: aws.String("too many secret IDs")}
+	}
+
+	out := &secretsmanager.BatchGetSecretValueOutput{}
+
+	for _, id := range params.SecretIdList {
+		c.getCalls.Add(-1) // don't count the batched reads as separate calls
+
+		v, err := c.GetSecretValue(ctx, &secretsmanager.GetSecretValueInput{SecretId: aws.String(id)})
+
+		var rnfErr *types.ResourceNotFoundException
+
+		switch {
+		case errors.As(err, &rnfErr):
+			out.Errors = append(out.Errors, types.APIErrorType{
+				SecretId:  aws.String(id),
+				ErrorCode: aws.String("ResourceNotFoundException"),
+				Message:   rnfErr.Message,
+			})
+		case err != nil:
+			return nil, err
+		default:
+			out.SecretValues = append(out.SecretValues, types.SecretValueEntry{
+				Name:         v.Name,
+				SecretString: v.SecretString,
+				SecretBinary: v.SecretBinary,
+				CreatedDate:  v.CreatedDate,
+			})
+		}
+	}
+
+	return out, nil
+}
+
+func (c *fakeClient) DescribeSecret(
+	_ context.Context,
+	params *secretsmanager.DescribeSecretInput,
+	_ ...func(*secretsmanager.Options),
+) (*secretsmanager.DescribeSecretOutput, error) {
+	c.t.Helper()
+
+	c.describeCalls.Add(1)
+
+	if c.describeErr != nil {
+		return nil, c.describeErr
+	}
+
+	if c.getErr != nil {
+		return nil, c.getErr
+	}
+
+	val, ok := c.secrets[*params.SecretId]
+	if !ok {
+		return nil, &types.ResourceNotFoundException{
+			Message: aws.String("Secrets Manager can't find the specified secret."),
+		}
+	}
+
+	entry := val.listEntry(*params.SecretId)
+
+	return &secretsmanager.DescribeSecretOutput{
+		Name:             entry.Name,
+		Description:      entry.Description,
+		KmsKeyId:         entry.KmsKeyId,
+		RotationEnabled:  entry.RotationEnabled,
+		RotationRules:    entry.RotationRules,
+		LastRotatedDate:  entry.LastRotatedDate,
+		LastAccessedDate: entry.LastAccessedDate,
+		Tags:             entry.Tags,
+	}, nil
+}
+
+func (c *fakeClient) ListSecretVersionIds(
+	_ context.Context,
+	params *secretsmanager.ListSecretVersionIdsInput,
+	_ ...func(*secretsmanager.Options),
+) (*secretsmanager.ListSecretVersionIdsOutput, error) {
+	c.t.Helper()
+
+	if c.listErr != nil {
+		return nil, c.listErr
+	}
+
+	val, ok := c.secrets[*params.SecretId]
+	if !ok {
+		return nil, &types.ResourceNotFoundException{
+			Message: aws.String("Secrets Manager can't find the specified secret."),
+		}
+	}
+
+	versions := val.versions
+
+	// return one version at a time so we trigger pagination
+	offset := 0
+	if params.NextToken != nil {
+		offset, _ = strconv.Atoi(*params.NextToken)
+	}
+
+	out := &secretsmanager.ListSecretVersionIdsOutput{}
+
+	if offset < len(versions) {
+		v := versions[offset]
+		out.Versions = []types.SecretVersionsListEntry{{
+			VersionId:     aws.String(v.id),
+			VersionStages: v.stages,
+			CreatedDate:   aws.Time(v.created),
+		}}
+	}
+
+	if offset+1 < len(versions) {
+		out.NextToken = aws.String(strconv.Itoa(offset + 1))
+	}
+
+	return out, nil
+}
+
 func clientWithValues(t *testing.T, secrets map[string]*testVal, errs ...error) *fakeClient {
 	t.Helper()
 
@@ -153,8 +349,78 @@ func clientWithValues(t *testing.T, secrets map[string]*testVal, errs ...error)
 }
 
 type testVal struct {
-	s string
-	b []byte
+	tags     map[string]string
+	meta     *testMeta
+	s        string
+	b        []byte
+	versions []testVersion
+}
+
+// testMeta holds the metadata of a secret
+type testMeta struct {
+	lastRotated time.Time
+	desc        string
+	kmsKeyID    string
+	schedule    string
+	rotation    bool
+}
+
+// listEntry returns the secret's entry as listed by ListSecrets
+func (v *testVal) listEntry(name string) types.SecretListEntry {
+	entry := types.SecretListEntry{Name: aws.String(name), Tags: v.tagList()}
+
+	if m := v.meta; m != nil {
+		entry.Description = aws.String(m.desc)
+		entry.KmsKeyId = aws.String(m.kmsKeyID)
+		entry.RotationEnabled = aws.Bool(m.rotation)
+		entry.RotationRules = &types.RotationRulesType{ScheduleExpression: aws.String(m.schedule)}
+		entry.LastRotatedDate = aws.Time(m.lastRotated)
+	}
+
+	return entry
+}
+
+func (v *testVal) hasTagKey(key string) bool {
+	_, ok := v.tags[key]
+
+	return ok
+}
+
+func (v *testVal) hasTagValue(value string) bool {
+	for _, tv := range v.tags {
+		if tv == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (v *testVal) tagList() []types.Tag {
+	tags := []types.Tag{}
+	for k, tv := range v.tags {
+		tags = append(tags, types.Tag{Key: aws.String(k), Value: aws.String(tv)})
+	}
+
+	return tags
+}
+
+type testVersion struct {
+	created time.Time
+	val     *testVal
+	id      string
+	stages  []string
+}
+
+// version returns the version with the given ID and/or staging label
+func (v *testVal) version(id, stage string) *testVersion {
+	for i, ver := range v.versions {
+		if (id == "" || ver.id == id) && (stage == "" || slices.Contains(ver.stages, stage)) {
+			return &v.versions[i]
+		}
+	}
+
+	return nil
 }
 
 func vs(s string) *testVal {
@@ -0,0 +1,138 @@
+package awssmfs
+
+import (
+	"fmt"
+	"io/fs"
+	"maps"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	smtypes "github.com/aws/aws-sdk-go-v2/service/secretsmanager/types"
+)
+
+const (
+	// tagParamPrefix prefixes the URL query parameters used to filter secrets
+	// by tag, as in "tag:team=payments"
+	tagParamPrefix = "tag:"
+
+	// descriptionParam is the URL query parameter used to filter secrets by
+	// description
+	descriptionParam = "description"
+)
+
+// filtersFromURL reads the tag and description filters from the URL's query
+// parameters
+func filtersFromURL(u *url.URL) (tags map[string]string, description string) {
+	q := u.Query()
+
+	for k := range q {
+		if key, ok := strings.CutPrefix(k, tagParamPrefix); ok && key != "" {
+			if tags == nil {
+				tags = map[string]string{}
+			}
+
+			tags[key] = q.Get(k)
+		}
+	}
+
+	return tags, q.Get(descriptionParam)
+}
+
+// filtered returns true if the file's secrets are filtered by tag or
+// description
+func (f *awssmFile) filtered() bool {
+	return len(f.tags) > 0 || f.description != ""
+}
+
+// listFilters returns the ListSecrets filters for secrets with names starting
+// with prefix, and with the file's tags and description
+func (f *awssmFile) listFilters(prefix string) []smtypes.Filter {
+	filters := []smtypes.Filter{{Key: "name", Values: []string{prefix}}}
+
+	// Secrets Manager matches tag keys and values separately (any secret with
+	// a tag with one of the keys, and a tag with one of the values), and by
+	// prefix, so the tags must also be checked with visible
+	if len(f.tags) > 0 {
+		keys := slices.Sorted(maps.Keys(f.tags))
+
+		values := make([]string, 0, len(keys))
+		for _, k := range keys {
+			values = append(values, f.tags[k])
+		}
+
+		filters = append(filters,
+			smtypes.Filter{Key: "tag-key", Values: keys},
+			smtypes.Filter{Key: "tag-value", Values: values},
+		)
+	}
+
+	if f.description != "" {
+		filters = append(filters, smtypes.Filter{Key: "description", Values: []string{f.description}})
+	}
+
+	return filters
+}
+
+// visible returns true if the secret has all of the file's tags, and a
+// description starting with the file's description filter (ignoring case, as
+// Secrets Manager does)
+func (f *awssmFile) visible(info *SecretInfo) bool {
+	for k, v := range f.tags {
+		if tv, ok := info.Tags[k]; !ok || tv != v {
+			return false
+		}
+	}
+
+	return strings.HasPrefix(strings.ToLower(info.Description), strings.ToLower(f.description))
+}
+
+// checkVisible returns an error wrapping fs.ErrNotExist if the file's secret
+// is hidden by the filters
+func (f *awssmFile) checkVisible() error {
+	if !f.filtered() {
+		return nil
+	}
+
+	info, err := f.describe()
+	if err != nil {
+		return err
+	}
+
+	if !f.visible(info) {
+		return fmt.Errorf("%w: secret doesn't match filters", fs.ErrNotExist)
+	}
+
+	return nil
+}
+
+// hasSecrets returns true if any secret is listed with a name starting with
+// prefix
+func (f *awssmFile) hasSecrets(prefix string) (bool, error) {
+	for token := (*string)(nil); ; {
+		params := secretsmanager.ListSecretsInput{Filters: f.listFilters(prefix), NextToken: token}
+
+		// without filters to check, any secret will do
+		if !f.filtered() {
+			params.MaxResults = aws.Int32(1)
+		}
+
+		secrets, err := f.client.ListSecrets(f.ctx, &params)
+		if err != nil {
+			return false, fmt.Errorf("listSecrets: %w", err)
+		}
+
+		for i := range secrets.SecretList {
+			if f.visible(secretInfo(&secrets.SecretList[i])) {
+				return true, nil
+			}
+		}
+
+		token = secrets.NextToken
+		if token == nil || !f.filtered() {
+			return false, nil
+		}
+	}
+}
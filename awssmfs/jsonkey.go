@@ -0,0 +1,60 @@
+package awssmfs
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+)
+
+// keySeparator separates a secret's name from the key to extract from its
+// JSON value. Secret names can't contain "#", so this can't clash with real
+// secrets.
+const keySeparator = "#"
+
+// secretValue returns the value of the secret, or only the value of the file's
+// JSON key in it, if set
+func (f *awssmFile) secretValue(secret *secretsmanager.GetSecretValueOutput) ([]byte, error) {
+	body := secret.SecretBinary
+
+	// May be a string
+	if secret.SecretString != nil {
+		body = []byte(*secret.SecretString)
+	}
+
+	if f.jsonKey == "" {
+		return body, nil
+	}
+
+	return extractJSONKey(body, f.jsonKey)
+}
+
+// extractJSONKey returns the value of the top-level key in the JSON object
+// held in body. String values are returned without quotes, and other values
+// (numbers, booleans, arrays, and objects) are returned as JSON.
+func extractJSONKey(body []byte, key string) ([]byte, error) {
+	obj := map[string]json.RawMessage{}
+
+	if err := json.Unmarshal(body, &obj); err != nil {
+		return nil, fmt.Errorf("%w: secret value is not a JSON object: %w", fs.ErrInvalid, err)
+	}
+
+	raw, ok := obj[key]
+	if !ok {
+		return nil, fmt.Errorf("%w: no key %q in secret", fs.ErrNotExist, key)
+	}
+
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return []byte(s), nil
+	}
+
+	out := &bytes.Buffer{}
+	if err := json.Compact(out, raw); err != nil {
+		return nil, fmt.Errorf("compact: %w", err)
+	}
+
+	return out.Bytes(), nil
+}
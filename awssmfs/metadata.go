@@ -0,0 +1,166 @@
+package awssmfs
+
+import (
+	"fmt"
+	"io/fs"
+	"path"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	smtypes "github.com/aws/aws-sdk-go-v2/service/secretsmanager/types"
+)
+
+// SecretInfo holds a secret's metadata. It's available from the Sys method of
+// the [fs.FileInfo] of secrets (though not of files in a secret's versions
+// directory, which have a [SecretVersion] instead).
+type SecretInfo struct {
+	// CreatedDate is the date the secret was created
+	CreatedDate time.Time
+
+	// LastChangedDate is the date the secret was last changed
+	LastChangedDate time.Time
+
+	// LastAccessedDate is the date the secret was last read (rounded to the
+	// day by Secrets Manager)
+	LastAccessedDate time.Time
+
+	// LastRotatedDate is the date the secret was last rotated
+	LastRotatedDate time.Time
+
+	// NextRotationDate is the date the secret is next scheduled to be rotated
+	NextRotationDate time.Time
+
+	// Tags holds the secret's tags
+	Tags map[string]string
+
+	// ARN is the secret's ARN
+	ARN string
+
+	// Description is the secret's description
+	Description string
+
+	// KMSKeyID is the ID or ARN of the KMS key used to encrypt the secret,
+	// or empty if the AWS managed key is used
+	KMSKeyID string
+
+	// RotationLambdaARN is the ARN of the Lambda function that rotates the
+	// secret
+	RotationLambdaARN string
+
+	// RotationSchedule is the cron() or rate() expression defining the
+	// rotation schedule
+	RotationSchedule string
+
+	// RotationWindow is the length of the rotation window, such as "3h"
+	RotationWindow string
+
+	// RotationAfterDays is the number of days between rotations
+	RotationAfterDays int64
+
+	// RotationEnabled is true if automatic rotation is enabled
+	RotationEnabled bool
+}
+
+// secretInfo returns the metadata of a listed secret
+func secretInfo(entry *smtypes.SecretListEntry) *SecretInfo {
+	info := &SecretInfo{
+		CreatedDate:       aws.ToTime(entry.CreatedDate),
+		LastChangedDate:   aws.ToTime(entry.LastChangedDate),
+		LastAccessedDate:  aws.ToTime(entry.LastAccessedDate),
+		LastRotatedDate:   aws.ToTime(entry.LastRotatedDate),
+		NextRotationDate:  aws.ToTime(entry.NextRotationDate),
+		Tags:              make(map[string]string, len(entry.Tags)),
+		ARN:               aws.ToString(entry.ARN),
+		Description:       aws.ToString(entry.Description),
+		KMSKeyID:          aws.ToString(entry.KmsKeyId),
+		RotationLambdaARN: aws.ToString(entry.RotationLambdaARN),
+		RotationEnabled:   aws.ToBool(entry.RotationEnabled),
+	}
+
+	for _, tag := range entry.Tags {
+		info.Tags[aws.ToString(tag.Key)] = aws.ToString(tag.Value)
+	}
+
+	if rules := entry.RotationRules; rules != nil {
+		info.RotationSchedule = aws.ToString(rules.ScheduleExpression)
+		info.RotationWindow = aws.ToString(rules.Duration)
+		info.RotationAfterDays = aws.ToInt64(rules.AutomaticallyAfterDays)
+	}
+
+	return info
+}
+
+// describe returns the metadata of the file's secret, reading it with
+// DescribeSecret unless it's already known, or the client doesn't support it
+func (f *awssmFile) describe() (*SecretInfo, error) {
+	if f.info != nil {
+		return f.info, nil
+	}
+
+	secretID := f.secretID
+	if secretID == "" {
+		secretID = path.Join(f.root, f.name)
+	}
+
+	describer, ok := f.client.(secretDescriber)
+	if !ok {
+		info, err := f.findSecret(secretID)
+		if err != nil {
+			return nil, err
+		}
+
+		f.info = info
+
+		return f.info, nil
+	}
+
+	out, err := describer.DescribeSecret(f.ctx, &secretsmanager.DescribeSecretInput{
+		SecretId: aws.String(secretID),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("describeSecret: %w", convertAWSError(err))
+	}
+
+	f.info = secretInfo(&smtypes.SecretListEntry{
+		ARN:               out.ARN,
+		CreatedDate:       out.CreatedDate,
+		Description:       out.Description,
+		KmsKeyId:          out.KmsKeyId,
+		LastAccessedDate:  out.LastAccessedDate,
+		LastChangedDate:   out.LastChangedDate,
+		LastRotatedDate:   out.LastRotatedDate,
+		NextRotationDate:  out.NextRotationDate,
+		RotationEnabled:   out.RotationEnabled,
+		RotationLambdaARN: out.RotationLambdaARN,
+		RotationRules:     out.RotationRules,
+		Tags:              out.Tags,
+	})
+
+	return f.info, nil
+}
+
+// findSecret returns the metadata of the named secret, found by listing the
+// secrets with names starting with its name
+func (f *awssmFile) findSecret(name string) (*SecretInfo, error) {
+	for token := (*string)(nil); ; {
+		secrets, err := f.client.ListSecrets(f.ctx, &secretsmanager.ListSecretsInput{
+			Filters:   []smtypes.Filter{{Key: "name", Values: []string{name}}},
+			NextToken: token,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("listSecrets: %w", convertAWSError(err))
+		}
+
+		for i := range secrets.SecretList {
+			if aws.ToString(secrets.SecretList[i].Name) == name {
+				return secretInfo(&secrets.SecretList[i]), nil
+			}
+		}
+
+		token = secrets.NextToken
+		if token == nil {
+			return nil, fmt.Errorf("%w: secret %s not found", fs.ErrNotExist, name)
+		}
+	}
+}
@@ -14,3 +14,30 @@ type SecretsManagerClient interface {
 		params *secretsmanager.GetSecretValueInput,
 		optFns ...func(*secretsmanager.Options)) (*secretsmanager.GetSecretValueOutput, error)
 }
+
+// secretVersionLister is an optional interface for clients which can list
+// the versions of a secret (such as [*secretsmanager.Client]), needed for the
+// versions directory
+type secretVersionLister interface {
+	ListSecretVersionIds(ctx context.Context,
+		params *secretsmanager.ListSecretVersionIdsInput,
+		optFns ...func(*secretsmanager.Options)) (*secretsmanager.ListSecretVersionIdsOutput, error)
+}
+
+// batchSecretGetter is an optional interface for clients which can read
+// several secrets in one request (such as [*secretsmanager.Client]). Secrets
+// are read one at a time with other clients.
+type batchSecretGetter interface {
+	BatchGetSecretValue(ctx context.Context,
+		params *secretsmanager.BatchGetSecretValueInput,
+		optFns ...func(*secretsmanager.Options)) (*secretsmanager.BatchGetSecretValueOutput, error)
+}
+
+// secretDescriber is an optional interface for clients which can read a
+// single secret's metadata (such as [*secretsmanager.Client]). With other
+// clients, the secret is found with ListSecrets instead.
+type secretDescriber interface {
+	DescribeSecret(ctx context.Context,
+		params *secretsmanager.DescribeSecretInput,
+		optFns ...func(*secretsmanager.Options)) (*secretsmanager.DescribeSecretOutput, error)
+}
@@ -0,0 +1,234 @@
+package awssmfs
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	smtypes "github.com/aws/aws-sdk-go-v2/service/secretsmanager/types"
+	"github.com/hairyhenderson/go-fsimpl/internal"
+)
+
+const (
+	// stageParam is the query parameter used to read the version of a secret
+	// with a given staging label (such as "AWSPREVIOUS")
+	stageParam = "stage"
+
+	// versionParam is the query parameter used to read the version of a
+	// secret with a given version ID
+	versionParam = "version"
+
+	// versionsSuffix is appended to a secret's name to address the virtual
+	// directory listing its versions. Secret names can't contain ":", so this
+	// can't clash with real secrets.
+	versionsSuffix = ":versions"
+)
+
+// SecretVersion describes a version of a secret. It's available from the Sys
+// method of the [fs.FileInfo] of files in a secret's versions directory.
+type SecretVersion struct {
+	// CreatedDate is the date the version was created
+	CreatedDate time.Time
+
+	// ID is the version's unique identifier
+	ID string
+
+	// Stages holds the version's staging labels, such as "AWSCURRENT",
+	// "AWSPREVIOUS", or "AWSPENDING"
+	Stages []string
+}
+
+// versionFromQuery parses the "stage" and "version" query parameters from the
+// query part of a file name, if present
+func versionFromQuery(rawQuery string) (stage, version string, err error) {
+	if rawQuery == "" {
+		return "", "", nil
+	}
+
+	q, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return "", "", fmt.Errorf("%w: %w", fs.ErrInvalid, err)
+	}
+
+	return q.Get(stageParam), q.Get(versionParam), nil
+}
+
+// setVersions configures f to address a secret's versions, when its full path
+// refers to the versions directory of a secret, or to a file in one
+func (f *awssmFile) setVersions() {
+	full := path.Join(f.root, f.name)
+
+	if secretID, ok := strings.CutSuffix(full, versionsSuffix); ok {
+		f.versions = true
+		f.secretID = secretID
+
+		return
+	}
+
+	if secretID, ok := strings.CutSuffix(path.Dir(full), versionsSuffix); ok {
+		f.secretID = secretID
+		f.version = path.Base(full)
+	}
+}
+
+// secretValueInput returns the input for reading the file's secret, and the
+// version selected by its stage or version ID, if any
+func (f *awssmFile) secretValueInput() *secretsmanager.GetSecretValueInput {
+	input := &secretsmanager.GetSecretValueInput{SecretId: aws.String(f.secretID)}
+	if f.secretID == "" {
+		input.SecretId = aws.String(path.Join(f.root, f.name))
+	}
+
+	if f.stage != "" {
+		input.VersionStage = aws.String(f.stage)
+	}
+
+	if f.version != "" {
+		input.VersionId = aws.String(f.version)
+	}
+
+	return input
+}
+
+// versionLister returns the client as a secretVersionLister, or an error if
+// the client can't list secret versions
+func (f *awssmFile) versionLister() (secretVersionLister, error) {
+	lister, ok := f.client.(secretVersionLister)
+	if !ok {
+		return nil, fmt.Errorf("%w: client can't list secret versions", errors.ErrUnsupported)
+	}
+
+	return lister, nil
+}
+
+// statsFromVersions returns true if the file can be stat'd from its secret's
+// version listing, without reading its value: for the versions directory
+// itself, and for the files in it (unless a JSON key is selected, as the size
+// of the key's value isn't listed)
+func (f *awssmFile) statsFromVersions() bool {
+	if f.versions {
+		return true
+	}
+
+	_, ok := f.client.(secretVersionLister)
+
+	return ok && f.secretID != "" && f.jsonKey == ""
+}
+
+// statVersions stats the versions directory, or a file in it, from the
+// secret's version listing. The secret's values aren't read, so the sizes of
+// the files in the directory aren't known.
+func (f *awssmFile) statVersions() (fs.FileInfo, error) {
+	lister, err := f.versionLister()
+	if err != nil {
+		return nil, err
+	}
+
+	if err = f.checkVisible(); err != nil {
+		return nil, err
+	}
+
+	input := &secretsmanager.ListSecretVersionIdsInput{SecretId: aws.String(f.secretID)}
+
+	// only the secret's existence needs to be checked for the directory
+	if f.versions {
+		input.MaxResults = aws.Int32(1)
+	}
+
+	for {
+		versions, err := lister.ListSecretVersionIds(f.ctx, input)
+		if err != nil {
+			return nil, fmt.Errorf("listSecretVersionIds: %w", convertAWSError(err))
+		}
+
+		if f.versions {
+			return internal.DirInfo(f.name, time.Time{}), nil
+		}
+
+		for i := range versions.Versions {
+			if aws.ToString(versions.Versions[i].VersionId) == f.version {
+				return versionInfo(f.name, &versions.Versions[i]), nil
+			}
+		}
+
+		input.NextToken = versions.NextToken
+		if input.NextToken == nil {
+			return nil, fmt.Errorf("%w: version %s not found", fs.ErrNotExist, f.version)
+		}
+	}
+}
+
+// versionInfo returns the FileInfo of a file in a versions directory, from
+// the version's listing. The size is unknown (-1), as the value isn't read.
+func versionInfo(name string, entry *smtypes.SecretVersionsListEntry) fs.FileInfo {
+	created := aws.ToTime(entry.CreatedDate)
+
+	// secret versions are immutable, so the created date for this version
+	// is also the last modified date
+	return internal.FileInfoWithSys(name, -1, 0o444, created, "", &SecretVersion{
+		CreatedDate: created,
+		ID:          aws.ToString(entry.VersionId),
+		Stages:      entry.VersionStages,
+	})
+}
+
+// listVersions assigns the secret's versions to f.children, sorted by version
+// ID (the staging labels and creation dates are available from each file's
+// SecretVersion). The versions' values are only read when the files are read.
+func (f *awssmFile) listVersions() error {
+	lister, err := f.versionLister()
+	if err != nil {
+		return err
+	}
+
+	if err = f.checkVisible(); err != nil {
+		return err
+	}
+
+	for token := (*string)(nil); ; {
+		versions, err := lister.ListSecretVersionIds(f.ctx, &secretsmanager.ListSecretVersionIdsInput{
+			SecretId:  aws.String(f.secretID),
+			NextToken: token,
+		})
+		if err != nil {
+			return fmt.Errorf("listSecretVersionIds: %w", convertAWSError(err))
+		}
+
+		for i := range versions.Versions {
+			id := aws.ToString(versions.Versions[i].VersionId)
+
+			f.children = append(f.children, &awssmFile{
+				ctx:      f.ctx,
+				fi:       versionInfo(id, &versions.Versions[i]),
+				name:     id,
+				root:     path.Join(f.root, f.name),
+				client:   f.client,
+				secretID: f.secretID,
+				version:  id,
+
+				// the secret has already been checked against the filters
+				tags:        f.tags,
+				description: f.description,
+				info:        f.info,
+			})
+		}
+
+		token = versions.NextToken
+		if token == nil {
+			break
+		}
+	}
+
+	sort.Slice(f.children, func(i, j int) bool {
+		return f.children[i].name < f.children[j].name
+	})
+
+	return nil
+}
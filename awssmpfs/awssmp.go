@@ -2,6 +2,7 @@ package awssmpfs
 
 import (
 	"context"
+	"crypto/tls"
 	"errors"
 	"fmt"
 	"io"
@@ -51,9 +52,13 @@ type awssmpFS struct {
 	ctx        context.Context
 	base       *url.URL
 	httpclient *http.Client
+	tlsConfig  *tls.Config
 	ssmclient  SSMClient
 	imdsfs     fs.FS
 	root       string
+	// builtClient is set when ssmclient was created by getClient (rather than
+	// set with WithClient), so must be recreated when the TLS config changes
+	builtClient bool
 }
 
 // New provides a filesystem (an [io/fs.FS]) backed by the AWS Systems Manager
@@ -73,6 +78,11 @@ func New(u *url.URL) (fs.FS, error) {
 		u.Path = "/"
 	}
 
+	tlsConfig, err := internal.TLSConfigFromURL(u)
+	if err != nil {
+		return nil, fmt.Errorf("invalid TLS configuration: %w", err)
+	}
+
 	iu, _ := url.Parse("aws+imds:")
 
 	imdsfs, err := awsimdsfs.New(iu)
@@ -81,10 +91,11 @@ func New(u *url.URL) (fs.FS, error) {
 	}
 
 	return &awssmpFS{
-		ctx:    context.Background(),
-		base:   u,
-		root:   u.Path,
-		imdsfs: imdsfs,
+		ctx:       context.Background(),
+		base:      u,
+		root:      u.Path,
+		imdsfs:    imdsfs,
+		tlsConfig: tlsConfig,
 	}, nil
 }
 
@@ -102,6 +113,7 @@ var (
 	_ internal.WithHTTPClienter = (*awssmpFS)(nil)
 	_ withClienter              = (*awssmpFS)(nil)
 	_ internal.WithIMDSFSer     = (*awssmpFS)(nil)
+	_ internal.WithTLSConfiger  = (*awssmpFS)(nil)
 )
 
 func (f awssmpFS) URL() string {
@@ -130,6 +142,22 @@ func (f *awssmpFS) WithHTTPClient(client *http.Client) fs.FS {
 	return &fsys
 }
 
+func (f *awssmpFS) WithTLSConfig(config *tls.Config) fs.FS {
+	if config == nil {
+		return f
+	}
+
+	fsys := *f
+	fsys.tlsConfig = config
+
+	if fsys.builtClient {
+		fsys.ssmclient = nil
+		fsys.builtClient = false
+	}
+
+	return &fsys
+}
+
 func (f *awssmpFS) WithClient(ssmclient SSMClient) fs.FS {
 	if ssmclient == nil {
 		return f
@@ -137,6 +165,7 @@ func (f *awssmpFS) WithClient(ssmclient SSMClient) fs.FS {
 
 	fsys := *f
 	fsys.ssmclient = ssmclient
+	fsys.builtClient = false
 
 	return &fsys
 }
@@ -157,9 +186,14 @@ func (f *awssmpFS) getClient(ctx context.Context) (SSMClient, error) {
 		return f.ssmclient, nil
 	}
 
+	httpclient, err := internal.HTTPClientWithTLSConfig(f.httpclient, f.tlsConfig)
+	if err != nil {
+		return nil, err
+	}
+
 	opts := [](func(*config.LoadOptions) error){}
-	if f.httpclient != nil {
-		opts = append(opts, config.WithHTTPClient(f.httpclient))
+	if httpclient != nil {
+		opts = append(opts, config.WithHTTPClient(httpclient))
 	}
 
 	cfg, err := config.LoadDefaultConfig(ctx, opts...)
@@ -187,6 +221,7 @@ func (f *awssmpFS) getClient(ctx context.Context) (SSMClient, error) {
 	}
 
 	f.ssmclient = ssm.NewFromConfig(cfg, optFns...)
+	f.builtClient = true
 
 	return f.ssmclient, nil
 }
@@ -1,13 +1,19 @@
 package awssmpfs
 
 import (
+	"crypto/tls"
+	"crypto/x509"
 	"io"
 	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
 	"testing"
 	"testing/fstest"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/ssm/types"
+	"github.com/hairyhenderson/go-fsimpl"
 	"github.com/hairyhenderson/go-fsimpl/internal/tests"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -227,3 +233,45 @@ func TestAWSSMPFS_ReadDir(t *testing.T) {
 	require.NoError(t, err)
 	assert.Len(t, de, 3)
 }
+
+func TestAWSSMPFS_TLSConfig(t *testing.T) {
+	var reqs atomic.Int32
+
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reqs.Add(1)
+
+		assert.Equal(t, "AmazonSSM.GetParameter", r.Header.Get("X-Amz-Target"))
+
+		w.Header().Set("Content-Type", "application/x-amz-json-1.1")
+		_, _ = w.Write([]byte(`{"Parameter":{"Name":"/foo","Type":"String","Value":"bar"}}`))
+	}))
+	t.Cleanup(srv.Close)
+
+	t.Setenv("AWS_ENDPOINT_URL_SSM", srv.URL)
+	t.Setenv("AWS_REGION", "us-east-1")
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKIAEXAMPLE")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+	// a CA bundle can't be combined with a custom HTTP client
+	t.Setenv("AWS_CA_BUNDLE", "")
+	t.Setenv("AWS_MAX_ATTEMPTS", "1")
+
+	fsys, err := New(tests.MustURL("aws+smp:///"))
+	require.NoError(t, err)
+
+	fsys = fsimpl.WithContextFS(t.Context(), fsys)
+
+	// the server's certificate isn't trusted by default
+	_, err = fs.ReadFile(fsys, "foo")
+	require.Error(t, err)
+	assert.Equal(t, int32(0), reqs.Load())
+
+	pool := x509.NewCertPool()
+	pool.AddCert(srv.Certificate())
+
+	fsys = fsimpl.WithTLSConfigFS(&tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}, fsys)
+
+	b, err := fs.ReadFile(fsys, "foo")
+	require.NoError(t, err)
+	assert.Equal(t, "bar", string(b))
+	assert.Equal(t, int32(1), reqs.Load())
+}
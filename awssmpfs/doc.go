@@ -45,6 +45,7 @@
 //
 //   - [fsimpl.WithContextFS]
 //   - [fsimpl.WithHTTPClientFS]
+//   - [fsimpl.WithTLSConfigFS]
 //   - [WithClientFS]
 //
 // [1]: https://docs.aws.amazon.com/systems-manager/latest/userguide/sysman-paramstore-su-create.html#sysman-parameter-name-constraints
@@ -2,6 +2,7 @@ package blobfs
 
 import (
 	"context"
+	"crypto/tls"
 	"errors"
 	"fmt"
 	"io"
@@ -31,10 +32,14 @@ type blobFS struct {
 	ctx     context.Context
 	base    *url.URL
 	hclient *http.Client
+	tls     *tls.Config
 	bucket  *blob.Bucket
 	envfs   fs.FS
 	imdsfs  fs.FS
-	root    string
+	// clientErr records a failure to configure the HTTP client, returned when
+	// the filesystem is used
+	clientErr error
+	root      string
 }
 
 // Some blob APIs don't return valid modTimes, and some do. To conform to fstest
@@ -54,6 +59,11 @@ func New(u *url.URL) (fs.FS, error) {
 		return nil, fmt.Errorf("invalid URL scheme %q", u.Scheme)
 	}
 
+	tlsConfig, err := internal.TLSConfigFromURL(u)
+	if err != nil {
+		return nil, fmt.Errorf("invalid TLS configuration: %w", err)
+	}
+
 	root := strings.TrimPrefix(u.Path, "/")
 
 	iu, _ := url.Parse("aws+imds:")
@@ -63,14 +73,20 @@ func New(u *url.URL) (fs.FS, error) {
 		return nil, fmt.Errorf("couldn't create IMDS filesystem: %w", err)
 	}
 
-	return &blobFS{
-		ctx:     context.Background(),
-		base:    u,
-		hclient: http.DefaultClient,
-		root:    root,
-		envfs:   os.DirFS("/"),
-		imdsfs:  imdsfs,
-	}, nil
+	fsys := &blobFS{
+		ctx:    context.Background(),
+		base:   u,
+		tls:    tlsConfig,
+		root:   root,
+		envfs:  os.DirFS("/"),
+		imdsfs: imdsfs,
+	}
+	fsys.hclient, err = fsys.configureClient(http.DefaultClient)
+	if err != nil {
+		return nil, err
+	}
+
+	return fsys, nil
 }
 
 // FS is used to register this filesystem with an fsimpl.FSMux
@@ -85,6 +101,7 @@ var (
 	_ internal.WithContexter    = (*blobFS)(nil)
 	_ internal.WithHTTPClienter = (*blobFS)(nil)
 	_ internal.WithIMDSFSer     = (*blobFS)(nil)
+	_ internal.WithTLSConfiger  = (*blobFS)(nil)
 )
 
 func (f blobFS) URL() string {
@@ -108,11 +125,34 @@ func (f *blobFS) WithHTTPClient(client *http.Client) fs.FS {
 	}
 
 	fsys := *f
-	fsys.hclient = client
+	fsys.hclient, fsys.clientErr = fsys.configureClient(client)
 
 	return &fsys
 }
 
+func (f *blobFS) WithTLSConfig(config *tls.Config) fs.FS {
+	if config == nil {
+		return f
+	}
+
+	fsys := *f
+	fsys.tls = config
+	fsys.hclient, fsys.clientErr = fsys.configureClient(fsys.hclient)
+
+	return &fsys
+}
+
+// configureClient returns a copy of client configured with the filesystem's
+// TLS configuration, if set.
+func (f *blobFS) configureClient(client *http.Client) (*http.Client, error) {
+	c, err := internal.HTTPClientWithTLSConfig(client, f.tls)
+	if err != nil {
+		return nil, fmt.Errorf("unable to apply TLS configuration to HTTP client: %w", err)
+	}
+
+	return c, nil
+}
+
 func (f *blobFS) WithIMDSFS(imdsfs fs.FS) fs.FS {
 	if imdsfs == nil {
 		return f
@@ -125,6 +165,10 @@ func (f *blobFS) WithIMDSFS(imdsfs fs.FS) fs.FS {
 }
 
 func (f *blobFS) openBucket() (*blob.Bucket, error) {
+	if f.clientErr != nil {
+		return nil, f.clientErr
+	}
+
 	o, err := f.newOpener(f.ctx, f.base.Scheme)
 	if err != nil {
 		return nil, fmt.Errorf("bucket opener: %w", err)
@@ -218,7 +262,14 @@ func (f *blobFS) newOpener(ctx context.Context, scheme string) (opener blob.Buck
 	switch scheme {
 	case s3blob.Scheme:
 		// see https://gocloud.dev/concepts/urls/#muxes
-		return &s3v2URLOpener{imdsfs: f.imdsfs}, nil
+		o := &s3v2URLOpener{imdsfs: f.imdsfs}
+
+		// only override the SDK's default HTTP client when necessary
+		if f.tls != nil {
+			o.hclient = f.hclient
+		}
+
+		return o, nil
 	case gcsblob.Scheme:
 		if env.GetenvFS(f.envfs, "GOOGLE_ANON") == "true" {
 			return &gcsblob.URLOpener{
@@ -3,7 +3,9 @@ package blobfs
 import (
 	"bytes"
 	"context"
+	"crypto/tls"
 	"io/fs"
+	"net/http"
 	"net/http/httptest"
 	"net/url"
 	"os"
@@ -239,6 +241,24 @@ func TestBlobFS_ReadDir(t *testing.T) {
 	assert.Equal(t, fs.FileMode(0o444), fi.Mode())
 }
 
+func TestBlobFS_TLSConfig(t *testing.T) {
+	srvURL := setupTestS3Bucket(t)
+
+	t.Setenv("AWS_ANON", "true")
+
+	fsys, err := New(tests.MustURL("s3://mybucket/?region=us-east-1&disableSSL=true&s3ForcePathStyle=true&endpoint=" + srvURL.Host))
+	require.NoError(t, err)
+
+	fsys = fsimpl.WithTLSConfigFS(&tls.Config{MinVersion: tls.VersionTLS12}, fsys)
+
+	// the TLS config can't be applied to other transports, so they aren't
+	// silently used without it
+	fsys = fsimpl.WithHTTPClientFS(&http.Client{Transport: http.NewFileTransport(http.Dir("."))}, fsys)
+
+	_, err = fs.ReadFile(fsys, "file1")
+	require.ErrorContains(t, err, "TLS configuration")
+}
+
 func TestBlobFS_CleanCdkURL(t *testing.T) {
 	b := &blobFS{}
 
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"io/fs"
+	"net/http"
 	"net/url"
 	"strconv"
 	"strings"
@@ -25,7 +26,8 @@ import (
 var _ blob.BucketURLOpener = (*s3v2URLOpener)(nil)
 
 type s3v2URLOpener struct {
-	imdsfs fs.FS
+	imdsfs  fs.FS
+	hclient *http.Client
 	// Options specifies the options to pass to OpenBucket.
 	Options s3blob.Options
 }
@@ -132,6 +134,10 @@ func (o *s3v2URLOpener) OpenBucketURL(ctx context.Context, u *url.URL) (*blob.Bu
 		cfg.Region = string(region)
 	}
 
+	if o.hclient != nil {
+		cfg.HTTPClient = o.hclient
+	}
+
 	clientV2 := s3v2.NewFromConfig(cfg, opts...)
 
 	return s3blob.OpenBucketV2(ctx, clientV2, u.Host, &o.Options)
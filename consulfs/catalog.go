@@ -0,0 +1,469 @@
+package consulfs
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/hairyhenderson/go-fsimpl/internal"
+	"github.com/hashicorp/consul/api/v2"
+)
+
+const (
+	// catalogScheme is the URL scheme for the service catalog filesystem
+	catalogScheme = "consul+catalog"
+
+	// passingParam is the URL query parameter used to include only service
+	// instances which are passing their health checks
+	passingParam = "passing"
+
+	jsonContentType = "application/json"
+)
+
+// ServiceInstance describes an instance of a service registered in the Consul
+// catalog. It is the content (as JSON) of the files in the "services/<name>/"
+// directories of the catalog filesystem, and is also returned by the Sys
+// method of their [fs.FileInfo].
+type ServiceInstance struct {
+	// Meta is the service instance's metadata.
+	Meta map[string]string `json:"meta,omitempty"`
+
+	// ID and Service are the service instance's ID and the service's name.
+	ID      string `json:"id"`
+	Service string `json:"service"`
+
+	// Address is the service instance's address, or the node's address if the
+	// service doesn't have its own.
+	Address string `json:"address"`
+
+	// Node, NodeID, and NodeAddress identify the node the instance runs on.
+	Node        string `json:"node"`
+	NodeID      string `json:"node_id"`
+	NodeAddress string `json:"node_address"`
+
+	// Datacenter is the datacenter the node is in.
+	Datacenter string `json:"datacenter"`
+
+	// Status is the aggregated status of the instance's health checks - one
+	// of "passing", "warning", "critical", or "maintenance".
+	Status string `json:"status"`
+
+	// Tags are the service instance's tags.
+	Tags []string `json:"tags"`
+
+	// Port is the service instance's port.
+	Port int `json:"port"`
+}
+
+// NodeInfo describes a node registered in the Consul catalog. It is the
+// content (as JSON) of the files in the "nodes/" directory of the catalog
+// filesystem, and is also returned by the Sys method of their [fs.FileInfo].
+type NodeInfo struct {
+	// TaggedAddresses are the node's additional addresses, such as "lan" and
+	// "wan".
+	TaggedAddresses map[string]string `json:"tagged_addresses,omitempty"`
+
+	// Meta is the node's metadata.
+	Meta map[string]string `json:"meta,omitempty"`
+
+	ID         string `json:"id"`
+	Node       string `json:"node"`
+	Address    string `json:"address"`
+	Datacenter string `json:"datacenter"`
+}
+
+// isCatalog reports whether u addresses the service catalog rather than the
+// KV store
+func isCatalog(u *url.URL) bool {
+	return strings.HasPrefix(u.Scheme, catalogScheme)
+}
+
+// passingOnlyFromURL reads the "passing" query parameter from u
+func passingOnlyFromURL(u *url.URL) (bool, error) {
+	q := u.Query()
+	if !q.Has(passingParam) {
+		return false, nil
+	}
+
+	passing, err := strconv.ParseBool(q.Get(passingParam))
+	if err != nil {
+		return false, fmt.Errorf("invalid %s parameter %q: %w", passingParam, q.Get(passingParam), fs.ErrInvalid)
+	}
+
+	return passing, nil
+}
+
+// catalogFile is a file or directory in the service catalog filesystem. The
+// catalog is read when the file is first read or stat'd.
+type catalogFile struct {
+	ctx       context.Context
+	fi        fs.FileInfo
+	body      io.Reader
+	client    *api.Client
+	queryOpts *api.QueryOptions
+	name      string
+	parts     []string
+	children  []fs.DirEntry
+	diridx    int
+
+	passingOnly bool
+
+	closed atomic.Int32
+}
+
+var _ fs.ReadDirFile = (*catalogFile)(nil)
+
+func newCatalogFile(ctx context.Context, name string, u *url.URL, client *api.Client,
+	queryOpts *api.QueryOptions, passingOnly bool,
+) *catalogFile {
+	var parts []string
+	if p := strings.Trim(u.Path, "/"); p != "" {
+		parts = strings.Split(p, "/")
+	}
+
+	return &catalogFile{
+		ctx:         ctx,
+		name:        name,
+		parts:       parts,
+		client:      client,
+		queryOpts:   queryOpts,
+		passingOnly: passingOnly,
+	}
+}
+
+// Close the file. Will error on second call.
+func (f *catalogFile) Close() error {
+	if f.closed.Load() == 1 {
+		return &fs.PathError{Op: "close", Path: f.name, Err: fs.ErrClosed}
+	}
+
+	f.closed.Store(1)
+
+	return nil
+}
+
+func (f *catalogFile) Read(p []byte) (int, error) {
+	if err := f.load(); err != nil {
+		return 0, &fs.PathError{Op: "read", Path: f.name, Err: err}
+	}
+
+	if f.fi.IsDir() {
+		return 0, &fs.PathError{Op: "read", Path: f.name, Err: fs.ErrInvalid}
+	}
+
+	return f.body.Read(p)
+}
+
+func (f *catalogFile) Stat() (fs.FileInfo, error) {
+	if err := f.load(); err != nil {
+		return nil, &fs.PathError{Op: "stat", Path: f.name, Err: err}
+	}
+
+	return f.fi, nil
+}
+
+func (f *catalogFile) ReadDir(n int) ([]fs.DirEntry, error) {
+	if err := f.load(); err != nil {
+		return nil, &fs.PathError{Op: "readDir", Path: f.name, Err: err}
+	}
+
+	if !f.fi.IsDir() {
+		return nil, &fs.PathError{Op: "readDir", Path: f.name, Err: fs.ErrInvalid}
+	}
+
+	remaining := f.children[f.diridx:]
+
+	if n <= 0 {
+		f.diridx = len(f.children)
+
+		return slices.Clone(remaining), nil
+	}
+
+	if len(remaining) == 0 {
+		return []fs.DirEntry{}, io.EOF
+	}
+
+	remaining = remaining[:min(n, len(remaining))]
+	f.diridx += len(remaining)
+
+	return slices.Clone(remaining), nil
+}
+
+// load reads the part of the catalog the file refers to - either the file's
+// content, or the directory's entries
+func (f *catalogFile) load() error {
+	if f.fi != nil {
+		return nil
+	}
+
+	base := path.Base(f.name)
+
+	switch {
+	case len(f.parts) == 0:
+		f.setDir(base, []fs.DirEntry{dirEntry("nodes"), dirEntry("services")})
+	case f.parts[0] == "services" && len(f.parts) == 1:
+		return f.loadServices(base)
+	case f.parts[0] == "services" && len(f.parts) <= 3:
+		return f.loadServiceInstances(base)
+	case f.parts[0] == "nodes" && len(f.parts) <= 2:
+		return f.loadNodes(base)
+	default:
+		return fs.ErrNotExist
+	}
+
+	return nil
+}
+
+func (f *catalogFile) setDir(name string, children []fs.DirEntry) {
+	f.fi = internal.DirInfo(name, time.Time{})
+	f.children = children
+}
+
+func (f *catalogFile) setFile(name string, b []byte, sys any) {
+	f.fi = internal.FileInfoWithSys(name, int64(len(b)), 0o444, time.Time{}, jsonContentType, sys)
+	f.body = bytes.NewReader(b)
+}
+
+func (f *catalogFile) loadServices(name string) error {
+	services, _, err := f.client.Catalog().Services(f.queryOpts.WithContext(f.ctx))
+	if err != nil {
+		return fmt.Errorf("catalog.Services: %w", err)
+	}
+
+	names := make([]string, 0, len(services))
+	for s := range services {
+		names = append(names, s)
+	}
+
+	slices.Sort(names)
+
+	children := make([]fs.DirEntry, len(names))
+	for i, s := range names {
+		children[i] = dirEntry(s)
+	}
+
+	f.setDir(name, children)
+
+	return nil
+}
+
+// loadServiceInstances loads the directory of a service's instances, or a
+// single instance
+func (f *catalogFile) loadServiceInstances(name string) error {
+	entries, _, err := f.client.Health().Service(f.parts[1], "", f.passingOnly, f.queryOpts.WithContext(f.ctx))
+	if err != nil {
+		return fmt.Errorf("health.Service: %w", err)
+	}
+
+	if len(entries) == 0 {
+		if err := f.checkServiceExists(); err != nil {
+			return err
+		}
+	}
+
+	files := instanceFiles(entries)
+
+	if len(f.parts) == 2 {
+		children := make([]fs.DirEntry, 0, len(files))
+
+		for _, file := range files {
+			b, err := json.Marshal(file.instance)
+			if err != nil {
+				return fmt.Errorf("marshal service instance: %w", err)
+			}
+
+			children = append(children, internal.FileInfoDirEntry(internal.FileInfoWithSys(
+				file.name, int64(len(b)), 0o444, time.Time{}, jsonContentType, file.instance)))
+		}
+
+		f.setDir(name, children)
+
+		return nil
+	}
+
+	for _, file := range files {
+		if file.name != f.parts[2] {
+			continue
+		}
+
+		b, err := json.Marshal(file.instance)
+		if err != nil {
+			return fmt.Errorf("marshal service instance: %w", err)
+		}
+
+		f.setFile(name, b, file.instance)
+
+		return nil
+	}
+
+	return fs.ErrNotExist
+}
+
+// checkServiceExists returns an error wrapping fs.ErrNotExist unless the
+// file's service is in the catalog. Services only exist while they have
+// instances, but when only passing instances are included, a service with no
+// passing instances is an empty directory, as it's still listed.
+func (f *catalogFile) checkServiceExists() error {
+	if !f.passingOnly {
+		return fs.ErrNotExist
+	}
+
+	instances, _, err := f.client.Catalog().Service(f.parts[1], "", f.queryOpts.WithContext(f.ctx))
+	if err != nil {
+		return fmt.Errorf("catalog.Service: %w", err)
+	}
+
+	if len(instances) == 0 {
+		return fs.ErrNotExist
+	}
+
+	return nil
+}
+
+// loadNodes loads the directory of nodes, or a single node
+func (f *catalogFile) loadNodes(name string) error {
+	if len(f.parts) == 2 {
+		nodeName, ok := strings.CutSuffix(f.parts[1], ".json")
+		if !ok {
+			return fs.ErrNotExist
+		}
+
+		node, _, err := f.client.Catalog().Node(nodeName, f.queryOpts.WithContext(f.ctx))
+		if err != nil {
+			return fmt.Errorf("catalog.Node: %w", err)
+		}
+
+		if node == nil || node.Node == nil {
+			return fs.ErrNotExist
+		}
+
+		info := newNodeInfo(node.Node)
+
+		b, err := json.Marshal(info)
+		if err != nil {
+			return fmt.Errorf("marshal node: %w", err)
+		}
+
+		f.setFile(name, b, info)
+
+		return nil
+	}
+
+	nodes, _, err := f.client.Catalog().Nodes(f.queryOpts.WithContext(f.ctx))
+	if err != nil {
+		return fmt.Errorf("catalog.Nodes: %w", err)
+	}
+
+	slices.SortFunc(nodes, func(a, b *api.Node) int { return strings.Compare(a.Node, b.Node) })
+
+	children := make([]fs.DirEntry, 0, len(nodes))
+
+	for _, node := range nodes {
+		info := newNodeInfo(node)
+
+		b, err := json.Marshal(info)
+		if err != nil {
+			return fmt.Errorf("marshal node: %w", err)
+		}
+
+		children = append(children, internal.FileInfoDirEntry(internal.FileInfoWithSys(
+			node.Node+".json", int64(len(b)), 0o444, time.Time{}, jsonContentType, info)))
+	}
+
+	f.setDir(name, children)
+
+	return nil
+}
+
+type instanceFile struct {
+	instance *ServiceInstance
+	name     string
+}
+
+// instanceFiles names the files for each service instance, sorted by name.
+// Files are named after the node's ID (or its name, for nodes without an ID),
+// unless there are several instances of the service on the same node, in which
+// case the service ID is appended.
+func instanceFiles(entries []*api.ServiceEntry) []instanceFile {
+	perNode := map[string]int{}
+	for _, e := range entries {
+		perNode[nodeFileName(e.Node)]++
+	}
+
+	files := make([]instanceFile, 0, len(entries))
+
+	for _, e := range entries {
+		name := nodeFileName(e.Node)
+		if perNode[name] > 1 {
+			name += "-" + e.Service.ID
+		}
+
+		files = append(files, instanceFile{name: name + ".json", instance: newServiceInstance(e)})
+	}
+
+	slices.SortFunc(files, func(a, b instanceFile) int { return strings.Compare(a.name, b.name) })
+
+	return files
+}
+
+// nodeFileName returns the name identifying a service instance's node, which
+// is the node's ID, or its name when it has no ID (as with agents configured
+// with disable_host_node_id and no node_id)
+func nodeFileName(n *api.Node) string {
+	if n.ID != "" {
+		return n.ID
+	}
+
+	return n.Node
+}
+
+func newServiceInstance(e *api.ServiceEntry) *ServiceInstance {
+	addr := e.Service.Address
+	if addr == "" {
+		addr = e.Node.Address
+	}
+
+	tags := e.Service.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+
+	return &ServiceInstance{
+		Meta:        e.Service.Meta,
+		ID:          e.Service.ID,
+		Service:     e.Service.Service,
+		Address:     addr,
+		Node:        e.Node.Node,
+		NodeID:      e.Node.ID,
+		NodeAddress: e.Node.Address,
+		Datacenter:  e.Node.Datacenter,
+		Status:      e.Checks.AggregatedStatus(),
+		Tags:        tags,
+		Port:        e.Service.Port,
+	}
+}
+
+func newNodeInfo(node *api.Node) *NodeInfo {
+	return &NodeInfo{
+		TaggedAddresses: node.TaggedAddresses,
+		Meta:            node.Meta,
+		ID:              node.ID,
+		Node:            node.Node,
+		Address:         node.Address,
+		Datacenter:      node.Datacenter,
+	}
+}
+
+func dirEntry(name string) fs.DirEntry {
+	return internal.FileInfoDirEntry(internal.DirInfo(name, time.Time{}))
+}
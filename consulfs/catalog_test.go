@@ -0,0 +1,272 @@
+package consulfs
+
+import (
+	"encoding/json"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+
+	"github.com/hairyhenderson/go-fsimpl"
+	"github.com/hairyhenderson/go-fsimpl/internal/tests"
+	"github.com/hashicorp/consul/api/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	node1ID = "11111111-1111-1111-1111-111111111111"
+	node2ID = "22222222-2222-2222-2222-222222222222"
+)
+
+// fakeCatalogServer creates a fake Consul server with a service catalog of
+// two nodes, running the "web" service (twice on node2) and the "db" service
+func fakeCatalogServer(t *testing.T) *api.Config {
+	t.Helper()
+
+	node1 := &api.Node{
+		ID: node1ID, Node: "node1", Address: "10.0.0.1",
+		Datacenter: "dc1", TaggedAddresses: map[string]string{"lan": "10.0.0.1"},
+	}
+	node2 := &api.Node{
+		ID: node2ID, Node: "node2", Address: "10.0.0.2",
+		Datacenter: "dc1", Meta: map[string]string{"rack": "r2"},
+	}
+
+	passing := api.HealthChecks{{Node: "node1", CheckID: "serfHealth", Status: api.HealthPassing}}
+	critical := api.HealthChecks{{Node: "node2", CheckID: "web2", Status: api.HealthCritical}}
+
+	services := map[string][]*api.ServiceEntry{
+		"web": {
+			{
+				Node:    node1,
+				Service: &api.AgentService{ID: "web", Service: "web", Tags: []string{"v1"}, Port: 80},
+				Checks:  passing,
+			},
+			{
+				Node: node2,
+				Service: &api.AgentService{
+					ID: "web1", Service: "web", Address: "192.168.0.2", Port: 8080,
+					Meta: map[string]string{"version": "2"},
+				},
+				Checks: passing,
+			},
+			{
+				Node:    node2,
+				Service: &api.AgentService{ID: "web2", Service: "web", Port: 8081},
+				Checks:  critical,
+			},
+		},
+		"db": {
+			{
+				Node:    node1,
+				Service: &api.AgentService{ID: "db", Service: "db", Tags: []string{"primary"}, Port: 5432},
+				Checks:  critical,
+			},
+		},
+	}
+
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/v1/catalog/services", func(w http.ResponseWriter, _ *http.Request) {
+		out := map[string][]string{}
+		for name, entries := range services {
+			out[name] = entries[0].Service.Tags
+		}
+
+		_ = json.NewEncoder(w).Encode(out)
+	})
+
+	mux.HandleFunc("/v1/catalog/nodes", func(w http.ResponseWriter, _ *http.Request) {
+		_ = json.NewEncoder(w).Encode([]*api.Node{node2, node1})
+	})
+
+	mux.HandleFunc("/v1/catalog/node/{node}", func(w http.ResponseWriter, r *http.Request) {
+		for _, n := range []*api.Node{node1, node2} {
+			if n.Node == r.PathValue("node") {
+				_ = json.NewEncoder(w).Encode(&api.CatalogNode{Node: n})
+
+				return
+			}
+		}
+
+		// Consul returns null for unknown nodes
+		_, _ = w.Write([]byte("null"))
+	})
+
+	mux.HandleFunc("/v1/catalog/service/{service}", func(w http.ResponseWriter, r *http.Request) {
+		out := []*api.CatalogService{}
+
+		for _, e := range services[r.PathValue("service")] {
+			out = append(out, &api.CatalogService{
+				ID: e.Node.ID, Node: e.Node.Node, ServiceID: e.Service.ID, ServiceName: e.Service.Service,
+			})
+		}
+
+		_ = json.NewEncoder(w).Encode(out)
+	})
+
+	mux.HandleFunc("/v1/health/service/{service}", func(w http.ResponseWriter, r *http.Request) {
+		out := []*api.ServiceEntry{}
+
+		for _, e := range services[r.PathValue("service")] {
+			if r.URL.Query().Has("passing") && e.Checks.AggregatedStatus() != api.HealthPassing {
+				continue
+			}
+
+			out = append(out, e)
+		}
+
+		_ = json.NewEncoder(w).Encode(out)
+	})
+
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	return &api.Config{Address: srv.URL}
+}
+
+func TestCatalogFS(t *testing.T) {
+	fsys, err := New(tests.MustURL("consul+catalog:///"))
+	require.NoError(t, err)
+
+	fsys = WithConfigFS(fakeCatalogServer(t), fsys)
+
+	require.NoError(t, fstest.TestFS(fsys,
+		"nodes/node1.json", "nodes/node2.json",
+		"services/db/"+node1ID+".json",
+		"services/web/"+node1ID+".json",
+		"services/web/"+node2ID+"-web1.json", "services/web/"+node2ID+"-web2.json",
+	))
+
+	b, err := fs.ReadFile(fsys, "services/web/"+node2ID+"-web1.json")
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"tags": [], "meta": {"version": "2"}, "id": "web1", "service": "web",
+		"address": "192.168.0.2", "node": "node2",
+		"node_id": "22222222-2222-2222-2222-222222222222", "node_address": "10.0.0.2",
+		"datacenter": "dc1", "status": "passing", "port": 8080
+	}`, string(b))
+
+	// the node's address is used when the service has none
+	b, err = fs.ReadFile(fsys, "services/db/"+node1ID+".json")
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"tags": ["primary"], "id": "db", "service": "db", "address": "10.0.0.1",
+		"node": "node1", "node_id": "11111111-1111-1111-1111-111111111111",
+		"node_address": "10.0.0.1", "datacenter": "dc1", "status": "critical",
+		"port": 5432
+	}`, string(b))
+
+	b, err = fs.ReadFile(fsys, "nodes/node1.json")
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"tagged_addresses": {"lan": "10.0.0.1"},
+		"id": "11111111-1111-1111-1111-111111111111", "node": "node1",
+		"address": "10.0.0.1", "datacenter": "dc1"
+	}`, string(b))
+
+	fi, err := fs.Stat(fsys, "services/web/"+node1ID+".json")
+	require.NoError(t, err)
+	assert.Equal(t, &ServiceInstance{
+		Tags: []string{"v1"}, ID: "web", Service: "web", Address: "10.0.0.1",
+		Node: "node1", NodeID: "11111111-1111-1111-1111-111111111111",
+		NodeAddress: "10.0.0.1", Datacenter: "dc1", Status: "passing", Port: 80,
+	}, fi.Sys())
+	assert.Equal(t, "application/json", fsimpl.ContentType(fi))
+
+	fi, err = fs.Stat(fsys, "nodes/node2.json")
+	require.NoError(t, err)
+	assert.Equal(t, "r2", fi.Sys().(*NodeInfo).Meta["rack"])
+
+	for _, name := range []string{
+		"bogus", "nodes/node3.json", "nodes/node1", "services/bogus",
+		"services/web/node1.json", "services/web/" + node1ID + ".json/foo",
+	} {
+		_, err = fs.Stat(fsys, name)
+		require.ErrorIs(t, err, fs.ErrNotExist, name)
+	}
+
+	_, err = fs.ReadFile(fsys, "services/web")
+	require.ErrorIs(t, err, fs.ErrInvalid)
+}
+
+func TestCatalogFS_PassingOnly(t *testing.T) {
+	config := fakeCatalogServer(t)
+
+	fsys, err := New(tests.MustURL("consul+catalog:///services/?passing=true"))
+	require.NoError(t, err)
+
+	fsys = WithConfigFS(config, fsys)
+
+	des, err := fs.ReadDir(fsys, "web")
+	require.NoError(t, err)
+	require.Len(t, des, 2)
+	assert.Equal(t, node1ID+".json", des[0].Name())
+	assert.Equal(t, node2ID+".json", des[1].Name())
+
+	// services with no passing instances are still listed, so are empty
+	// directories
+	des, err = fs.ReadDir(fsys, "db")
+	require.NoError(t, err)
+	assert.Empty(t, des)
+
+	_, err = fs.Stat(fsys, "db/"+node1ID+".json")
+	require.ErrorIs(t, err, fs.ErrNotExist)
+
+	_, err = fs.ReadDir(fsys, "bogus")
+	require.ErrorIs(t, err, fs.ErrNotExist)
+
+	var walked []string
+
+	err = fs.WalkDir(fsys, ".", func(p string, _ fs.DirEntry, err error) error {
+		walked = append(walked, p)
+
+		return err
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{".", "db", "web", "web/" + node1ID + ".json", "web/" + node2ID + ".json"}, walked)
+
+	fsys, err = New(tests.MustURL("consul+catalog:///services/"))
+	require.NoError(t, err)
+
+	fsys = WithPassingOnlyFS(WithConfigFS(config, fsys))
+
+	des, err = fs.ReadDir(fsys, "web")
+	require.NoError(t, err)
+	require.Len(t, des, 2)
+
+	_, err = New(tests.MustURL("consul+catalog:///?passing=maybe"))
+	require.Error(t, err)
+}
+
+func TestCatalogFS_KVUnaffected(t *testing.T) {
+	fsys, err := New(tests.MustURL("consul:///"))
+	require.NoError(t, err)
+
+	fsys = WithPassingOnlyFS(WithConfigFS(fakeConsulServer(t), fsys))
+
+	b, err := fs.ReadFile(fsys, "foo")
+	require.NoError(t, err)
+	assert.Equal(t, "foo value", string(b))
+}
+
+func TestInstanceFiles(t *testing.T) {
+	withID := &api.Node{ID: node1ID, Node: "node1"}
+	noID := &api.Node{Node: "node2"}
+
+	files := instanceFiles([]*api.ServiceEntry{
+		{Node: noID, Service: &api.AgentService{ID: "web1"}},
+		{Node: withID, Service: &api.AgentService{ID: "web"}},
+		{Node: noID, Service: &api.AgentService{ID: "web2"}},
+	})
+
+	names := make([]string, 0, len(files))
+	for _, f := range files {
+		names = append(names, f.name)
+	}
+
+	// nodes without an ID are named after the node instead
+	assert.Equal(t, []string{node1ID + ".json", "node2-web1.json", "node2-web2.json"}, names)
+}
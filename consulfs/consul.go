@@ -3,6 +3,7 @@ package consulfs
 import (
 	"bytes"
 	"context"
+	"crypto/tls"
 	"errors"
 	"fmt"
 	"io"
@@ -16,6 +17,7 @@ import (
 	"time"
 
 	"github.com/hairyhenderson/go-fsimpl"
+	"github.com/hairyhenderson/go-fsimpl/consulfs/consulauth"
 	"github.com/hairyhenderson/go-fsimpl/internal"
 	"github.com/hashicorp/consul/api/v2"
 )
@@ -28,10 +30,32 @@ type consulFS struct {
 	config    *api.Config
 	queryOpts *api.QueryOptions
 	header    http.Header
-	token     string
+	tlsConfig *tls.Config
+	auth      consulauth.AuthMethod
+
+	// snapshot is set when recursive prefetch is enabled
+	snapshot *kvSnapshot
+
+	// login is set when an auth method is used
+	login *loginSession
+
+	token string
+
+	// catalog is set when the filesystem exposes the service catalog instead
+	// of the KV store
+	catalog     bool
+	passingOnly bool
 }
 
 // New creates a filesystem for the Consul KV endpoint rooted at u.
+//
+// The datacenter, namespace, admin partition, cluster peer, and consistency
+// mode can be set with the "dc", "ns", "partition", "peer", and "consistency"
+// query parameters.
+//
+// With the "consul+catalog" scheme, the filesystem exposes the service catalog
+// instead of the KV store. Set the "passing" query parameter to "true" to only
+// include service instances which are passing their health checks.
 func New(u *url.URL) (fs.FS, error) {
 	if u == nil {
 		return nil, errors.New("url must not be nil")
@@ -45,25 +69,60 @@ func New(u *url.URL) (fs.FS, error) {
 		return nil, fmt.Errorf("invalid url: path %q must be a prefix ending with \"/\"", u)
 	}
 
-	return &consulFS{
-		ctx:  context.Background(),
-		base: u,
-	}, nil
+	tlsConfig, err := internal.TLSConfigFromURL(u)
+	if err != nil {
+		return nil, fmt.Errorf("invalid TLS configuration: %w", err)
+	}
+
+	_, err = queryOptionsFromURL(u, nil)
+	if err != nil {
+		return nil, fmt.Errorf("invalid query options: %w", err)
+	}
+
+	passingOnly, err := passingOnlyFromURL(u)
+	if err != nil {
+		return nil, fmt.Errorf("invalid query options: %w", err)
+	}
+
+	prefetch, err := prefetchFromURL(u)
+	if err != nil {
+		return nil, fmt.Errorf("invalid query options: %w", err)
+	}
+
+	fsys := &consulFS{
+		ctx:         context.Background(),
+		base:        u,
+		tlsConfig:   tlsConfig,
+		catalog:     isCatalog(u),
+		passingOnly: passingOnly,
+	}
+
+	if prefetch {
+		fsys.snapshot = newKVSnapshot(u.Path)
+	}
+
+	return fsys, nil
 }
 
 // FS is used to register this filesystem with an [fsimpl.FSMux]
 //
 //nolint:gochecknoglobals
-var FS = fsimpl.FSProviderFunc(New, "consul", "consul+http", "consul+https")
+var FS = fsimpl.FSProviderFunc(New, "consul", "consul+http", "consul+https",
+	catalogScheme, catalogScheme+"+http", catalogScheme+"+https")
 
 var (
-	_ fs.FS                  = (*consulFS)(nil)
-	_ fs.ReadFileFS          = (*consulFS)(nil)
-	_ internal.WithContexter = (*consulFS)(nil)
-	_ internal.WithHeaderer  = (*consulFS)(nil)
-	_ withConfiger           = (*consulFS)(nil)
-	_ withQueryOptionser     = (*consulFS)(nil)
-	_ withTokener            = (*consulFS)(nil)
+	_ fs.FS                    = (*consulFS)(nil)
+	_ io.Closer                = (*consulFS)(nil)
+	_ fs.ReadFileFS            = (*consulFS)(nil)
+	_ internal.WithContexter   = (*consulFS)(nil)
+	_ internal.WithHeaderer    = (*consulFS)(nil)
+	_ internal.WithTLSConfiger = (*consulFS)(nil)
+	_ withAuthMethoder         = (*consulFS)(nil)
+	_ withConfiger             = (*consulFS)(nil)
+	_ withPassingOnlyer        = (*consulFS)(nil)
+	_ withPrefetcher           = (*consulFS)(nil)
+	_ withQueryOptionser       = (*consulFS)(nil)
+	_ withTokener              = (*consulFS)(nil)
 )
 
 func (f consulFS) URL() string {
@@ -88,6 +147,7 @@ func (f *consulFS) WithHeader(header http.Header) fs.FS {
 
 	fsys := *f
 	fsys.header = header
+	fsys.resetSnapshot()
 
 	if fsys.client != nil {
 		for k, vs := range header {
@@ -108,6 +168,20 @@ func (f *consulFS) WithConfig(config *api.Config) fs.FS {
 	fsys := *f
 	fsys.client = nil
 	fsys.config = config
+	fsys.resetSnapshot()
+
+	return &fsys
+}
+
+func (f *consulFS) WithTLSConfig(config *tls.Config) fs.FS {
+	if config == nil {
+		return f
+	}
+
+	fsys := *f
+	fsys.client = nil
+	fsys.tlsConfig = config
+	fsys.resetSnapshot()
 
 	return &fsys
 }
@@ -116,23 +190,82 @@ func (f consulFS) WithToken(token string) fs.FS {
 	fsys := f
 	fsys.client = nil
 	fsys.token = token
+	fsys.resetSnapshot()
+
+	return &fsys
+}
+
+// WithAuthMethod returns a filesystem which logs in with auth to acquire an
+// ACL token. The token is shared by all copies of the filesystem, and is
+// logged out when the filesystem is closed.
+func (f consulFS) WithAuthMethod(auth consulauth.AuthMethod) fs.FS {
+	fsys := f
+	fsys.client = nil
+	fsys.auth = auth
+	fsys.login = nil
+	fsys.resetSnapshot()
+
+	if auth != nil {
+		fsys.login = &loginSession{auth: auth}
+	}
 
 	return &fsys
 }
 
+// Close logs out the ACL token acquired with the auth method set by
+// [consulauth.WithAuthMethod]. It is a no-op when no auth method is set.
+func (f consulFS) Close() error {
+	if f.login == nil {
+		return nil
+	}
+
+	err := f.login.close(f.ctx)
+	if err != nil {
+		return &fs.PathError{Op: "close", Path: ".", Err: err}
+	}
+
+	return nil
+}
+
 func (f consulFS) WithQueryOptions(opts *api.QueryOptions) fs.FS {
 	fsys := f
 	fsys.queryOpts = opts
+	fsys.resetSnapshot()
+
+	return &fsys
+}
+
+func (f consulFS) WithPassingOnly() fs.FS {
+	fsys := f
+	fsys.passingOnly = true
+
+	return &fsys
+}
+
+func (f consulFS) WithPrefetch() fs.FS {
+	fsys := f
+	fsys.snapshot = newKVSnapshot(f.base.Path)
 
 	return &fsys
 }
 
+// resetSnapshot gives the filesystem its own prefetch snapshot, so that keys
+// read with different credentials or options aren't shared with the
+// filesystem it was copied from
+func (f *consulFS) resetSnapshot() {
+	if f.snapshot != nil {
+		f.snapshot = newKVSnapshot(f.base.Path)
+	}
+}
+
 func getAddress(u *url.URL) string {
 	// handle compound URL scheme not supported by the client, but only if the
 	// URL has a host part set - otherwise just use the defaults
 	if u.Host != "" {
-		scheme := strings.TrimPrefix(u.Scheme, "consul+")
-		if scheme == "consul" {
+		scheme := strings.TrimPrefix(u.Scheme, catalogScheme)
+		scheme = strings.TrimPrefix(strings.TrimPrefix(scheme, "consul"), "+")
+
+		if scheme == "" {
 			scheme = "http"
 		}
 
@@ -144,6 +277,10 @@ func getAddress(u *url.URL) string {
 
 // initClient must be called before referencing f.client
 func (f *consulFS) initClient() error {
+	if f.login != nil && f.login.isClosed() {
+		return fs.ErrClosed
+	}
+
 	if f.client != nil {
 		return nil
 	}
@@ -163,11 +300,47 @@ func (f *consulFS) initClient() error {
 		config.Token = f.token
 	}
 
+	if err := f.configureTLS(config); err != nil {
+		return fmt.Errorf("consul TLS configuration failed: %w", err)
+	}
+
+	// the token acquired by logging in takes precedence over all others
+	var loginConfig *api.Config
+
+	if f.login != nil {
+		token, err := f.login.ensureToken(f.ctx, config)
+		if err != nil {
+			return err
+		}
+
+		// kept to log in again with, should the token be rejected
+		lc := *config
+		loginConfig = &lc
+
+		// the HTTP client is reconfigured below, so neither it nor the
+		// config can be shared
+		cc := *config
+		config = &cc
+
+		if config.HttpClient != nil {
+			hc := *config.HttpClient
+			config.HttpClient = &hc
+		}
+
+		config.Token = token
+		config.TokenFile = ""
+	}
+
 	c, err := api.NewClient(config)
 	if err != nil {
 		return fmt.Errorf("consul client creation failed: %w", err)
 	}
 
+	// the client's config holds the same HTTP client
+	if loginConfig != nil {
+		f.login.withRelogin(config.HttpClient, loginConfig)
+	}
+
 	if f.header != nil {
 		c.SetHeaders(f.header)
 	}
@@ -177,6 +350,36 @@ func (f *consulFS) initClient() error {
 	return nil
 }
 
+// configureTLS sets the filesystem's TLS configuration, if any, on config's
+// HTTP client or transport. This takes precedence over config.TLSConfig.
+func (f *consulFS) configureTLS(config *api.Config) error {
+	if f.tlsConfig == nil {
+		return nil
+	}
+
+	if config.HttpClient != nil {
+		hc, err := internal.HTTPClientWithTLSConfig(config.HttpClient, f.tlsConfig)
+		if err != nil {
+			return err
+		}
+
+		config.HttpClient = hc
+
+		return nil
+	}
+
+	tr := config.Transport
+	if tr == nil {
+		tr = api.DefaultConfig().Transport
+	}
+
+	tr = tr.Clone()
+	tr.TLSClientConfig = f.tlsConfig.Clone()
+	config.Transport = tr
+
+	return nil
+}
+
 func (f *consulFS) Open(name string) (fs.File, error) {
 	if !internal.ValidPath(name) {
 		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
@@ -187,17 +390,39 @@ func (f *consulFS) Open(name string) (fs.File, error) {
 		return nil, err
 	}
 
+	opts, err := queryOptionsFromURL(u, f.queryOpts)
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+
 	if err = f.initClient(); err != nil {
 		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
 	}
 
-	return &consulFile{
+	if f.catalog {
+		passingOnly, err := passingOnlyFromURL(u)
+		if err != nil {
+			return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+		}
+
+		return newCatalogFile(f.ctx, name, u, f.client, opts, f.passingOnly || passingOnly), nil
+	}
+
+	cf := &consulFile{
 		ctx:       f.ctx,
 		name:      name,
 		u:         u,
 		client:    f.client,
-		queryOpts: f.queryOpts,
-	}, nil
+		queryOpts: opts,
+	}
+
+	// the snapshot is read with the base URL's query options, so files with
+	// their own query parameters are read directly
+	if !strings.Contains(name, "?") {
+		cf.snapshot = f.snapshot
+	}
+
+	return cf, nil
 }
 
 // ReadFile implements fs.ReadFileFS
@@ -214,11 +439,27 @@ func (f *consulFS) ReadFile(name string) ([]byte, error) {
 		return nil, err
 	}
 
+	opts, err := queryOptionsFromURL(u, f.queryOpts)
+	if err != nil {
+		return nil, &fs.PathError{Op: "readFile", Path: name, Err: err}
+	}
+
 	if err = f.initClient(); err != nil {
 		return nil, &fs.PathError{Op: "readFile", Path: name, Err: err}
 	}
 
-	kvPair, _, err := f.client.KV().Get(u.Path, f.queryOpts.WithContext(f.ctx))
+	if f.catalog || f.snapshot != nil {
+		file, err := f.Open(name)
+		if err != nil {
+			return nil, err
+		}
+
+		defer file.Close()
+
+		return io.ReadAll(file)
+	}
+
+	kvPair, _, err := f.client.KV().Get(u.Path, opts.WithContext(f.ctx))
 	if err != nil {
 		return nil, &fs.PathError{
 			Op: "readFile", Path: u.Path,
@@ -229,6 +470,47 @@ func (f *consulFS) ReadFile(name string) ([]byte, error) {
 	return kvPair.Value, nil
 }
 
+// KVPairInfo holds the metadata of a Consul KV pair. It is returned by the Sys
+// method of the [fs.FileInfo] for keys (but not for directories, which are
+// only prefixes in Consul).
+//
+// Consul doesn't record when keys are modified, so the file's ModTime is
+// always zero. Use ModifyIndex to detect changes instead - it changes
+// whenever the key is modified, and can be used for check-and-set operations.
+type KVPairInfo struct {
+	// Session is the ID of the session holding a lock on the key, if any.
+	Session string
+
+	// Namespace and Partition are the namespace and admin partition the key
+	// is in (Consul Enterprise only).
+	Namespace string
+	Partition string
+
+	// CreateIndex is the Raft index at which the key was created.
+	CreateIndex uint64
+
+	// ModifyIndex is the Raft index at which the key was last modified.
+	ModifyIndex uint64
+
+	// LockIndex is the number of times a lock on the key has been acquired.
+	LockIndex uint64
+
+	// Flags is an opaque value which applications can set on the key.
+	Flags uint64
+}
+
+func newKVPairInfo(kvPair *api.KVPair) *KVPairInfo {
+	return &KVPairInfo{
+		Session:     kvPair.Session,
+		Namespace:   kvPair.Namespace,
+		Partition:   kvPair.Partition,
+		CreateIndex: kvPair.CreateIndex,
+		ModifyIndex: kvPair.ModifyIndex,
+		LockIndex:   kvPair.LockIndex,
+		Flags:       kvPair.Flags,
+	}
+}
+
 type consulFile struct {
 	ctx       context.Context
 	name      string
@@ -236,6 +518,7 @@ type consulFile struct {
 	client    *api.Client
 	kv        *api.KV
 	queryOpts *api.QueryOptions
+	snapshot  *kvSnapshot
 
 	body     io.ReadCloser
 	fi       fs.FileInfo
@@ -270,9 +553,9 @@ func (f *consulFile) get() error {
 
 	key := strings.TrimPrefix(f.u.Path, "/")
 
-	kvPair, _, err := f.kv.Get(key, f.queryOpts.WithContext(f.ctx))
+	kvPair, err := f.getPair(key)
 	if err != nil {
-		return fmt.Errorf("kv.Get: %w", err)
+		return err
 	}
 
 	if kvPair == nil {
@@ -284,8 +567,10 @@ func (f *consulFile) get() error {
 		name = path.Base(name)
 	}
 
-	f.fi = internal.FileInfo(name, int64(len(kvPair.Value)),
-		0o444, time.Time{}, "",
+	// Consul doesn't record when keys are modified, so the modification time
+	// is left unset - the ModifyIndex should be used to detect changes instead
+	f.fi = internal.FileInfoWithSys(name, int64(len(kvPair.Value)),
+		0o444, time.Time{}, "", newKVPairInfo(kvPair),
 	)
 
 	f.body = io.NopCloser(bytes.NewReader(kvPair.Value))
@@ -303,9 +588,9 @@ func (f *consulFile) list() ([]string, error) {
 		key += "/"
 	}
 
-	keys, _, err := f.kv.Keys(key, "/", f.queryOpts.WithContext(f.ctx))
+	keys, err := f.keys(key)
 	if err != nil {
-		return nil, fmt.Errorf("kv.Keys: %w", err)
+		return nil, err
 	}
 
 	// can't have empty directories as they're just prefixes with consul
@@ -319,6 +604,45 @@ func (f *consulFile) list() ([]string, error) {
 	return keys, nil
 }
 
+// getPair reads the KV pair for key, from the snapshot when prefetching
+func (f *consulFile) getPair(key string) (*api.KVPair, error) {
+	if f.snapshot != nil {
+		tree, err := f.snapshot.load(f.ctx, f.kv, f.queryOpts)
+		if err != nil {
+			return nil, err
+		}
+
+		return tree.get(key), nil
+	}
+
+	kvPair, _, err := f.kv.Get(key, f.queryOpts.WithContext(f.ctx))
+	if err != nil {
+		return nil, fmt.Errorf("kv.Get: %w", err)
+	}
+
+	return kvPair, nil
+}
+
+// keys lists the keys under the directory key, from the snapshot when
+// prefetching
+func (f *consulFile) keys(key string) ([]string, error) {
+	if f.snapshot != nil {
+		tree, err := f.snapshot.load(f.ctx, f.kv, f.queryOpts)
+		if err != nil {
+			return nil, err
+		}
+
+		return tree.keys(key), nil
+	}
+
+	keys, _, err := f.kv.Keys(key, "/", f.queryOpts.WithContext(f.ctx))
+	if err != nil {
+		return nil, fmt.Errorf("kv.Keys: %w", err)
+	}
+
+	return keys, nil
+}
+
 // onlyChildren returns the sorted slice of keys that are direct children of the
 // given key.
 func onlyChildren(parent string, keys []string) []string {
@@ -412,6 +736,7 @@ func (f *consulFile) childFile(childName string) *consulFile {
 		u:         childURL,
 		client:    f.client,
 		queryOpts: f.queryOpts,
+		snapshot:  f.snapshot,
 	}
 
 	return cf
@@ -2,10 +2,12 @@ package consulfs
 
 import (
 	"context"
+	"crypto/tls"
 	"fmt"
 	"io"
 	"io/fs"
 	"net/http"
+	"net/http/httptest"
 	"net/url"
 	"runtime"
 	"testing"
@@ -35,6 +37,10 @@ func TestGetAddress(t *testing.T) {
 		getAddress(tests.MustURL("consul://myconsul.local:1234")))
 	assert.Equal(t, "https://consul.example.com",
 		getAddress(tests.MustURL("consul+https://consul.example.com")))
+	assert.Equal(t, "http://myconsul.local:1234",
+		getAddress(tests.MustURL("consul+catalog://myconsul.local:1234")))
+	assert.Equal(t, "https://consul.example.com",
+		getAddress(tests.MustURL("consul+catalog+https://consul.example.com")))
 }
 
 func TestNew(t *testing.T) {
@@ -113,6 +119,37 @@ func TestWithToken(t *testing.T) {
 	assert.Nil(t, fsys.client)
 }
 
+func TestWithTLSConfig(t *testing.T) {
+	fsys := &consulFS{config: fakeConsulServer(t), base: tests.MustURL("consul:///")}
+
+	err := fsys.initClient()
+	require.NoError(t, err)
+	require.NotNil(t, fsys.client)
+
+	cfg := &tls.Config{MinVersion: tls.VersionTLS13}
+
+	fsys = fsys.WithTLSConfig(cfg).(*consulFS)
+
+	// WithTLSConfig should clear the client
+	assert.Nil(t, fsys.client)
+
+	err = fsys.initClient()
+	require.NoError(t, err)
+
+	// the first client creation populated config.HttpClient, so the TLS
+	// configuration must be applied to that client's transport
+	tr, ok := fsys.config.HttpClient.Transport.(*http.Transport)
+	require.True(t, ok)
+	assert.Equal(t, uint16(tls.VersionTLS13), tr.TLSClientConfig.MinVersion)
+
+	_, err = New(tests.MustURL("consul:///?ca_file=/bogus.pem"))
+	require.Error(t, err)
+
+	fsys2, err := New(tests.MustURL("consul:///?insecure_skip_verify=true"))
+	require.NoError(t, err)
+	assert.True(t, fsys2.(*consulFS).tlsConfig.InsecureSkipVerify)
+}
+
 func TestWithQueryOptions(t *testing.T) {
 	opts := &api.QueryOptions{}
 
@@ -124,6 +161,85 @@ func TestWithQueryOptions(t *testing.T) {
 	assert.NotNil(t, fsys.client)
 }
 
+func TestQueryOptionsFromURL(t *testing.T) {
+	base := &api.QueryOptions{Datacenter: "dc1", Token: "foo", AllowStale: true}
+
+	opts, err := queryOptionsFromURL(tests.MustURL("consul:///?param=value"), base)
+	require.NoError(t, err)
+	assert.Same(t, base, opts)
+
+	opts, err = queryOptionsFromURL(tests.MustURL(
+		"consul:///?dc=dc2&ns=team1&partition=part1&peer=peer1&consistency=consistent"), base)
+	require.NoError(t, err)
+	assert.Equal(t, &api.QueryOptions{
+		Datacenter:        "dc2",
+		Namespace:         "team1",
+		Partition:         "part1",
+		Peer:              "peer1",
+		Token:             "foo",
+		RequireConsistent: true,
+	}, opts)
+
+	// the base options must not be modified
+	assert.Equal(t, "dc1", base.Datacenter)
+
+	opts, err = queryOptionsFromURL(tests.MustURL("consul:///?consistency=STALE"), nil)
+	require.NoError(t, err)
+	assert.Equal(t, &api.QueryOptions{AllowStale: true}, opts)
+
+	_, err = queryOptionsFromURL(tests.MustURL("consul:///?consistency=eventual"), nil)
+	require.ErrorIs(t, err, fs.ErrInvalid)
+
+	_, err = New(tests.MustURL("consul:///?consistency=eventual"))
+	require.Error(t, err)
+}
+
+func TestQueryOptionsParams(t *testing.T) {
+	var queries []url.Values
+
+	files := map[string]consulKVEntry{
+		"/v1/kv/dir/":    {Keys: []string{"dir/foo"}},
+		"/v1/kv/dir/foo": {Value: "foo"},
+	}
+	handler := fakeConsulHandler(t, files)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		queries = append(queries, r.URL.Query())
+
+		handler(w, r)
+	}))
+	t.Cleanup(srv.Close)
+
+	fsys, err := New(tests.MustURL("consul:///dir/?dc=dc2&ns=team1&partition=part1&consistency=stale"))
+	require.NoError(t, err)
+
+	fsys = WithConfigFS(&api.Config{Address: srv.URL}, fsys)
+
+	b, err := fs.ReadFile(fsys, "foo")
+	require.NoError(t, err)
+	assert.Equal(t, "foo", string(b))
+
+	des, err := fs.ReadDir(fsys, ".")
+	require.NoError(t, err)
+	require.Len(t, des, 1)
+
+	// parameters can also be set for a single file
+	_, err = fs.ReadFile(fsys, "foo?dc=dc3&peer=peer1")
+	require.NoError(t, err)
+
+	require.Len(t, queries, 4)
+
+	for _, q := range queries[:3] {
+		assert.Equal(t, "dc2", q.Get("dc"))
+		assert.Equal(t, "team1", q.Get("ns"))
+		assert.Equal(t, "part1", q.Get("partition"))
+		assert.True(t, q.Has("stale"))
+	}
+
+	assert.Equal(t, "dc3", queries[3].Get("dc"))
+	assert.Equal(t, "peer1", queries[3].Get("peer"))
+}
+
 func TestOpen(t *testing.T) {
 	fsys, err := New(tests.MustURL("consul+https://127.0.0.1:8500/foo/"))
 	require.NoError(t, err)
@@ -175,9 +291,9 @@ func TestReadDirFS(t *testing.T) {
 	require.NoError(t, err)
 
 	des := []fs.DirEntry{
-		internal.FileInfo("bar", 3, 0o444, time.Time{}, "").(fs.DirEntry),
+		internal.FileInfoWithSys("bar", 3, 0o444, time.Time{}, "", &KVPairInfo{}).(fs.DirEntry),
 		internal.DirInfo("bazDir", time.Time{}).(fs.DirEntry),
-		internal.FileInfo("foo", 3, 0o444, time.Time{}, "").(fs.DirEntry),
+		internal.FileInfoWithSys("foo", 3, 0o444, time.Time{}, "", &KVPairInfo{}).(fs.DirEntry),
 	}
 	assert.Equal(t, des, de)
 
@@ -190,9 +306,9 @@ func TestReadDirFS(t *testing.T) {
 	require.NoError(t, err)
 
 	des = []fs.DirEntry{
-		internal.FileInfo("bar", 3, 0o444, time.Time{}, "").(fs.DirEntry),
+		internal.FileInfoWithSys("bar", 3, 0o444, time.Time{}, "", &KVPairInfo{}).(fs.DirEntry),
 		internal.DirInfo("bazDir", time.Time{}).(fs.DirEntry),
-		internal.FileInfo("foo", 3, 0o444, time.Time{}, "").(fs.DirEntry),
+		internal.FileInfoWithSys("foo", 3, 0o444, time.Time{}, "", &KVPairInfo{}).(fs.DirEntry),
 	}
 	assert.Equal(t, des, de)
 }
@@ -218,7 +334,7 @@ func TestReadDirN(t *testing.T) {
 	require.NoError(t, err)
 
 	des := []fs.DirEntry{
-		internal.FileInfo("bar", 3, 0o444, time.Time{}, "").(fs.DirEntry),
+		internal.FileInfoWithSys("bar", 3, 0o444, time.Time{}, "", &KVPairInfo{}).(fs.DirEntry),
 	}
 	assert.Equal(t, des, de)
 
@@ -227,7 +343,7 @@ func TestReadDirN(t *testing.T) {
 
 	des = []fs.DirEntry{
 		internal.DirInfo("bazDir", time.Time{}).(fs.DirEntry),
-		internal.FileInfo("foo", 3, 0o444, time.Time{}, "").(fs.DirEntry),
+		internal.FileInfoWithSys("foo", 3, 0o444, time.Time{}, "", &KVPairInfo{}).(fs.DirEntry),
 	}
 	assert.Equal(t, des, de)
 
@@ -309,6 +425,50 @@ func TestStat(t *testing.T) {
 	assert.Equal(t, internal.DirInfo(".", time.Time{}), fi)
 }
 
+func TestStat_KVPairInfo(t *testing.T) {
+	files := map[string]consulKVEntry{
+		"/v1/kv/dir/": {Keys: []string{"dir/lock", "dir/sub/"}},
+		"/v1/kv/dir/lock": {
+			Value: "held", Session: "adf4238a-882b-9ddc-4a9d-5b6758e4159e",
+			CreateIndex: 10, ModifyIndex: 42, LockIndex: 3, Flags: 7,
+		},
+		"/v1/kv/dir/sub/": {Keys: []string{"dir/sub/foo"}},
+	}
+
+	srv := httptest.NewServer(fakeConsulHandler(t, files))
+	t.Cleanup(srv.Close)
+
+	fsys, err := New(tests.MustURL("consul:///dir/"))
+	require.NoError(t, err)
+
+	fsys = WithConfigFS(&api.Config{Address: srv.URL}, fsys)
+
+	fi, err := fs.Stat(fsys, "lock")
+	require.NoError(t, err)
+	assert.True(t, fi.ModTime().IsZero())
+	assert.Equal(t, &KVPairInfo{
+		Session:     "adf4238a-882b-9ddc-4a9d-5b6758e4159e",
+		CreateIndex: 10,
+		ModifyIndex: 42,
+		LockIndex:   3,
+		Flags:       7,
+	}, fi.Sys())
+
+	// directory entries include the info too
+	des, err := fs.ReadDir(fsys, ".")
+	require.NoError(t, err)
+	require.Len(t, des, 2)
+
+	fi, err = des[0].Info()
+	require.NoError(t, err)
+	assert.Equal(t, uint64(42), fi.Sys().(*KVPairInfo).ModifyIndex)
+
+	fi, err = des[1].Info()
+	require.NoError(t, err)
+	assert.True(t, fi.IsDir())
+	assert.Nil(t, fi.Sys())
+}
+
 func TestRootLevelOperations(t *testing.T) {
 	config := fakeConsulServer(t)
 
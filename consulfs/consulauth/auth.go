@@ -0,0 +1,78 @@
+package consulauth
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"path/filepath"
+	"strings"
+
+	"github.com/hashicorp/consul/api/v2"
+)
+
+// AuthMethod is an authentication method that consulfs can use to acquire a
+// Consul ACL token.
+//
+// Auth methods may also implement a Logout method, with the signature
+//
+//	Logout(ctx context.Context, client *api.Client, token *api.ACLToken) error
+//
+// to override the default logout (which destroys the token with the Consul
+// acl/logout endpoint).
+type AuthMethod interface {
+	// Login acquires an ACL token, using client for communicating with
+	// Consul.
+	Login(ctx context.Context, client *api.Client) (*api.ACLToken, error)
+}
+
+// withAuthMethoder is an fs.FS that can be configured with an AuthMethod
+type withAuthMethoder interface {
+	WithAuthMethod(auth AuthMethod) fs.FS
+}
+
+// WithAuthMethod configures the given FS to authenticate with auth, if the
+// filesystem supports it.
+//
+// Note that this is not required if $CONSUL_HTTP_TOKEN or
+// $CONSUL_HTTP_TOKEN_FILE is set.
+func WithAuthMethod(auth AuthMethod, fsys fs.FS) fs.FS {
+	if afsys, ok := fsys.(withAuthMethoder); ok {
+		return afsys.WithAuthMethod(auth)
+	}
+
+	return fsys
+}
+
+// readTokenFile reads a token (such as a JWT) from the file at p, trimming any
+// surrounding whitespace
+func readTokenFile(fsys fs.FS, p string) (string, error) {
+	f, err := fsys.Open(strings.TrimPrefix(p, "/"))
+	if err != nil {
+		return "", fmt.Errorf("unable to open token file: %w", err)
+	}
+	defer f.Close()
+
+	// JWTs can be large-ish, but anything over 64kB is suspicious
+	b, err := io.ReadAll(io.LimitReader(f, 64*1024))
+	if err != nil {
+		return "", fmt.Errorf("unable to read token file: %w", err)
+	}
+
+	token := strings.TrimSpace(string(b))
+	if token == "" {
+		return "", fmt.Errorf("token file %q is empty", p)
+	}
+
+	return token, nil
+}
+
+// absPath resolves p against the current working directory, as token files
+// are read relative to the root of the filesystem
+func absPath(p string) string {
+	if abs, err := filepath.Abs(p); err == nil {
+		return abs
+	}
+
+	return p
+}
@@ -0,0 +1,22 @@
+// Package consulauth provides Consul ACL auth methods for use with
+// [github.com/hairyhenderson/go-fsimpl/consulfs], which can also be used
+// directly with a [*github.com/hashicorp/consul/api/v2.Client].
+//
+// The auth methods provided here are:
+//   - [NewKubernetesAuth] - log in to a Consul Kubernetes auth method with a
+//     (projected) service account token
+//   - [NewJWTAuth] - log in to a Consul JWT or OIDC auth method with a JWT,
+//     such as an OIDC ID token from a CI system
+//   - [NewTokenAuth] - an existing ACL token, such as from
+//     $CONSUL_HTTP_TOKEN or $CONSUL_HTTP_TOKEN_FILE
+//   - [NewTokenFileAuth] - an ACL token from a file, such as one written by
+//     "consul login -token-sink-file"
+//
+// Tokens acquired by logging in to an auth method are logged out (destroyed)
+// when the filesystem is closed. Existing tokens are never logged out.
+//
+// [EnvAuthMethod] can be used to select and configure an auth method from
+// environment variables.
+//
+// See also https://developer.hashicorp.com/consul/docs/security/acl/auth-methods
+package consulauth
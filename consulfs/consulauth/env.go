@@ -0,0 +1,62 @@
+package consulauth
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/hashicorp/consul/api/v2"
+)
+
+// EnvAuthMethod configures the auth method based on environment variables.
+//
+// If $CONSUL_LOGIN_AUTH_METHOD is set, the named Consul auth method is logged
+// in to with a bearer token read from the file named by
+// $CONSUL_LOGIN_BEARER_TOKEN_FILE (such as a projected service account
+// token), or the bearer token in $CONSUL_LOGIN_BEARER_TOKEN. If neither is
+// set, the Kubernetes service account token is read from
+// [DefaultServiceAccountTokenPath].
+//
+// Otherwise, the [NewTokenAuth] is used, with the token from
+// $CONSUL_HTTP_TOKEN or the file named by $CONSUL_HTTP_TOKEN_FILE.
+//
+// Note that this auth method is provided as a convenience, and is not intended
+// to be heavily depended upon. It is recommended that you use the auth methods
+// directly, and configure them with the appropriate options.
+func EnvAuthMethod() AuthMethod {
+	authMethod := os.Getenv("CONSUL_LOGIN_AUTH_METHOD")
+	if authMethod == "" {
+		return NewTokenAuth("")
+	}
+
+	var (
+		a   AuthMethod
+		err error
+	)
+
+	switch {
+	case os.Getenv("CONSUL_LOGIN_BEARER_TOKEN_FILE") != "":
+		a, err = NewJWTAuth(authMethod,
+			&BearerToken{FromFile: os.Getenv("CONSUL_LOGIN_BEARER_TOKEN_FILE")})
+	case os.Getenv("CONSUL_LOGIN_BEARER_TOKEN") != "":
+		a, err = NewJWTAuth(authMethod, &BearerToken{FromEnv: "CONSUL_LOGIN_BEARER_TOKEN"})
+	default:
+		a, err = NewKubernetesAuth(authMethod)
+	}
+
+	if err != nil {
+		return &errAuthMethod{err: err}
+	}
+
+	return a
+}
+
+// errAuthMethod is an auth method which always fails to log in with the given
+// error
+type errAuthMethod struct {
+	err error
+}
+
+func (m *errAuthMethod) Login(_ context.Context, _ *api.Client) (*api.ACLToken, error) {
+	return nil, fmt.Errorf("consul login failed: %w", m.err)
+}
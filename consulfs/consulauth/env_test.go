@@ -0,0 +1,42 @@
+package consulauth
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEnvAuthMethod(t *testing.T) {
+	t.Setenv("CONSUL_LOGIN_AUTH_METHOD", "")
+
+	assert.IsType(t, &tokenAuthMethod{}, EnvAuthMethod())
+
+	t.Setenv("CONSUL_LOGIN_AUTH_METHOD", "myauth")
+	t.Setenv("CONSUL_LOGIN_BEARER_TOKEN_FILE", "")
+	t.Setenv("CONSUL_LOGIN_BEARER_TOKEN", "")
+
+	a := EnvAuthMethod().(*loginAuthMethod)
+	assert.Equal(t, "kubernetes", a.kind)
+	assert.Equal(t, "myauth", a.authMethod)
+	assert.Equal(t, &BearerToken{FromFile: DefaultServiceAccountTokenPath}, a.token)
+
+	t.Setenv("CONSUL_LOGIN_BEARER_TOKEN", "some.jwt")
+
+	a = EnvAuthMethod().(*loginAuthMethod)
+	assert.Equal(t, "jwt", a.kind)
+	assert.Equal(t, &BearerToken{FromEnv: "CONSUL_LOGIN_BEARER_TOKEN"}, a.token)
+
+	t.Setenv("CONSUL_LOGIN_BEARER_TOKEN_FILE", "/run/secrets/token")
+
+	a = EnvAuthMethod().(*loginAuthMethod)
+	assert.Equal(t, &BearerToken{FromFile: "/run/secrets/token"}, a.token)
+
+	// relative paths are resolved against the working directory
+	dir := t.TempDir()
+	t.Chdir(dir)
+	t.Setenv("CONSUL_LOGIN_BEARER_TOKEN_FILE", "./token")
+
+	a = EnvAuthMethod().(*loginAuthMethod)
+	assert.Equal(t, &BearerToken{FromFile: filepath.Join(dir, "token")}, a.token)
+}
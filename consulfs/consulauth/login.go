@@ -0,0 +1,186 @@
+package consulauth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+
+	"github.com/hashicorp/consul/api/v2"
+)
+
+// DefaultServiceAccountTokenPath is the path where Kubernetes mounts the
+// (projected) service account token in a pod.
+const DefaultServiceAccountTokenPath = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+
+// NewKubernetesAuth logs in to the named Consul auth method of type
+// "kubernetes", with the pod's service account token.
+//
+// By default the token is read from [DefaultServiceAccountTokenPath] at each
+// login, so that rotated (projected) tokens are picked up. Use
+// [WithBearerToken] to provide the token differently.
+//
+// See also https://developer.hashicorp.com/consul/docs/security/acl/auth-methods/kubernetes
+func NewKubernetesAuth(authMethod string, opts ...LoginOption) (AuthMethod, error) {
+	return newLoginAuth("kubernetes", authMethod,
+		&BearerToken{FromFile: DefaultServiceAccountTokenPath}, opts...)
+}
+
+// NewJWTAuth logs in to the named Consul auth method of type "jwt" (or
+// "oidc"), with the given JWT (such as an OIDC ID token issued by a CI system,
+// or by a cloud provider's workload identity federation).
+//
+// See also https://developer.hashicorp.com/consul/docs/security/acl/auth-methods/jwt
+func NewJWTAuth(authMethod string, token *BearerToken, opts ...LoginOption) (AuthMethod, error) {
+	if token == nil {
+		return nil, errors.New("jwt auth method requires a bearer token")
+	}
+
+	return newLoginAuth("jwt", authMethod, token, opts...)
+}
+
+func newLoginAuth(kind, authMethod string, token *BearerToken, opts ...LoginOption) (*loginAuthMethod, error) {
+	if authMethod == "" {
+		return nil, fmt.Errorf("%s auth method requires the auth method's name", kind)
+	}
+
+	a := &loginAuthMethod{
+		fsys:       os.DirFS("/"),
+		kind:       kind,
+		authMethod: authMethod,
+		token:      token,
+	}
+
+	for _, opt := range opts {
+		if err := opt(a); err != nil {
+			return nil, fmt.Errorf("error from %s login option: %w", kind, err)
+		}
+	}
+
+	if err := a.token.validate(); err != nil {
+		return nil, fmt.Errorf("invalid bearer token: %w", err)
+	}
+
+	// copied so that the caller's token isn't modified
+	if a.token.FromFile != "" {
+		token := *a.token
+		token.FromFile = absPath(token.FromFile)
+		a.token = &token
+	}
+
+	return a, nil
+}
+
+type LoginOption func(a *loginAuthMethod) error
+
+// WithBearerToken sets where the bearer token to log in with is read from.
+func WithBearerToken(token *BearerToken) LoginOption {
+	return func(a *loginAuthMethod) error {
+		if token == nil {
+			return errors.New("bearer token must not be nil")
+		}
+
+		a.token = token
+
+		return nil
+	}
+}
+
+// WithLoginMeta sets metadata to attach to the token created by the login.
+func WithLoginMeta(meta map[string]string) LoginOption {
+	return func(a *loginAuthMethod) error {
+		a.meta = maps.Clone(meta)
+
+		return nil
+	}
+}
+
+// WithLoginWriteOptions sets the options for the login request, such as the
+// datacenter, namespace, or admin partition that the auth method is in.
+func WithLoginWriteOptions(opts *api.WriteOptions) LoginOption {
+	return func(a *loginAuthMethod) error {
+		a.writeOpts = opts
+
+		return nil
+	}
+}
+
+// BearerToken is a struct that allows you to specify where your application is
+// storing the bearer token (a JWT) required to log in to an auth method. Files
+// are read again at each login, so that rotated tokens are picked up.
+type BearerToken struct {
+	FromFile   string
+	FromString string
+	FromEnv    string
+}
+
+func (token *BearerToken) validate() error {
+	n := 0
+
+	for _, s := range []string{token.FromFile, token.FromString, token.FromEnv} {
+		if s != "" {
+			n++
+		}
+	}
+
+	switch n {
+	case 0:
+		return errors.New("bearer token must be provided with a source file, environment variable, or plaintext string")
+	case 1:
+		return nil
+	default:
+		return errors.New("only one source for the bearer token should be specified")
+	}
+}
+
+func (token *BearerToken) read(fsys fs.FS) (string, error) {
+	switch {
+	case token.FromFile != "":
+		t, err := readTokenFile(fsys, token.FromFile)
+		if err != nil {
+			return "", fmt.Errorf("error reading bearer token from file: %w", err)
+		}
+
+		return t, nil
+	case token.FromEnv != "":
+		t := os.Getenv(token.FromEnv)
+		if t == "" {
+			return "", fmt.Errorf("bearer token environment variable %q not set", token.FromEnv)
+		}
+
+		return t, nil
+	default:
+		return token.FromString, nil
+	}
+}
+
+type loginAuthMethod struct {
+	fsys       fs.FS
+	token      *BearerToken
+	meta       map[string]string
+	writeOpts  *api.WriteOptions
+	kind       string
+	authMethod string
+}
+
+func (a *loginAuthMethod) Login(ctx context.Context, client *api.Client) (*api.ACLToken, error) {
+	bearer, err := a.token.read(a.fsys)
+	if err != nil {
+		return nil, fmt.Errorf("%s login failed: %w", a.kind, err)
+	}
+
+	params := &api.ACLLoginParams{
+		AuthMethod:  a.authMethod,
+		BearerToken: bearer,
+		Meta:        a.meta,
+	}
+
+	token, _, err := client.ACL().Login(params, a.writeOpts.WithContext(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("%s login failed: %w", a.kind, err)
+	}
+
+	return token, nil
+}
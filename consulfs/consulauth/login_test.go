@@ -0,0 +1,129 @@
+package consulauth
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+
+	"github.com/hashicorp/consul/api/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeLoginClient returns a client for a fake Consul server which accepts
+// logins to the "myauth" auth method with the expected bearer token
+func fakeLoginClient(t *testing.T, bearer *string, meta map[string]string) *api.Client {
+	t.Helper()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/acl/login", r.URL.Path)
+
+		in := api.ACLLoginParams{}
+		_ = json.NewDecoder(r.Body).Decode(&in)
+
+		assert.Equal(t, "myauth", in.AuthMethod)
+		assert.Equal(t, meta, in.Meta)
+
+		if in.BearerToken != *bearer {
+			w.WriteHeader(http.StatusForbidden)
+
+			return
+		}
+
+		_ = json.NewEncoder(w).Encode(api.ACLToken{
+			AccessorID: "accessor-" + in.BearerToken,
+			SecretID:   "secret-" + in.BearerToken,
+		})
+	}))
+	t.Cleanup(srv.Close)
+
+	client, err := api.NewClient(&api.Config{Address: srv.URL})
+	require.NoError(t, err)
+
+	return client
+}
+
+func TestKubernetesAuth(t *testing.T) {
+	bearer := "sa.jwt.one"
+	client := fakeLoginClient(t, &bearer, nil)
+
+	_, err := NewKubernetesAuth("")
+	require.Error(t, err)
+
+	_, err = NewKubernetesAuth("myauth", WithBearerToken(nil))
+	require.Error(t, err)
+
+	fsys := fstest.MapFS{
+		"var/run/secrets/kubernetes.io/serviceaccount/token": &fstest.MapFile{Data: []byte(bearer + "\n")},
+	}
+
+	a, err := NewKubernetesAuth("myauth")
+	require.NoError(t, err)
+
+	a.(*loginAuthMethod).fsys = fsys
+
+	token, err := a.Login(t.Context(), client)
+	require.NoError(t, err)
+	assert.Equal(t, "secret-sa.jwt.one", token.SecretID)
+
+	// the token is read again at each login
+	bearer = "sa.jwt.two"
+	fsys["var/run/secrets/kubernetes.io/serviceaccount/token"].Data = []byte(bearer)
+
+	token, err = a.Login(t.Context(), client)
+	require.NoError(t, err)
+	assert.Equal(t, "secret-sa.jwt.two", token.SecretID)
+
+	// a projected token at a different path
+	fsys["run/token"] = &fstest.MapFile{Data: []byte(bearer)}
+
+	a, err = NewKubernetesAuth("myauth", WithBearerToken(&BearerToken{FromFile: "/run/token"}))
+	require.NoError(t, err)
+
+	a.(*loginAuthMethod).fsys = fsys
+
+	_, err = a.Login(t.Context(), client)
+	require.NoError(t, err)
+
+	bearer = "sa.jwt.three"
+
+	_, err = a.Login(t.Context(), client)
+	require.Error(t, err)
+}
+
+func TestJWTAuth(t *testing.T) {
+	bearer := "ci.jwt"
+	meta := map[string]string{"pipeline": "42"}
+	client := fakeLoginClient(t, &bearer, meta)
+
+	_, err := NewJWTAuth("myauth", nil)
+	require.Error(t, err)
+
+	_, err = NewJWTAuth("myauth", &BearerToken{})
+	require.Error(t, err)
+
+	_, err = NewJWTAuth("myauth", &BearerToken{FromString: "a", FromEnv: "B"})
+	require.Error(t, err)
+
+	a, err := NewJWTAuth("myauth", &BearerToken{FromString: bearer}, WithLoginMeta(meta),
+		WithLoginWriteOptions(&api.WriteOptions{Namespace: "team1"}))
+	require.NoError(t, err)
+
+	token, err := a.Login(t.Context(), client)
+	require.NoError(t, err)
+	assert.Equal(t, "secret-ci.jwt", token.SecretID)
+
+	a, err = NewJWTAuth("myauth", &BearerToken{FromEnv: "CONSUL_TEST_JWT"}, WithLoginMeta(meta))
+	require.NoError(t, err)
+
+	_, err = a.Login(t.Context(), client)
+	require.Error(t, err)
+
+	t.Setenv("CONSUL_TEST_JWT", bearer)
+
+	token, err = a.Login(t.Context(), client)
+	require.NoError(t, err)
+	assert.Equal(t, "secret-ci.jwt", token.SecretID)
+}
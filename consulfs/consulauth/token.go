@@ -0,0 +1,72 @@
+package consulauth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+
+	"github.com/hairyhenderson/go-fsimpl/internal/env"
+	"github.com/hashicorp/consul/api/v2"
+)
+
+// NewTokenAuth authenticates with the given ACL token, or if none is provided,
+// with the token from $CONSUL_HTTP_TOKEN, or the file named by
+// $CONSUL_HTTP_TOKEN_FILE (read again at each login).
+//
+// The token is not owned by consulfs, so it is never logged out.
+func NewTokenAuth(token string) AuthMethod {
+	return &tokenAuthMethod{fsys: os.DirFS("/"), token: token}
+}
+
+type tokenAuthMethod struct {
+	fsys  fs.FS
+	token string
+}
+
+func (m *tokenAuthMethod) Login(_ context.Context, _ *api.Client) (*api.ACLToken, error) {
+	token := m.token
+	if token == "" {
+		token = env.GetenvFS(m.fsys, api.HTTPTokenEnvName)
+	}
+
+	if token == "" {
+		return nil, errors.New("token auth failure: no token provided")
+	}
+
+	return &api.ACLToken{SecretID: token}, nil
+}
+
+// Logout does nothing, as the token is not owned by consulfs.
+func (m *tokenAuthMethod) Logout(_ context.Context, _ *api.Client, _ *api.ACLToken) error {
+	return nil
+}
+
+// NewTokenFileAuth authenticates with the ACL token in the file at path, such
+// as a token sink file written by "consul login". The file is read again at
+// each login.
+//
+// The token is not owned by consulfs, so it is never logged out.
+func NewTokenFileAuth(path string) AuthMethod {
+	return &tokenFileAuthMethod{fsys: os.DirFS("/"), path: absPath(path)}
+}
+
+type tokenFileAuthMethod struct {
+	fsys fs.FS
+	path string
+}
+
+func (m *tokenFileAuthMethod) Login(_ context.Context, _ *api.Client) (*api.ACLToken, error) {
+	token, err := readTokenFile(m.fsys, m.path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading ACL token from file: %w", err)
+	}
+
+	return &api.ACLToken{SecretID: token}, nil
+}
+
+// Logout does nothing, as the token is not owned by consulfs.
+func (m *tokenFileAuthMethod) Logout(_ context.Context, _ *api.Client, _ *api.ACLToken) error {
+	return nil
+}
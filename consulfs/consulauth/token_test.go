@@ -0,0 +1,90 @@
+package consulauth
+
+import (
+	"os"
+	"runtime"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTokenAuth(t *testing.T) {
+	ctx := t.Context()
+
+	token, err := NewTokenAuth("foo").Login(ctx, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "foo", token.SecretID)
+
+	t.Setenv("CONSUL_HTTP_TOKEN", "")
+	t.Setenv("CONSUL_HTTP_TOKEN_FILE", "")
+
+	_, err = NewTokenAuth("").Login(ctx, nil)
+	require.Error(t, err)
+
+	t.Setenv("CONSUL_HTTP_TOKEN", "bar")
+
+	token, err = NewTokenAuth("").Login(ctx, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "bar", token.SecretID)
+
+	t.Setenv("CONSUL_HTTP_TOKEN", "")
+	t.Setenv("CONSUL_HTTP_TOKEN_FILE", "/etc/consul/token")
+
+	a := NewTokenAuth("")
+	a.(*tokenAuthMethod).fsys = fstest.MapFS{
+		"etc/consul/token": &fstest.MapFile{Data: []byte("baz\n")},
+	}
+
+	token, err = a.Login(ctx, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "baz", token.SecretID)
+
+	// existing tokens are never logged out
+	require.NoError(t, a.(*tokenAuthMethod).Logout(ctx, nil, token))
+}
+
+func TestTokenFileAuth(t *testing.T) {
+	ctx := t.Context()
+	fsys := fstest.MapFS{"run/consul/token": &fstest.MapFile{Data: []byte("one\n")}}
+
+	a := NewTokenFileAuth("/run/consul/token")
+	a.(*tokenFileAuthMethod).fsys = fsys
+
+	token, err := a.Login(ctx, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "one", token.SecretID)
+
+	// the file is read again at each login
+	fsys["run/consul/token"].Data = []byte("two")
+
+	token, err = a.Login(ctx, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "two", token.SecretID)
+
+	fsys["run/consul/token"].Data = []byte{}
+
+	_, err = a.Login(ctx, nil)
+	require.Error(t, err)
+
+	a = NewTokenFileAuth("/bogus")
+	a.(*tokenFileAuthMethod).fsys = fsys
+
+	_, err = a.Login(ctx, nil)
+	require.Error(t, err)
+}
+
+func TestTokenFileAuth_RelativePath(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("token files are read relative to the root, which isn't supported on Windows")
+	}
+
+	t.Chdir(t.TempDir())
+
+	require.NoError(t, os.WriteFile("token", []byte("secret\n"), 0o600))
+
+	token, err := NewTokenFileAuth("token").Login(t.Context(), nil)
+	require.NoError(t, err)
+	assert.Equal(t, "secret", token.SecretID)
+}
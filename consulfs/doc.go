@@ -23,6 +23,96 @@
 //
 // See the [Consul KV Store docs] for more details.
 //
+// # Query Options
+//
+// The datacenter, namespace, admin partition, and cluster peer to read from,
+// and the consistency mode, can be set with URL query parameters:
+//
+//   - "dc": the datacenter
+//   - "ns": the namespace (Consul Enterprise)
+//   - "partition": the admin partition (Consul Enterprise)
+//   - "peer": the cluster peer
+//   - "consistency": the consistency mode - one of "stale", "consistent", or
+//     "default"
+//
+// For example:
+//
+//	consul://consul.example.com:8500/config/?dc=dc2&consistency=stale
+//
+// These can be set on the base URL, or on the name of a single file. They are
+// applied to all KV reads and listings, and take precedence over the
+// corresponding options set with [WithQueryOptionsFS].
+//
+// # Recursive Prefetch
+//
+// By default, each directory listing and each key is read with a separate
+// request, so walking a large tree (for example with [fs.WalkDir]) can take
+// thousands of requests. To avoid this, set the "prefetch" query parameter to
+// "true", or use the [WithPrefetchFS] extension. The whole subtree under the
+// base URL is then read with a single recursive request the first time it's
+// needed, and that snapshot serves all reads, listings, and stats. The
+// snapshot isn't refreshed (except that it's discarded after writes made
+// through the filesystem), so this is best suited to short-lived filesystems.
+// For example:
+//
+//	consul://consul.example.com:8500/config/?prefetch=true
+//
+// # Writing
+//
+// Although [fs.FS] is read-only, keys can be written and deleted with the
+// [WriteFile], [WriteFileCAS], and [RemoveAll] functions. Names are relative
+// to the base URL, in the same way as for reads. [WriteFileCAS] only writes
+// the key if it hasn't been modified since the given ModifyIndex (see
+// Metadata, below), and fails with [ErrCASConflict] otherwise. [RemoveAll]
+// deletes a key and all keys under it in a single transaction.
+//
+// To coordinate writes between multiple processes, use [Lock] to hold a lock
+// on a key with a Consul session:
+//
+//	lock, err := consulfs.Lock(fsys, "config/.lock")
+//	if err != nil {
+//		return err
+//	}
+//	defer lock.Unlock()
+//
+// Sessions can also be managed directly with [CreateSession],
+// [DestroySession], [Acquire], and [Release].
+//
+// # Metadata
+//
+// The metadata of each key (its ModifyIndex, CreateIndex, LockIndex, flags,
+// and the session holding a lock on it) is available from the [KVPairInfo]
+// returned by the Sys method of the file's [fs.FileInfo]. Consul doesn't
+// record when keys are modified, so the ModTime is always zero - use the
+// ModifyIndex to detect changes.
+//
+// # Service Catalog
+//
+// With the "consul+catalog" scheme (or "consul+catalog+https"), the
+// filesystem exposes the Consul service catalog instead of the KV store, as a
+// read-only tree of JSON files:
+//
+//	nodes/<node>.json
+//	services/<service>/<node-id>.json
+//
+// Each service instance file contains the instance's ID, address, port, tags,
+// metadata, and the aggregated status of its health checks, along with the
+// node it's registered on. Instance files are named after the node's ID, or
+// its name when it has no ID. When a service has several instances on the
+// same node, the instance's ID is appended to the file name (as in
+// "<node-id>-<id>.json"). The same data is available from the [ServiceInstance]
+// and [NodeInfo] returned by the Sys method of the file's [fs.FileInfo].
+//
+// To only include instances which are passing their health checks, set the
+// "passing" query parameter to "true", or use the [WithPassingOnlyFS]
+// extension. Services with no passing instances are empty directories. For
+// example:
+//
+//	consul+catalog://consul.example.com:8500/services/?passing=true
+//
+// The query options and authentication described above apply to the catalog
+// as well.
+//
 // # Authentication
 //
 // To authenticate with Consul, an [ACL Token] will need to be set. You can set
@@ -34,6 +124,18 @@
 //
 // Usually, you'll want to use the [WithTokenFS] extension.
 //
+// To log in with a Consul [ACL auth method] (such as the Kubernetes or JWT auth
+// methods), use the [consulauth.WithAuthMethod] extension with one of the auth
+// methods from the [consulauth] package. The token acquired by logging in is
+// shared by all copies of the filesystem. When Consul no longer recognises the
+// token (for example because it expired), the filesystem logs in again. The
+// token is logged out when the filesystem is closed, so the filesystem must be
+// closed when no longer needed:
+//
+//	auth, _ := consulauth.NewKubernetesAuth("my-k8s-auth-method")
+//	fsys = consulauth.WithAuthMethod(auth, fsys)
+//	defer fsys.(io.Closer).Close()
+//
 // # Extensions
 //
 // The filesystem may be configured with a few standard and consulfs-specific
@@ -41,10 +143,15 @@
 //
 //   - [fsimpl.WithContextFS]
 //   - [fsimpl.WithHeaderFS]
+//   - [fsimpl.WithTLSConfigFS]
 //   - [WithTokenFS]
+//   - [consulauth.WithAuthMethod]
 //   - [WithConfigFS]
 //   - [WithQueryOptionsFS]
+//   - [WithPassingOnlyFS]
+//   - [WithPrefetchFS]
 //
 // [Consul KV Store docs]: https://www.consul.io/docs/dynamic-app-config/kv
 // [ACL Token]: https://www.consul.io/docs/security/acl/acl-tokens
+// [ACL auth method]: https://developer.hashicorp.com/consul/docs/security/acl/auth-methods
 package consulfs
@@ -3,9 +3,14 @@ package consulfs
 import (
 	"io/fs"
 
+	"github.com/hairyhenderson/go-fsimpl/consulfs/consulauth"
 	"github.com/hashicorp/consul/api/v2"
 )
 
+type withAuthMethoder interface {
+	WithAuthMethod(auth consulauth.AuthMethod) fs.FS
+}
+
 type withConfiger interface {
 	WithConfig(config *api.Config) fs.FS
 }
@@ -14,6 +19,14 @@ type withQueryOptionser interface {
 	WithQueryOptions(opts *api.QueryOptions) fs.FS
 }
 
+type withPassingOnlyer interface {
+	WithPassingOnly() fs.FS
+}
+
+type withPrefetcher interface {
+	WithPrefetch() fs.FS
+}
+
 type withTokener interface {
 	WithToken(token string) fs.FS
 }
@@ -63,3 +76,34 @@ func WithQueryOptionsFS(opts *api.QueryOptions, fsys fs.FS) fs.FS {
 
 	return fsys
 }
+
+// WithPassingOnlyFS restricts the service instances listed by a
+// "consul+catalog" filesystem to those which are passing their health checks,
+// if the filesystem supports it (i.e. has a WithPassingOnly method). This is
+// equivalent to setting the "passing=true" URL query parameter.
+func WithPassingOnlyFS(fsys fs.FS) fs.FS {
+	if fsys, ok := fsys.(withPassingOnlyer); ok {
+		return fsys.WithPassingOnly()
+	}
+
+	return fsys
+}
+
+// WithPrefetchFS enables recursive prefetch on fsys, if the filesystem
+// supports it (i.e. has a WithPrefetch method). The whole subtree under the
+// filesystem's base URL is read with a single request the first time it's
+// needed, and is then used for all reads, listings, and stats. This is much
+// faster when walking large trees (for example with [fs.WalkDir]), but the
+// data isn't refreshed - call WithPrefetchFS again for a new snapshot. Writes
+// made through the filesystem (for example with [WriteFile]) discard the
+// snapshot, so that it's read again when next needed. This is equivalent to
+// setting the "prefetch=true" URL query parameter.
+//
+// Files opened with their own query parameters are always read directly.
+func WithPrefetchFS(fsys fs.FS) fs.FS {
+	if fsys, ok := fsys.(withPrefetcher); ok {
+		return fsys.WithPrefetch()
+	}
+
+	return fsys
+}
@@ -2,11 +2,18 @@ package consulfs
 
 import (
 	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
 	"net/http"
 	"net/http/httptest"
+	"slices"
 	"sort"
+	"strconv"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/hashicorp/consul/api/v2"
 )
@@ -39,8 +46,13 @@ func fakeConsulServer(t *testing.T) *api.Config {
 
 // consulKVEntry represents a key-value entry in the fake Consul server
 type consulKVEntry struct {
-	Value string   `json:"value,omitempty"`
-	Keys  []string `json:"keys,omitempty"`
+	Value       string   `json:"value,omitempty"`
+	Session     string   `json:"session,omitempty"`
+	Keys        []string `json:"keys,omitempty"`
+	CreateIndex uint64   `json:"createIndex,omitempty"`
+	ModifyIndex uint64   `json:"modifyIndex,omitempty"`
+	LockIndex   uint64   `json:"lockIndex,omitempty"`
+	Flags       uint64   `json:"flags,omitempty"`
 }
 
 // fakeConsulHandler creates an HTTP handler for a fake Consul KV API
@@ -124,7 +136,251 @@ func handleGetRequest(t *testing.T, w http.ResponseWriter, key string, data cons
 	}
 
 	return []*api.KVPair{{
-		Key:   key,
-		Value: []byte(data.Value),
+		Key:         key,
+		Value:       []byte(data.Value),
+		Session:     data.Session,
+		CreateIndex: data.CreateIndex,
+		ModifyIndex: data.ModifyIndex,
+		LockIndex:   data.LockIndex,
+		Flags:       data.Flags,
 	}}
 }
+
+// fakeKVStore is a stateful fake of the Consul KV, transaction, and session
+// APIs, for testing writes and locks
+type fakeKVStore struct {
+	t        *testing.T
+	pairs    map[string]*api.KVPair
+	sessions map[string]bool
+	// scopes records the "<dc>/<partition>" of each request
+	scopes  map[string]bool
+	changed chan struct{}
+	index   uint64
+	mu      sync.Mutex
+}
+
+func newFakeKVStore(t *testing.T) (*fakeKVStore, *api.Config) {
+	t.Helper()
+
+	s := &fakeKVStore{
+		t:        t,
+		pairs:    map[string]*api.KVPair{},
+		sessions: map[string]bool{},
+		scopes:   map[string]bool{},
+		changed:  make(chan struct{}),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v1/kv/{key...}", s.get)
+	mux.HandleFunc("PUT /v1/kv/{key...}", s.put)
+	mux.HandleFunc("PUT /v1/txn", s.txn)
+	mux.HandleFunc("PUT /v1/session/create", s.createSession)
+	mux.HandleFunc("PUT /v1/session/renew/{id}", s.renewSession)
+	mux.HandleFunc("PUT /v1/session/destroy/{id}", s.destroySession)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+
+		s.mu.Lock()
+		s.scopes[q.Get("dc")+"/"+q.Get("partition")] = true
+		s.mu.Unlock()
+
+		mux.ServeHTTP(w, r)
+	}))
+	t.Cleanup(srv.Close)
+
+	return s, &api.Config{Address: srv.URL}
+}
+
+// modified must be called with s.mu held, after each change
+func (s *fakeKVStore) modified() uint64 {
+	s.index++
+	close(s.changed)
+	s.changed = make(chan struct{})
+
+	return s.index
+}
+
+func (s *fakeKVStore) get(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+
+	// blocking queries wait (briefly) for a change
+	if idx, _ := strconv.ParseUint(r.URL.Query().Get("index"), 10, 64); idx > 0 && idx >= s.index {
+		changed := s.changed
+		s.mu.Unlock()
+
+		select {
+		case <-changed:
+		case <-time.After(50 * time.Millisecond):
+		case <-r.Context().Done():
+		}
+
+		s.mu.Lock()
+	}
+
+	defer s.mu.Unlock()
+
+	w.Header().Set("X-Consul-Index", strconv.FormatUint(max(s.index, 1), 10))
+
+	if r.URL.Query().Has("recurse") {
+		pairs := []*api.KVPair{}
+
+		for _, k := range slices.Sorted(maps.Keys(s.pairs)) {
+			if strings.HasPrefix(k, r.PathValue("key")) {
+				pairs = append(pairs, s.pairs[k])
+			}
+		}
+
+		_ = json.NewEncoder(w).Encode(pairs)
+
+		return
+	}
+
+	pair, ok := s.pairs[r.PathValue("key")]
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+
+		return
+	}
+
+	_ = json.NewEncoder(w).Encode([]*api.KVPair{pair})
+}
+
+func (s *fakeKVStore) put(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := r.PathValue("key")
+	q := r.URL.Query()
+	body, _ := io.ReadAll(r.Body)
+	existing := s.pairs[key]
+
+	ok := true
+
+	switch {
+	case q.Has("cas"):
+		cas, _ := strconv.ParseUint(q.Get("cas"), 10, 64)
+		ok = (cas == 0 && existing == nil) || (existing != nil && existing.ModifyIndex == cas)
+	case q.Has("acquire"):
+		ok = s.sessions[q.Get("acquire")] &&
+			(existing == nil || existing.Session == "" || existing.Session == q.Get("acquire"))
+	case q.Has("release"):
+		ok = existing != nil && existing.Session == q.Get("release")
+	}
+
+	if ok {
+		pair := &api.KVPair{Key: key, Value: body}
+		if existing != nil {
+			pair.CreateIndex = existing.CreateIndex
+			pair.LockIndex = existing.LockIndex
+			pair.Session = existing.Session
+		}
+
+		pair.Flags, _ = strconv.ParseUint(q.Get("flags"), 10, 64)
+
+		switch {
+		case q.Has("acquire"):
+			if pair.Session == "" {
+				pair.LockIndex++
+			}
+
+			pair.Session = q.Get("acquire")
+		case q.Has("release"):
+			pair.Session = ""
+
+			if existing != nil {
+				pair.Value = existing.Value
+			}
+		}
+
+		pair.ModifyIndex = s.modified()
+		if pair.CreateIndex == 0 {
+			pair.CreateIndex = pair.ModifyIndex
+		}
+
+		s.pairs[key] = pair
+	}
+
+	_, _ = fmt.Fprintf(w, "%t", ok)
+}
+
+func (s *fakeKVStore) txn(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ops := api.TxnOps{}
+	_ = json.NewDecoder(r.Body).Decode(&ops)
+
+	for _, op := range ops {
+		switch op.KV.Verb {
+		case api.KVDelete:
+			delete(s.pairs, op.KV.Key)
+		case api.KVDeleteTree:
+			for k := range s.pairs {
+				if strings.HasPrefix(k, op.KV.Key) {
+					delete(s.pairs, k)
+				}
+			}
+		default:
+			s.t.Errorf("unexpected txn verb %q", op.KV.Verb)
+		}
+	}
+
+	s.modified()
+
+	_ = json.NewEncoder(w).Encode(api.TxnResponse{})
+}
+
+func (s *fakeKVStore) createSession(w http.ResponseWriter, _ *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id := fmt.Sprintf("session-%d", len(s.sessions)+1)
+	s.sessions[id] = true
+
+	_ = json.NewEncoder(w).Encode(map[string]string{"ID": id})
+}
+
+func (s *fakeKVStore) renewSession(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.sessions[r.PathValue("id")] {
+		w.WriteHeader(http.StatusNotFound)
+
+		return
+	}
+
+	_ = json.NewEncoder(w).Encode([]*api.SessionEntry{{ID: r.PathValue("id"), TTL: "15s"}})
+}
+
+func (s *fakeKVStore) destroySession(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id := r.PathValue("id")
+	s.sessions[id] = false
+
+	// locks held by the session are released
+	for _, pair := range s.pairs {
+		if pair.Session == id {
+			pair.Session = ""
+		}
+	}
+
+	s.modified()
+
+	_, _ = w.Write([]byte("true"))
+}
+
+// session returns the session holding the lock on key, if any
+func (s *fakeKVStore) session(key string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if pair, ok := s.pairs[key]; ok {
+		return pair.Session
+	}
+
+	return ""
+}
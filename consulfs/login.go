@@ -0,0 +1,204 @@
+package consulfs
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/hairyhenderson/go-fsimpl/consulfs/consulauth"
+	"github.com/hashicorp/consul/api/v2"
+)
+
+// an optional interface that auth methods may implement to override the
+// default logout
+type authLogouter interface {
+	Logout(ctx context.Context, client *api.Client, token *api.ACLToken) error
+}
+
+// loginSession holds the ACL token acquired with an auth method, shared
+// between all copies of a filesystem created with [consulauth.WithAuthMethod].
+// The token is acquired on first use, acquired again when Consul no longer
+// recognises it (such as when it expires), and logged out when the filesystem
+// is closed.
+type loginSession struct {
+	auth   consulauth.AuthMethod
+	client *api.Client
+	token  *api.ACLToken
+
+	// rejected is the last token which Consul no longer recognised
+	rejected string
+
+	mu     sync.Mutex
+	closed bool
+}
+
+// ensureToken returns the session's token, logging in with a client
+// configured by config if necessary. Safe for concurrent use.
+func (s *loginSession) ensureToken(ctx context.Context, config *api.Config) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return "", fs.ErrClosed
+	}
+
+	if s.token != nil {
+		return s.token.SecretID, nil
+	}
+
+	// copy the config, as the client modifies it
+	loginConfig := *config
+
+	client, err := api.NewClient(&loginConfig)
+	if err != nil {
+		return "", fmt.Errorf("consul client creation failed: %w", err)
+	}
+
+	token, err := s.auth.Login(ctx, client)
+	if err != nil {
+		return "", fmt.Errorf("consul login failure: %w", err)
+	}
+
+	if token == nil || token.SecretID == "" {
+		return "", errors.New("consul login failure: no token returned")
+	}
+
+	s.client = client
+	s.token = token
+
+	return token.SecretID, nil
+}
+
+// relogin discards the session's token when it's the rejected token, and
+// logs in again. It returns false when rejected isn't (and wasn't) the
+// session's token, in which case logging in again wouldn't help.
+func (s *loginSession) relogin(ctx context.Context, config *api.Config, rejected string) (string, bool, error) {
+	s.mu.Lock()
+
+	// the token is gone, so there's nothing to log out
+	if s.token != nil && s.token.SecretID == rejected {
+		s.token = nil
+		s.rejected = rejected
+	}
+
+	ok := rejected != "" && rejected == s.rejected
+
+	s.mu.Unlock()
+
+	if !ok {
+		return "", false, nil
+	}
+
+	token, err := s.ensureToken(ctx, config)
+
+	return token, true, err
+}
+
+// withRelogin configures hc (which must not be shared) to log in again with
+// loginConfig, and retry, when a request is rejected because Consul no longer
+// recognises the session's token
+func (s *loginSession) withRelogin(hc *http.Client, loginConfig *api.Config) {
+	base := hc.Transport
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	hc.Transport = &reloginTransport{base: base, session: s, config: loginConfig}
+}
+
+// reloginTransport retries requests rejected with an "ACL not found" error
+// once the session has logged in again
+type reloginTransport struct {
+	base    http.RoundTripper
+	session *loginSession
+	config  *api.Config
+}
+
+func (t *reloginTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+	if err != nil || resp.StatusCode != http.StatusForbidden {
+		return resp, err
+	}
+
+	// a request with a body can only be retried if the body can be re-read
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return resp, nil
+	}
+
+	b, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	if err != nil {
+		return nil, err
+	}
+
+	resp.Body = io.NopCloser(bytes.NewReader(b))
+
+	if !strings.Contains(string(b), "ACL not found") {
+		return resp, nil
+	}
+
+	token, ok, err := t.session.relogin(req.Context(), t.config, req.Header.Get("X-Consul-Token"))
+	if err != nil {
+		return nil, err
+	}
+
+	if !ok {
+		return resp, nil
+	}
+
+	retry := req.Clone(req.Context())
+	retry.Header.Set("X-Consul-Token", token)
+
+	if req.GetBody != nil {
+		retry.Body, err = req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return t.base.RoundTrip(retry)
+}
+
+func (s *loginSession) isClosed() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.closed
+}
+
+// close logs out the session's token. Subsequent calls return fs.ErrClosed.
+func (s *loginSession) close(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return fs.ErrClosed
+	}
+
+	s.closed = true
+
+	if s.token == nil {
+		return nil
+	}
+
+	token := s.token
+	s.token = nil
+
+	if lauth, ok := s.auth.(authLogouter); ok {
+		return lauth.Logout(ctx, s.client, token)
+	}
+
+	_, err := s.client.ACL().Logout((&api.WriteOptions{Token: token.SecretID}).WithContext(ctx))
+	if err != nil {
+		return fmt.Errorf("consul logout failure: %w", err)
+	}
+
+	return nil
+}
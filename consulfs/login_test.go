@@ -0,0 +1,182 @@
+package consulfs
+
+import (
+	"encoding/json"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+
+	"github.com/hairyhenderson/go-fsimpl/consulfs/consulauth"
+	"github.com/hairyhenderson/go-fsimpl/internal/tests"
+	"github.com/hashicorp/consul/api/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuthMethodLogin(t *testing.T) {
+	var logins, logouts atomic.Int32
+
+	handler := fakeConsulHandler(t, map[string]consulKVEntry{
+		"/v1/kv/foo": {Value: "foo value"},
+	})
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/acl/login":
+			logins.Add(1)
+
+			in := api.ACLLoginParams{}
+			_ = json.NewDecoder(r.Body).Decode(&in)
+
+			assert.Equal(t, "myauth", in.AuthMethod)
+			assert.Equal(t, "some.jwt", in.BearerToken)
+
+			_ = json.NewEncoder(w).Encode(api.ACLToken{SecretID: "logged-in-token"})
+		case "/v1/acl/logout":
+			logouts.Add(1)
+
+			assert.Equal(t, "logged-in-token", r.Header.Get("X-Consul-Token"))
+		default:
+			if r.Header.Get("X-Consul-Token") != "logged-in-token" {
+				w.WriteHeader(http.StatusForbidden)
+
+				return
+			}
+
+			handler(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	// a static token must not take precedence over the login token
+	t.Setenv("CONSUL_HTTP_TOKEN", "static-token")
+
+	auth, err := consulauth.NewJWTAuth("myauth", &consulauth.BearerToken{FromString: "some.jwt"})
+	require.NoError(t, err)
+
+	fsys, err := New(tests.MustURL("consul:///"))
+	require.NoError(t, err)
+
+	fsys = WithConfigFS(&api.Config{Address: srv.URL}, fsys)
+	fsys = consulauth.WithAuthMethod(auth, fsys)
+
+	b, err := fs.ReadFile(fsys, "foo")
+	require.NoError(t, err)
+	assert.Equal(t, "foo value", string(b))
+
+	// copies of the filesystem share the token
+	b, err = fs.ReadFile(WithTokenFS("other", fsys), "foo")
+	require.NoError(t, err)
+	assert.Equal(t, "foo value", string(b))
+	assert.Equal(t, int32(1), logins.Load())
+
+	require.NoError(t, fsys.(io.Closer).Close())
+	assert.Equal(t, int32(1), logouts.Load())
+
+	_, err = fs.ReadFile(fsys, "foo")
+	require.ErrorIs(t, err, fs.ErrClosed)
+
+	require.ErrorIs(t, fsys.(io.Closer).Close(), fs.ErrClosed)
+	assert.Equal(t, int32(1), logouts.Load())
+}
+
+func TestAuthMethodLogin_Expired(t *testing.T) {
+	var logins atomic.Int32
+
+	handler := fakeConsulHandler(t, map[string]consulKVEntry{
+		"/v1/kv/foo": {Value: "foo value"},
+	})
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/v1/acl/login":
+			n := logins.Add(1)
+
+			_ = json.NewEncoder(w).Encode(api.ACLToken{SecretID: "token" + strconv.Itoa(int(n))})
+		case r.Header.Get("X-Consul-Token") != "token"+strconv.Itoa(int(logins.Load())):
+			// only the latest token is known, as though the others expired
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte("ACL not found"))
+		default:
+			handler(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	auth, err := consulauth.NewJWTAuth("myauth", &consulauth.BearerToken{FromString: "some.jwt"})
+	require.NoError(t, err)
+
+	fsys, err := New(tests.MustURL("consul:///"))
+	require.NoError(t, err)
+
+	fsys = consulauth.WithAuthMethod(auth, WithConfigFS(&api.Config{Address: srv.URL}, fsys))
+
+	f, err := fsys.Open("foo")
+	require.NoError(t, err)
+
+	// the token expires while the file is open
+	logins.Add(1)
+
+	b, err := io.ReadAll(f)
+	require.NoError(t, err)
+	assert.Equal(t, "foo value", string(b))
+	assert.Equal(t, int32(3), logins.Load())
+
+	// the new token is used from then on
+	b, err = fs.ReadFile(fsys, "foo")
+	require.NoError(t, err)
+	assert.Equal(t, "foo value", string(b))
+	assert.Equal(t, int32(3), logins.Load())
+}
+
+func TestAuthMethodLogin_NotLoggedOut(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.NotEqual(t, "/v1/acl/logout", r.URL.Path)
+		assert.Equal(t, "static-token", r.Header.Get("X-Consul-Token"))
+
+		fakeConsulHandler(t, map[string]consulKVEntry{"/v1/kv/foo": {Value: "foo"}})(w, r)
+	}))
+	t.Cleanup(srv.Close)
+
+	fsys, err := New(tests.MustURL("consul:///"))
+	require.NoError(t, err)
+
+	fsys = WithConfigFS(&api.Config{Address: srv.URL}, fsys)
+	fsys = consulauth.WithAuthMethod(consulauth.NewTokenAuth("static-token"), fsys)
+
+	_, err = fs.ReadFile(fsys, "foo")
+	require.NoError(t, err)
+
+	require.NoError(t, fsys.(io.Closer).Close())
+
+	// closing without an auth method is a no-op
+	fsys, err = New(tests.MustURL("consul:///"))
+	require.NoError(t, err)
+
+	require.NoError(t, fsys.(io.Closer).Close())
+}
+
+func TestAuthMethodLogin_Failure(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	t.Cleanup(srv.Close)
+
+	auth, err := consulauth.NewJWTAuth("myauth", &consulauth.BearerToken{FromString: "bad.jwt"})
+	require.NoError(t, err)
+
+	fsys, err := New(tests.MustURL("consul:///"))
+	require.NoError(t, err)
+
+	fsys = consulauth.WithAuthMethod(auth, WithConfigFS(&api.Config{Address: srv.URL}, fsys))
+
+	_, err = fs.ReadFile(fsys, "foo")
+	require.Error(t, err)
+
+	// nothing to log out
+	require.NoError(t, fsys.(io.Closer).Close())
+}
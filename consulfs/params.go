@@ -0,0 +1,68 @@
+package consulfs
+
+import (
+	"fmt"
+	"io/fs"
+	"net/url"
+	"strings"
+
+	"github.com/hashicorp/consul/api/v2"
+)
+
+// URL query parameters which set Consul query options
+const (
+	datacenterParam  = "dc"
+	namespaceParam   = "ns"
+	partitionParam   = "partition"
+	consistencyParam = "consistency"
+	peerParam        = "peer"
+)
+
+// queryOptionsFromURL returns the query options set by the query parameters in
+// u, applied on top of a copy of base. When none are set, base is returned
+// unchanged.
+func queryOptionsFromURL(u *url.URL, base *api.QueryOptions) (*api.QueryOptions, error) {
+	q := u.Query()
+
+	if !q.Has(datacenterParam) && !q.Has(namespaceParam) && !q.Has(partitionParam) &&
+		!q.Has(consistencyParam) && !q.Has(peerParam) {
+		return base, nil
+	}
+
+	opts := &api.QueryOptions{}
+	if base != nil {
+		*opts = *base
+	}
+
+	if q.Has(datacenterParam) {
+		opts.Datacenter = q.Get(datacenterParam)
+	}
+
+	if q.Has(namespaceParam) {
+		opts.Namespace = q.Get(namespaceParam)
+	}
+
+	if q.Has(partitionParam) {
+		opts.Partition = q.Get(partitionParam)
+	}
+
+	if q.Has(peerParam) {
+		opts.Peer = q.Get(peerParam)
+	}
+
+	if q.Has(consistencyParam) {
+		switch mode := strings.ToLower(q.Get(consistencyParam)); mode {
+		case "stale":
+			opts.AllowStale, opts.RequireConsistent = true, false
+		case "consistent":
+			opts.AllowStale, opts.RequireConsistent = false, true
+		case "default", "":
+			opts.AllowStale, opts.RequireConsistent = false, false
+		default:
+			return nil, fmt.Errorf("invalid consistency mode %q, must be one of stale, consistent, or default: %w",
+				mode, fs.ErrInvalid)
+		}
+	}
+
+	return opts, nil
+}
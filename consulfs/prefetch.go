@@ -0,0 +1,125 @@
+package consulfs
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"maps"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/hashicorp/consul/api/v2"
+)
+
+// prefetchParam is the URL query parameter used to enable recursive prefetch
+const prefetchParam = "prefetch"
+
+// prefetchFromURL reads the "prefetch" query parameter from u
+func prefetchFromURL(u *url.URL) (bool, error) {
+	q := u.Query()
+	if !q.Has(prefetchParam) {
+		return false, nil
+	}
+
+	prefetch, err := strconv.ParseBool(q.Get(prefetchParam))
+	if err != nil {
+		return false, fmt.Errorf("invalid %s parameter %q: %w", prefetchParam, q.Get(prefetchParam), fs.ErrInvalid)
+	}
+
+	return prefetch, nil
+}
+
+// kvSnapshot is an in-memory copy of the subtree of the KV store under a
+// prefix, read with a single recursive request the first time it's needed.
+// It's shared by all files opened from the filesystem, so that walking the
+// tree doesn't need a request for each key.
+type kvSnapshot struct {
+	tree   *kvTree
+	prefix string
+	mu     sync.Mutex
+}
+
+// kvTree holds the KV pairs read into a snapshot. It's never modified once
+// read.
+type kvTree struct {
+	// pairs holds every KV pair under the prefix, by key
+	pairs map[string]*api.KVPair
+
+	// dirs holds the sorted keys of the direct children of each directory
+	// (keyed with a trailing "/"), with directory keys also ending in "/"
+	dirs map[string][]string
+}
+
+func newKVSnapshot(prefix string) *kvSnapshot {
+	return &kvSnapshot{prefix: strings.TrimPrefix(prefix, "/")}
+}
+
+// load returns the subtree, reading it unless it's already been read. Errors
+// aren't cached, so a failed load is retried on the next call.
+func (s *kvSnapshot) load(ctx context.Context, kv *api.KV, opts *api.QueryOptions) (*kvTree, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.tree != nil {
+		return s.tree, nil
+	}
+
+	pairs, _, err := kv.List(s.prefix, opts.WithContext(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("kv.List: %w", err)
+	}
+
+	tree := &kvTree{pairs: make(map[string]*api.KVPair, len(pairs))}
+	children := map[string]map[string]struct{}{}
+
+	for _, pair := range pairs {
+		tree.pairs[pair.Key] = pair
+
+		// register the key with each of its ancestors under the prefix (keys
+		// ending in "/" are folder placeholders, and only create directories)
+		key := pair.Key
+		for key != s.prefix && strings.HasPrefix(key, s.prefix) {
+			parent := key[:strings.LastIndex(strings.TrimSuffix(key, "/"), "/")+1]
+
+			if children[parent] == nil {
+				children[parent] = map[string]struct{}{}
+			}
+
+			children[parent][key] = struct{}{}
+			key = parent
+		}
+	}
+
+	tree.dirs = make(map[string][]string, len(children))
+
+	for dir, keys := range children {
+		tree.dirs[dir] = slices.Sorted(maps.Keys(keys))
+	}
+
+	s.tree = tree
+
+	return tree, nil
+}
+
+// invalidate discards the snapshot (for example after a write), so that it's
+// read again when next needed
+func (s *kvSnapshot) invalidate() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.tree = nil
+}
+
+// get returns the KV pair for key, or nil if there's none (like [api.KV.Get])
+func (t *kvTree) get(key string) *api.KVPair {
+	return t.pairs[key]
+}
+
+// keys returns the keys of the direct children of the directory dir, which
+// must end in "/", or nil if it doesn't exist
+func (t *kvTree) keys(dir string) []string {
+	return t.dirs[strings.TrimPrefix(dir, "/")]
+}
@@ -0,0 +1,194 @@
+package consulfs
+
+import (
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"testing/fstest"
+
+	"github.com/hairyhenderson/go-fsimpl/internal/tests"
+	"github.com/hashicorp/consul/api/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKVSnapshot(t *testing.T) {
+	// folder placeholder keys create directories
+	files := map[string]consulKVEntry{
+		"/v1/kv/dir/":           {},
+		"/v1/kv/dir/a/b/":       {},
+		"/v1/kv/dir/a/b/c/file": {Value: "c"},
+		"/v1/kv/dir/foo":        {Value: "foo"},
+	}
+
+	var reqs atomic.Int32
+
+	handler := fakeConsulHandler(t, files)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reqs.Add(1)
+
+		handler(w, r)
+	}))
+	t.Cleanup(srv.Close)
+
+	client, err := api.NewClient(&api.Config{Address: srv.URL})
+	require.NoError(t, err)
+
+	s := newKVSnapshot("/dir/")
+
+	tree, err := s.load(t.Context(), client.KV(), nil)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"dir/a/", "dir/foo"}, tree.keys("dir/"))
+	assert.Equal(t, []string{"dir/a/b/"}, tree.keys("dir/a/"))
+	assert.Equal(t, []string{"dir/a/b/c/"}, tree.keys("dir/a/b/"))
+	assert.Equal(t, []string{"dir/a/b/c/file"}, tree.keys("dir/a/b/c/"))
+	assert.Nil(t, tree.keys("dir/bogus/"))
+	assert.Equal(t, "foo", string(tree.get("dir/foo").Value))
+	assert.Nil(t, tree.get("dir/bogus"))
+
+	// loaded only once, until invalidated
+	_, err = s.load(t.Context(), client.KV(), nil)
+	require.NoError(t, err)
+	assert.Equal(t, int32(1), reqs.Load())
+
+	s.invalidate()
+
+	_, err = s.load(t.Context(), client.KV(), nil)
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), reqs.Load())
+}
+
+func TestPrefetch(t *testing.T) {
+	var reqs atomic.Int32
+
+	handler := fakeConsulHandler(t, map[string]consulKVEntry{
+		"/v1/kv/":                   {Keys: []string{"bar", "dir/", "foo"}},
+		"/v1/kv/foo":                {Value: "foo value"},
+		"/v1/kv/bar":                {Value: "bar value"},
+		"/v1/kv/dir/":               {Keys: []string{"dir/bar", "dir/foo", "dir/sub/"}},
+		"/v1/kv/dir/foo":            {Value: "foo"},
+		"/v1/kv/dir/bar":            {Value: "bar"},
+		"/v1/kv/dir/sub/":           {Keys: []string{"dir/sub/bazDir/", "dir/sub/foo"}},
+		"/v1/kv/dir/sub/foo":        {Value: "foo"},
+		"/v1/kv/dir/sub/bazDir/":    {Keys: []string{"dir/sub/bazDir/qux"}},
+		"/v1/kv/dir/sub/bazDir/qux": {Value: "qux"},
+	})
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reqs.Add(1)
+
+		handler(w, r)
+	}))
+	t.Cleanup(srv.Close)
+
+	config := &api.Config{Address: srv.URL}
+
+	fsys, err := New(tests.MustURL("consul:///dir/?prefetch=true"))
+	require.NoError(t, err)
+
+	fsys = WithConfigFS(config, fsys)
+
+	require.NoError(t, fstest.TestFS(fsys, "bar", "foo", "sub/foo", "sub/bazDir/qux"))
+
+	b, err := fs.ReadFile(fsys, "sub/bazDir/qux")
+	require.NoError(t, err)
+	assert.Equal(t, "qux", string(b))
+
+	_, err = fs.ReadFile(fsys, "bogus")
+	require.ErrorIs(t, err, fs.ErrNotExist)
+
+	_, err = fs.Stat(fsys, "sub/bogus")
+	require.ErrorIs(t, err, fs.ErrNotExist)
+
+	// everything was served from a single recursive request
+	assert.Equal(t, int32(1), reqs.Load())
+
+	// files with their own query parameters are read directly
+	b, err = fs.ReadFile(fsys, "foo?dc=dc2")
+	require.NoError(t, err)
+	assert.Equal(t, "foo", string(b))
+	assert.Equal(t, int32(2), reqs.Load())
+
+	// a prefetched walk finds the same entries as a normal one
+	walk := func(fsys fs.FS) []string {
+		var found []string
+
+		err := fs.WalkDir(fsys, ".", func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+
+			fi, err := d.Info()
+			if err != nil {
+				return err
+			}
+
+			found = append(found, p+" "+fi.Mode().String())
+
+			return nil
+		})
+		require.NoError(t, err)
+
+		return found
+	}
+
+	fsys, err = New(tests.MustURL("consul:///"))
+	require.NoError(t, err)
+
+	fsys = WithConfigFS(config, fsys)
+
+	expected := walk(fsys)
+
+	reqs.Store(0)
+
+	assert.Equal(t, expected, walk(WithPrefetchFS(fsys)))
+	assert.Equal(t, int32(1), reqs.Load())
+
+	_, err = New(tests.MustURL("consul:///?prefetch=maybe"))
+	require.Error(t, err)
+}
+
+func TestPrefetch_WithToken(t *testing.T) {
+	handlers := map[string]http.HandlerFunc{
+		"alice": fakeConsulHandler(t, map[string]consulKVEntry{
+			"/v1/kv/dir/":    {Keys: []string{"dir/foo"}},
+			"/v1/kv/dir/foo": {Value: "alice's foo"},
+		}),
+		"bob": fakeConsulHandler(t, map[string]consulKVEntry{
+			"/v1/kv/dir/":    {Keys: []string{"dir/foo"}},
+			"/v1/kv/dir/foo": {Value: "bob's foo"},
+		}),
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handler, ok := handlers[r.Header.Get("X-Consul-Token")]
+		if !ok {
+			w.WriteHeader(http.StatusForbidden)
+
+			return
+		}
+
+		handler(w, r)
+	}))
+	t.Cleanup(srv.Close)
+
+	fsys, err := New(tests.MustURL("consul:///dir/?prefetch=true"))
+	require.NoError(t, err)
+
+	fsys = WithConfigFS(&api.Config{Address: srv.URL}, fsys)
+
+	alice := WithTokenFS("alice", fsys)
+	bob := WithTokenFS("bob", alice)
+
+	b, err := fs.ReadFile(alice, "foo")
+	require.NoError(t, err)
+	assert.Equal(t, "alice's foo", string(b))
+
+	b, err = fs.ReadFile(bob, "foo")
+	require.NoError(t, err)
+	assert.Equal(t, "bob's foo", string(b))
+}
@@ -0,0 +1,432 @@
+package consulfs
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"strings"
+
+	"github.com/hairyhenderson/go-fsimpl/internal"
+	"github.com/hashicorp/consul/api/v2"
+)
+
+// ErrCASConflict is returned (wrapped in an [fs.PathError]) by [WriteFileCAS]
+// when the key has been modified since the given ModifyIndex.
+var ErrCASConflict = errors.New("check-and-set failed: key has been modified")
+
+// lockSessionName is the name of the sessions created by [Lock]
+const lockSessionName = "consulfs lock"
+
+// kvWriter is an fs.FS that can write to the KV store
+type kvWriter interface {
+	WriteFile(name string, data []byte) error
+	WriteFileCAS(name string, data []byte, modifyIndex uint64) error
+	RemoveAll(name string) error
+	Acquire(name, session string, data []byte) (bool, error)
+	Release(name, session string) (bool, error)
+}
+
+// kvLocker is an fs.FS that can manage sessions and locks
+type kvLocker interface {
+	CreateSession(entry *api.SessionEntry) (string, error)
+	DestroySession(id string) error
+	Lock(name string) (*KVLock, error)
+}
+
+// WriteFile writes data to the key name, creating it if necessary, if the
+// filesystem supports it (i.e. has a WriteFile method).
+func WriteFile(fsys fs.FS, name string, data []byte) error {
+	if w, ok := fsys.(kvWriter); ok {
+		return w.WriteFile(name, data)
+	}
+
+	return &fs.PathError{Op: "write", Path: name, Err: errors.ErrUnsupported}
+}
+
+// WriteFileCAS writes data to the key name only if it hasn't been modified
+// since modifyIndex (see [KVPairInfo]), if the filesystem supports it (i.e.
+// has a WriteFileCAS method). A modifyIndex of 0 writes the key only if it
+// doesn't already exist.
+//
+// If the key has been modified, the returned error wraps [ErrCASConflict] -
+// the key should then be read again, and the write retried.
+func WriteFileCAS(fsys fs.FS, name string, data []byte, modifyIndex uint64) error {
+	if w, ok := fsys.(kvWriter); ok {
+		return w.WriteFileCAS(name, data, modifyIndex)
+	}
+
+	return &fs.PathError{Op: "write", Path: name, Err: errors.ErrUnsupported}
+}
+
+// RemoveAll atomically deletes the key name and all keys under it (as in
+// "name/..."), if the filesystem supports it (i.e. has a RemoveAll method).
+// Use "." to delete all keys under the filesystem's base URL. Deleting keys
+// that don't exist is not an error.
+func RemoveAll(fsys fs.FS, name string) error {
+	if w, ok := fsys.(kvWriter); ok {
+		return w.RemoveAll(name)
+	}
+
+	return &fs.PathError{Op: "removeAll", Path: name, Err: errors.ErrUnsupported}
+}
+
+// Acquire writes data to the key name, and acquires a lock on it for the given
+// session, if the filesystem supports it (i.e. has an Acquire method). It
+// returns false if the lock is held by another session.
+//
+// Most callers should use [Lock] instead, which manages the session.
+func Acquire(fsys fs.FS, name, session string, data []byte) (bool, error) {
+	if w, ok := fsys.(kvWriter); ok {
+		return w.Acquire(name, session, data)
+	}
+
+	return false, &fs.PathError{Op: "acquire", Path: name, Err: errors.ErrUnsupported}
+}
+
+// Release releases the lock held by the given session on the key name, if
+// the filesystem supports it (i.e. has a Release method). It returns false if
+// the lock isn't held by the session.
+func Release(fsys fs.FS, name, session string) (bool, error) {
+	if w, ok := fsys.(kvWriter); ok {
+		return w.Release(name, session)
+	}
+
+	return false, &fs.PathError{Op: "release", Path: name, Err: errors.ErrUnsupported}
+}
+
+// CreateSession creates a Consul session for use with [Acquire] and
+// [Release], if the filesystem supports it (i.e. has a CreateSession method).
+// The entry may be nil to use the default session options. The session should
+// be destroyed with [DestroySession] when no longer needed.
+func CreateSession(fsys fs.FS, entry *api.SessionEntry) (string, error) {
+	if l, ok := fsys.(kvLocker); ok {
+		return l.CreateSession(entry)
+	}
+
+	return "", &fs.PathError{Op: "createSession", Path: ".", Err: errors.ErrUnsupported}
+}
+
+// DestroySession destroys the session with the given ID, releasing all locks
+// it holds, if the filesystem supports it (i.e. has a DestroySession method).
+func DestroySession(fsys fs.FS, id string) error {
+	if l, ok := fsys.(kvLocker); ok {
+		return l.DestroySession(id)
+	}
+
+	return &fs.PathError{Op: "destroySession", Path: ".", Err: errors.ErrUnsupported}
+}
+
+// Lock acquires a lock on the key name, blocking until it's acquired, if the
+// filesystem supports it (i.e. has a Lock method). The lock is held with a new
+// session, which is renewed until [KVLock.Unlock] is called. The session is
+// created in the same datacenter, namespace, and admin partition as the key.
+// Use [fsimpl.WithContextFS] to stop waiting for the lock.
+//
+// Locks can be used to serialise writes to the same keys from multiple
+// processes, by agreeing on a lock key (such as "config/.lock").
+func Lock(fsys fs.FS, name string) (*KVLock, error) {
+	if l, ok := fsys.(kvLocker); ok {
+		return l.Lock(name)
+	}
+
+	return nil, &fs.PathError{Op: "lock", Path: name, Err: errors.ErrUnsupported}
+}
+
+// KVLock is a lock on a key, held with a Consul session.
+type KVLock struct {
+	lock *api.Lock
+	lost <-chan struct{}
+	name string
+}
+
+// Lost returns a channel which is closed if the lock is lost before Unlock is
+// called - for example if the session is invalidated, or Consul can't be
+// reached. Writes made after the lock is lost are no longer serialised.
+func (l *KVLock) Lost() <-chan struct{} {
+	return l.lost
+}
+
+// Unlock releases the lock, and destroys its session.
+func (l *KVLock) Unlock() error {
+	if err := l.lock.Unlock(); err != nil {
+		return &fs.PathError{Op: "unlock", Path: l.name, Err: err}
+	}
+
+	return nil
+}
+
+var (
+	_ kvWriter = (*consulFS)(nil)
+	_ kvLocker = (*consulFS)(nil)
+)
+
+func (f *consulFS) WriteFile(name string, data []byte) error {
+	key, opts, err := f.writeKey(name)
+	if err != nil {
+		return &fs.PathError{Op: "write", Path: name, Err: err}
+	}
+
+	_, err = f.client.KV().Put(&api.KVPair{Key: key, Value: data}, opts)
+	if err != nil {
+		return &fs.PathError{Op: "write", Path: name, Err: fmt.Errorf("kv.Put: %w", err)}
+	}
+
+	f.invalidateSnapshot()
+
+	return nil
+}
+
+func (f *consulFS) WriteFileCAS(name string, data []byte, modifyIndex uint64) error {
+	key, opts, err := f.writeKey(name)
+	if err != nil {
+		return &fs.PathError{Op: "write", Path: name, Err: err}
+	}
+
+	ok, _, err := f.client.KV().CAS(&api.KVPair{Key: key, Value: data, ModifyIndex: modifyIndex}, opts)
+	if err != nil {
+		return &fs.PathError{Op: "write", Path: name, Err: fmt.Errorf("kv.CAS: %w", err)}
+	}
+
+	if !ok {
+		return &fs.PathError{Op: "write", Path: name, Err: ErrCASConflict}
+	}
+
+	f.invalidateSnapshot()
+
+	return nil
+}
+
+func (f *consulFS) RemoveAll(name string) error {
+	key, opts, err := f.kvKey(name)
+	if err != nil {
+		return &fs.PathError{Op: "removeAll", Path: name, Err: err}
+	}
+
+	// never delete the whole KV store
+	if key == "" {
+		return &fs.PathError{Op: "removeAll", Path: name, Err: fs.ErrInvalid}
+	}
+
+	// the key and its children are deleted in one transaction, as deleting
+	// "foo" as a tree would also delete unrelated keys such as "foobar"
+	ops := api.TxnOps{}
+	if !strings.HasSuffix(key, "/") {
+		ops = append(ops, &api.TxnOp{KV: &api.KVTxnOp{Verb: api.KVDelete, Key: key}})
+		key += "/"
+	}
+
+	ops = append(ops, &api.TxnOp{KV: &api.KVTxnOp{Verb: api.KVDeleteTree, Key: key}})
+
+	ok, resp, _, err := f.client.Txn().Txn(ops, queryOptions(opts))
+	if err != nil {
+		return &fs.PathError{Op: "removeAll", Path: name, Err: fmt.Errorf("txn: %w", err)}
+	}
+
+	if !ok {
+		return &fs.PathError{Op: "removeAll", Path: name, Err: fmt.Errorf("txn failed: %v", resp.Errors)}
+	}
+
+	f.invalidateSnapshot()
+
+	return nil
+}
+
+func (f *consulFS) Acquire(name, session string, data []byte) (bool, error) {
+	key, opts, err := f.writeKey(name)
+	if err != nil {
+		return false, &fs.PathError{Op: "acquire", Path: name, Err: err}
+	}
+
+	ok, _, err := f.client.KV().Acquire(&api.KVPair{Key: key, Value: data, Session: session}, opts)
+	if err != nil {
+		return false, &fs.PathError{Op: "acquire", Path: name, Err: fmt.Errorf("kv.Acquire: %w", err)}
+	}
+
+	f.invalidateSnapshot()
+
+	return ok, nil
+}
+
+func (f *consulFS) Release(name, session string) (bool, error) {
+	key, opts, err := f.writeKey(name)
+	if err != nil {
+		return false, &fs.PathError{Op: "release", Path: name, Err: err}
+	}
+
+	ok, _, err := f.client.KV().Release(&api.KVPair{Key: key, Session: session}, opts)
+	if err != nil {
+		return false, &fs.PathError{Op: "release", Path: name, Err: fmt.Errorf("kv.Release: %w", err)}
+	}
+
+	f.invalidateSnapshot()
+
+	return ok, nil
+}
+
+func (f *consulFS) CreateSession(entry *api.SessionEntry) (string, error) {
+	_, opts, err := f.kvKey(".")
+	if err != nil {
+		return "", &fs.PathError{Op: "createSession", Path: ".", Err: err}
+	}
+
+	id, _, err := f.client.Session().Create(entry, opts)
+	if err != nil {
+		return "", &fs.PathError{Op: "createSession", Path: ".", Err: fmt.Errorf("session.Create: %w", err)}
+	}
+
+	return id, nil
+}
+
+func (f *consulFS) DestroySession(id string) error {
+	_, opts, err := f.kvKey(".")
+	if err != nil {
+		return &fs.PathError{Op: "destroySession", Path: ".", Err: err}
+	}
+
+	_, err = f.client.Session().Destroy(id, opts)
+	if err != nil {
+		return &fs.PathError{Op: "destroySession", Path: ".", Err: fmt.Errorf("session.Destroy: %w", err)}
+	}
+
+	return nil
+}
+
+func (f *consulFS) Lock(name string) (*KVLock, error) {
+	key, opts, err := f.writeKey(name)
+	if err != nil {
+		return nil, &fs.PathError{Op: "lock", Path: name, Err: err}
+	}
+
+	client, err := f.lockClient(opts)
+	if err != nil {
+		return nil, &fs.PathError{Op: "lock", Path: name, Err: err}
+	}
+
+	lock, err := client.LockOpts(&api.LockOptions{
+		Key:         key,
+		SessionName: lockSessionName,
+		Namespace:   opts.Namespace,
+	})
+	if err != nil {
+		return nil, &fs.PathError{Op: "lock", Path: name, Err: err}
+	}
+
+	lost, err := lock.Lock(f.ctx.Done())
+	if err != nil {
+		return nil, &fs.PathError{Op: "lock", Path: name, Err: err}
+	}
+
+	// the attempt was stopped before the lock was acquired
+	if lost == nil {
+		return nil, &fs.PathError{Op: "lock", Path: name, Err: f.ctx.Err()}
+	}
+
+	return &KVLock{lock: lock, lost: lost, name: name}, nil
+}
+
+// lockClient returns a client for the datacenter, admin partition, and token
+// in opts, as locks only take the namespace from their options, and are
+// otherwise managed with the client's configuration
+func (f *consulFS) lockClient(opts *api.WriteOptions) (*api.Client, error) {
+	if opts.Datacenter == "" && opts.Partition == "" && opts.Token == "" {
+		return f.client, nil
+	}
+
+	config := &api.Config{}
+	if f.config != nil {
+		*config = *f.config
+	}
+
+	if opts.Datacenter != "" {
+		config.Datacenter = opts.Datacenter
+	}
+
+	if opts.Partition != "" {
+		config.Partition = opts.Partition
+	}
+
+	fsys := *f
+	fsys.config = config
+	fsys.client = nil
+
+	if opts.Token != "" {
+		fsys.token = opts.Token
+	}
+
+	if err := fsys.initClient(); err != nil {
+		return nil, err
+	}
+
+	return fsys.client, nil
+}
+
+// writeKey returns the key to write for name, which must not be the
+// filesystem's root
+func (f *consulFS) writeKey(name string) (string, *api.WriteOptions, error) {
+	if name == "." {
+		return "", nil, fs.ErrInvalid
+	}
+
+	return f.kvKey(name)
+}
+
+// kvKey returns the key for name, and the write options set by the URL (and
+// any query options set on the filesystem)
+func (f *consulFS) kvKey(name string) (string, *api.WriteOptions, error) {
+	if !internal.ValidPath(name) {
+		return "", nil, fs.ErrInvalid
+	}
+
+	if f.catalog {
+		return "", nil, errors.ErrUnsupported
+	}
+
+	u, err := internal.SubURL(f.base, name)
+	if err != nil {
+		return "", nil, err
+	}
+
+	q, err := queryOptionsFromURL(u, f.queryOpts)
+	if err != nil {
+		return "", nil, err
+	}
+
+	if err = f.initClient(); err != nil {
+		return "", nil, err
+	}
+
+	return strings.TrimPrefix(u.Path, "/"), writeOptions(q).WithContext(f.ctx), nil
+}
+
+// writeOptions returns the write options corresponding to the query options
+func writeOptions(q *api.QueryOptions) *api.WriteOptions {
+	if q == nil {
+		return &api.WriteOptions{}
+	}
+
+	return &api.WriteOptions{
+		Datacenter: q.Datacenter,
+		Namespace:  q.Namespace,
+		Partition:  q.Partition,
+		Token:      q.Token,
+	}
+}
+
+// queryOptions returns the query options corresponding to the write options,
+// for requests (such as transactions) that take query options
+func queryOptions(w *api.WriteOptions) *api.QueryOptions {
+	return (&api.QueryOptions{
+		Datacenter: w.Datacenter,
+		Namespace:  w.Namespace,
+		Partition:  w.Partition,
+		Token:      w.Token,
+	}).WithContext(w.Context())
+}
+
+// invalidateSnapshot discards the prefetched snapshot (if any) after a write,
+// so that it's read again
+func (f *consulFS) invalidateSnapshot() {
+	if f.snapshot != nil {
+		f.snapshot.invalidate()
+	}
+}
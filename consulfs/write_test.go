@@ -0,0 +1,235 @@
+package consulfs
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/hairyhenderson/go-fsimpl"
+	"github.com/hairyhenderson/go-fsimpl/internal/tests"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteFile(t *testing.T) {
+	_, config := newFakeKVStore(t)
+
+	fsys, err := New(tests.MustURL("consul:///config/"))
+	require.NoError(t, err)
+
+	fsys = WithConfigFS(config, fsys)
+
+	require.NoError(t, WriteFile(fsys, "foo", []byte("foo value")))
+	require.NoError(t, WriteFile(fsys, "sub/bar", []byte("bar value")))
+
+	b, err := fs.ReadFile(fsys, "foo")
+	require.NoError(t, err)
+	assert.Equal(t, "foo value", string(b))
+
+	b, err = fs.ReadFile(fsys, "sub/bar")
+	require.NoError(t, err)
+	assert.Equal(t, "bar value", string(b))
+
+	require.ErrorIs(t, WriteFile(fsys, ".", nil), fs.ErrInvalid)
+	require.ErrorIs(t, WriteFile(fsys, "/foo", nil), fs.ErrInvalid)
+
+	// unsupported filesystems
+	require.ErrorIs(t, WriteFile(fstest.MapFS{}, "foo", nil), errors.ErrUnsupported)
+
+	catalog, err := New(tests.MustURL("consul+catalog:///"))
+	require.NoError(t, err)
+	require.ErrorIs(t, WriteFile(catalog, "foo", nil), errors.ErrUnsupported)
+}
+
+func TestWriteFileCAS(t *testing.T) {
+	_, config := newFakeKVStore(t)
+
+	fsys, err := New(tests.MustURL("consul:///config/"))
+	require.NoError(t, err)
+
+	fsys = WithConfigFS(config, fsys)
+
+	// index 0 only creates new keys
+	require.NoError(t, WriteFileCAS(fsys, "foo", []byte("one"), 0))
+	require.ErrorIs(t, WriteFileCAS(fsys, "foo", []byte("two"), 0), ErrCASConflict)
+
+	fi, err := fs.Stat(fsys, "foo")
+	require.NoError(t, err)
+
+	idx := fi.Sys().(*KVPairInfo).ModifyIndex
+
+	require.NoError(t, WriteFileCAS(fsys, "foo", []byte("two"), idx))
+
+	// the index is now stale
+	err = WriteFileCAS(fsys, "foo", []byte("three"), idx)
+	require.ErrorIs(t, err, ErrCASConflict)
+
+	var perr *fs.PathError
+	require.ErrorAs(t, err, &perr)
+	assert.Equal(t, "foo", perr.Path)
+
+	b, err := fs.ReadFile(fsys, "foo")
+	require.NoError(t, err)
+	assert.Equal(t, "two", string(b))
+}
+
+func TestRemoveAll(t *testing.T) {
+	store, config := newFakeKVStore(t)
+
+	fsys, err := New(tests.MustURL("consul:///config/"))
+	require.NoError(t, err)
+
+	fsys = WithConfigFS(config, fsys)
+
+	for _, name := range []string{"foo", "foobar", "foo/a", "foo/b/c", "bar"} {
+		require.NoError(t, WriteFile(fsys, name, []byte(name)))
+	}
+
+	require.NoError(t, RemoveAll(fsys, "foo"))
+
+	// keys merely prefixed by the name are kept
+	assert.Len(t, store.pairs, 2)
+	assert.Contains(t, store.pairs, "config/foobar")
+	assert.Contains(t, store.pairs, "config/bar")
+
+	// removing keys that don't exist is fine
+	require.NoError(t, RemoveAll(fsys, "bogus"))
+
+	require.NoError(t, RemoveAll(fsys, "."))
+	assert.Empty(t, store.pairs)
+
+	// the whole KV store can't be removed
+	root, err := New(tests.MustURL("consul:///"))
+	require.NoError(t, err)
+
+	root = WithConfigFS(config, root)
+	require.ErrorIs(t, RemoveAll(root, "."), fs.ErrInvalid)
+}
+
+func TestWrite_Prefetch(t *testing.T) {
+	_, config := newFakeKVStore(t)
+
+	fsys, err := New(tests.MustURL("consul:///config/?prefetch=true"))
+	require.NoError(t, err)
+
+	fsys = WithConfigFS(config, fsys)
+
+	require.NoError(t, WriteFile(fsys, "foo", []byte("one")))
+
+	b, err := fs.ReadFile(fsys, "foo")
+	require.NoError(t, err)
+	assert.Equal(t, "one", string(b))
+
+	// writes discard the snapshot
+	require.NoError(t, WriteFile(fsys, "foo", []byte("two")))
+
+	b, err = fs.ReadFile(fsys, "foo")
+	require.NoError(t, err)
+	assert.Equal(t, "two", string(b))
+}
+
+func TestAcquireRelease(t *testing.T) {
+	store, config := newFakeKVStore(t)
+
+	fsys, err := New(tests.MustURL("consul:///config/"))
+	require.NoError(t, err)
+
+	fsys = WithConfigFS(config, fsys)
+
+	s1, err := CreateSession(fsys, nil)
+	require.NoError(t, err)
+
+	s2, err := CreateSession(fsys, nil)
+	require.NoError(t, err)
+
+	ok, err := Acquire(fsys, "leader", s1, []byte("one"))
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, s1, store.session("config/leader"))
+
+	ok, err = Acquire(fsys, "leader", s2, []byte("two"))
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	ok, err = Release(fsys, "leader", s2)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	ok, err = Release(fsys, "leader", s1)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	ok, err = Acquire(fsys, "leader", s2, []byte("two"))
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	// destroying the session releases its locks
+	require.NoError(t, DestroySession(fsys, s2))
+	assert.Empty(t, store.session("config/leader"))
+
+	b, err := fs.ReadFile(fsys, "leader")
+	require.NoError(t, err)
+	assert.Equal(t, "two", string(b))
+}
+
+func TestLock(t *testing.T) {
+	store, config := newFakeKVStore(t)
+
+	fsys, err := New(tests.MustURL("consul:///config/"))
+	require.NoError(t, err)
+
+	fsys = WithConfigFS(config, fsys)
+
+	lock, err := Lock(fsys, ".lock")
+	require.NoError(t, err)
+	assert.NotEmpty(t, store.session("config/.lock"))
+
+	// a second lock can't be acquired while the first is held
+	ctx, cancel := context.WithTimeout(t.Context(), 200*time.Millisecond)
+	defer cancel()
+
+	_, err = Lock(fsimpl.WithContextFS(ctx, fsys), ".lock")
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+
+	select {
+	case <-lock.Lost():
+		t.Fatal("lock lost unexpectedly")
+	default:
+	}
+
+	require.NoError(t, lock.Unlock())
+	assert.Empty(t, store.session("config/.lock"))
+
+	select {
+	case <-lock.Lost():
+	case <-time.After(time.Second):
+		t.Fatal("lost channel not closed after unlock")
+	}
+
+	// now the lock can be acquired again
+	lock, err = Lock(fsys, ".lock")
+	require.NoError(t, err)
+	require.NoError(t, lock.Unlock())
+
+	_, err = Lock(fsys, ".")
+	require.ErrorIs(t, err, fs.ErrInvalid)
+}
+
+func TestLock_DatacenterAndPartition(t *testing.T) {
+	store, config := newFakeKVStore(t)
+
+	fsys, err := New(tests.MustURL("consul:///config/?dc=dc2&partition=part1"))
+	require.NoError(t, err)
+
+	fsys = WithConfigFS(config, fsys)
+
+	lock, err := Lock(fsys, ".lock")
+	require.NoError(t, err)
+	require.NoError(t, lock.Unlock())
+
+	// the lock's session and key must be in the same datacenter and partition
+	assert.Equal(t, map[string]bool{"dc2/part1": true}, store.scopes)
+}
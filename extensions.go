@@ -2,6 +2,7 @@ package fsimpl
 
 import (
 	"context"
+	"crypto/tls"
 	"io/fs"
 	"mime"
 	"net/http"
@@ -42,6 +43,22 @@ func WithHTTPClientFS(client *http.Client, fsys fs.FS) fs.FS {
 	return fsys
 }
 
+// WithTLSConfigFS injects a TLS configuration into the filesystem fs, if the
+// filesystem supports it (i.e. has a WithTLSConfig method). This can be used to
+// set custom CA certificates, client certificates for mutual TLS, or a minimum
+// TLS version.
+//
+// Filesystems that support this extension also understand the "ca_file",
+// "cert_file", "key_file", and "insecure_skip_verify" URL query parameters.
+// When both are used, the configuration given here takes precedence.
+func WithTLSConfigFS(config *tls.Config, fsys fs.FS) fs.FS {
+	if cfsys, ok := fsys.(internal.WithTLSConfiger); ok {
+		return cfsys.WithTLSConfig(config)
+	}
+
+	return fsys
+}
+
 // ContentType returns the MIME content type for the given [io/fs.FileInfo]. If
 // fi has a ContentType method, it will be used first, otherwise the filename's
 // extension will be used. See the docs for [mime.TypeByExtension] for details
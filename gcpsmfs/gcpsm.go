@@ -3,6 +3,7 @@ package gcpsmfs
 import (
 	"bytes"
 	"context"
+	"crypto/tls"
 	"errors"
 	"fmt"
 	"io"
@@ -24,7 +25,9 @@ import (
 	"golang.org/x/sync/errgroup"
 	"google.golang.org/api/iterator"
 	"google.golang.org/api/option"
+	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/status"
 )
 
@@ -107,9 +110,13 @@ type gcpsmFS struct {
 	smclient       SecretManagerClient
 	base           *url.URL
 	httpclient     *http.Client
+	tlsConfig      *tls.Config
+	cache          *secretCache
 	project        string
 	maxConcurrency int
-	cache          *secretCache
+	// builtClient is set when smclient was created by getClient (rather than
+	// set with WithSMClient), so must be recreated when the TLS config changes
+	builtClient bool
 }
 
 // New provides a filesystem (an fs.FS) backed by the GCP Secret Manager,
@@ -150,9 +157,15 @@ func New(u *url.URL) (fs.FS, error) {
 		return nil, fmt.Errorf("invalid URL scheme %q", u.Scheme)
 	}
 
+	tlsConfig, err := internal.TLSConfigFromURL(u)
+	if err != nil {
+		return nil, fmt.Errorf("invalid TLS configuration: %w", err)
+	}
+
 	f := &gcpsmFS{
 		ctx:            context.Background(),
 		base:           u,
+		tlsConfig:      tlsConfig,
 		maxConcurrency: defaultMaxConcurrency(),
 	}
 
@@ -198,6 +211,7 @@ var (
 	_ withSMClienter            = (*gcpsmFS)(nil)
 	_ withMaxConcurrencyer      = (*gcpsmFS)(nil)
 	_ withCacheEnabler          = (*gcpsmFS)(nil)
+	_ internal.WithTLSConfiger  = (*gcpsmFS)(nil)
 )
 
 func (f gcpsmFS) URL() string {
@@ -226,6 +240,22 @@ func (f *gcpsmFS) WithHTTPClient(client *http.Client) fs.FS {
 	return &fsys
 }
 
+func (f *gcpsmFS) WithTLSConfig(config *tls.Config) fs.FS {
+	if config == nil {
+		return f
+	}
+
+	fsys := *f
+	fsys.tlsConfig = config
+
+	if fsys.builtClient {
+		fsys.smclient = nil
+		fsys.builtClient = false
+	}
+
+	return &fsys
+}
+
 func (f *gcpsmFS) WithSMClient(smclient SecretManagerClient) fs.FS {
 	if smclient == nil {
 		return f
@@ -233,6 +263,7 @@ func (f *gcpsmFS) WithSMClient(smclient SecretManagerClient) fs.FS {
 
 	fsys := *f
 	fsys.smclient = smclient
+	fsys.builtClient = false
 
 	return &fsys
 }
@@ -267,21 +298,34 @@ func (f *gcpsmFS) getClient() (SecretManagerClient, error) {
 		return f.smclient, nil
 	}
 
-	opts := []option.ClientOption{}
-	if f.httpclient != nil {
-		opts = append(opts, option.WithHTTPClient(f.httpclient))
-	}
-
-	c, err := secretmanager.NewClient(f.ctx, opts...)
+	c, err := secretmanager.NewClient(f.ctx, f.clientOptions()...)
 	if err != nil {
 		return nil, err
 	}
 
 	f.smclient = &clientAdapter{c}
+	f.builtClient = true
 
 	return f.smclient, nil
 }
 
+// clientOptions returns the options for creating the Secret Manager client
+func (f *gcpsmFS) clientOptions() []option.ClientOption {
+	opts := []option.ClientOption{}
+	if f.httpclient != nil {
+		opts = append(opts, option.WithHTTPClient(f.httpclient))
+	}
+
+	// the Secret Manager client uses gRPC, so the TLS configuration must be
+	// set as transport credentials rather than on the HTTP client
+	if f.tlsConfig != nil {
+		opts = append(opts, option.WithGRPCDialOption(
+			grpc.WithTransportCredentials(credentials.NewTLS(f.tlsConfig))))
+	}
+
+	return opts
+}
+
 // getProjectAndFileName parses the project and file name out of name, given
 // the FS's configured project (which may be empty). On error, it returns a
 // plain fs.ErrInvalid (not wrapped in a *fs.PathError) so that callers can
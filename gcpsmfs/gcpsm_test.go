@@ -1,15 +1,28 @@
 package gcpsmfs
 
 import (
+	"crypto/tls"
+	"crypto/x509"
 	"fmt"
 	"io"
 	"io/fs"
+	"net/http"
+	"net/http/httptest"
 	"net/url"
+	"strconv"
+	"sync/atomic"
 	"testing"
 	"testing/fstest"
 
+	secretmanager "cloud.google.com/go/secretmanager/apiv1"
+	"cloud.google.com/go/secretmanager/apiv1/secretmanagerpb"
+	"github.com/hairyhenderson/go-fsimpl"
+	"github.com/hairyhenderson/go-fsimpl/internal/tests"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"google.golang.org/api/option"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 func TestNew(t *testing.T) {
@@ -538,3 +551,72 @@ func TestReadDir_ChecksProjectBeforeClientConstruction(t *testing.T) {
 	require.Error(t, err)
 	assert.Contains(t, err.Error(), "requires a project")
 }
+
+func TestTLSConfig(t *testing.T) {
+	var reqs atomic.Int32
+
+	// a gRPC server which rejects every call, enough to show that the TLS
+	// handshake succeeded
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		reqs.Add(1)
+
+		w.Header().Set("Content-Type", "application/grpc")
+		w.Header().Set("Grpc-Status", strconv.Itoa(int(codes.Unimplemented)))
+	}))
+	srv.EnableHTTP2 = true
+	srv.StartTLS()
+	t.Cleanup(srv.Close)
+
+	newClient := func(fsys fs.FS) *secretmanager.Client {
+		t.Helper()
+
+		opts := append(fsys.(*gcpsmFS).clientOptions(),
+			option.WithEndpoint(srv.Listener.Addr().String()),
+			option.WithoutAuthentication())
+
+		c, err := secretmanager.NewClient(t.Context(), opts...)
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = c.Close() })
+
+		return c
+	}
+
+	req := &secretmanagerpb.AccessSecretVersionRequest{Name: "projects/p/secrets/foo/versions/latest"}
+
+	fsys, err := New(tests.MustURL("gcp+sm:///projects/p"))
+	require.NoError(t, err)
+
+	// the server's certificate isn't trusted by default
+	_, err = newClient(fsys).AccessSecretVersion(t.Context(), req)
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+	assert.Equal(t, int32(0), reqs.Load())
+
+	pool := x509.NewCertPool()
+	pool.AddCert(srv.Certificate())
+
+	fsys = fsimpl.WithTLSConfigFS(&tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}, fsys)
+
+	_, err = newClient(fsys).AccessSecretVersion(t.Context(), req)
+	assert.Equal(t, codes.Unimplemented, status.Code(err))
+	assert.Equal(t, int32(1), reqs.Load())
+}
+
+func TestWithTLSConfig_RecreatesClient(t *testing.T) {
+	cfg := &tls.Config{MinVersion: tls.VersionTLS13}
+
+	fsys, err := New(tests.MustURL("gcp+sm:///projects/p"))
+	require.NoError(t, err)
+
+	// a client created before the TLS config was set must not be reused
+	built := *fsys.(*gcpsmFS)
+	built.smclient = &mockClient{}
+	built.builtClient = true
+
+	assert.Nil(t, built.WithTLSConfig(cfg).(*gcpsmFS).smclient)
+
+	// but a client set with WithSMClientFS is kept
+	mc := &mockClient{}
+	fsys = WithSMClientFS(mc, fsys)
+
+	assert.Same(t, mc, fsimpl.WithTLSConfigFS(cfg, fsys).(*gcpsmFS).smclient)
+}
@@ -0,0 +1,234 @@
+package httpfs
+
+import (
+	"encoding/json"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hairyhenderson/go-fsimpl"
+	"github.com/hairyhenderson/go-fsimpl/internal/tests"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2/clientcredentials"
+)
+
+// setupAuthHTTP returns a server which responds with the request's
+// Authorization header, or with a 401 if it isn't one of the accepted values
+func setupAuthHTTP(t *testing.T, accepted ...string) *httptest.Server {
+	t.Helper()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authz := r.Header.Get("Authorization")
+		for _, a := range accepted {
+			if authz == a {
+				_, _ = w.Write([]byte(authz))
+
+				return
+			}
+		}
+
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv
+}
+
+func TestWithAuthMethodFS(t *testing.T) {
+	srv := setupAuthHTTP(t, "Bearer foo")
+
+	fsys, err := New(tests.MustURL(srv.URL + "/"))
+	require.NoError(t, err)
+
+	fsys = fsimpl.WithContextFS(t.Context(), fsys)
+
+	_, err = fs.ReadFile(fsys, "hello.txt")
+	require.Error(t, err)
+
+	authfsys := WithAuthMethodFS(NewBearerTokenAuth("foo"), fsys)
+
+	b, err := fs.ReadFile(authfsys, "hello.txt")
+	require.NoError(t, err)
+	assert.Equal(t, "Bearer foo", string(b))
+
+	// the auth method takes precedence over a header
+	hdrfsys := fsimpl.WithHeaderFS(http.Header{"Authorization": {"Bearer bar"}}, authfsys)
+
+	b, err = fs.ReadFile(hdrfsys, "hello.txt")
+	require.NoError(t, err)
+	assert.Equal(t, "Bearer foo", string(b))
+
+	// and the original filesystem is unchanged
+	_, err = fs.ReadFile(fsys, "hello.txt")
+	require.Error(t, err)
+}
+
+func TestBasicAuth(t *testing.T) {
+	req := &http.Request{Header: http.Header{}}
+	require.NoError(t, NewBasicAuth("user", "pass").Authenticate(req))
+
+	expected := req.Header.Get("Authorization")
+
+	srv := setupAuthHTTP(t, expected)
+
+	fsys, err := New(tests.MustURL("http://user:pass@" + srv.Listener.Addr().String() + "/"))
+	require.NoError(t, err)
+
+	fsys = fsimpl.WithContextFS(t.Context(), fsys)
+
+	b, err := fs.ReadFile(fsys, "hello.txt")
+	require.NoError(t, err)
+	assert.Equal(t, expected, string(b))
+
+	fi, err := fs.Stat(fsys, "hello.txt")
+	require.NoError(t, err)
+	assert.Equal(t, int64(len(expected)), fi.Size())
+}
+
+func TestTokenFileAuth(t *testing.T) {
+	var valid atomic.Value
+
+	valid.Store("one")
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer "+valid.Load().(string) {
+			w.WriteHeader(http.StatusUnauthorized)
+
+			return
+		}
+
+		_, _ = w.Write([]byte(r.Header.Get("Authorization")))
+	}))
+	t.Cleanup(srv.Close)
+
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	modTime := time.Now().Truncate(time.Second)
+
+	writeToken := func(token string, modTime time.Time) {
+		t.Helper()
+
+		require.NoError(t, os.WriteFile(tokenFile, []byte(token), 0o600))
+		require.NoError(t, os.Chtimes(tokenFile, modTime, modTime))
+	}
+
+	writeToken("one\n", modTime)
+
+	auth := NewTokenFileAuth(tokenFile)
+
+	fsys, err := New(tests.MustURL(srv.URL + "/"))
+	require.NoError(t, err)
+
+	fsys = WithAuthMethodFS(auth, fsimpl.WithContextFS(t.Context(), fsys))
+
+	b, err := fs.ReadFile(fsys, "hello.txt")
+	require.NoError(t, err)
+	assert.Equal(t, "Bearer one", string(b))
+
+	// the token is cached while the file is unmodified
+	writeToken("two", modTime)
+
+	b, err = fs.ReadFile(fsys, "hello.txt")
+	require.NoError(t, err)
+	assert.Equal(t, "Bearer one", string(b))
+
+	// a rotated file is re-read
+	modTime = modTime.Add(time.Minute)
+	require.NoError(t, os.Chtimes(tokenFile, modTime, modTime))
+	valid.Store("two")
+
+	b, err = fs.ReadFile(fsys, "hello.txt")
+	require.NoError(t, err)
+	assert.Equal(t, "Bearer two", string(b))
+
+	// a rejected token is re-read even if the file's mod time is unchanged
+	writeToken("three", modTime)
+	valid.Store("three")
+
+	b, err = fs.ReadFile(fsys, "hello.txt")
+	require.NoError(t, err)
+	assert.Equal(t, "Bearer three", string(b))
+
+	require.NoError(t, os.Remove(tokenFile))
+
+	_, err = fs.ReadFile(fsys, "hello.txt")
+	require.ErrorIs(t, err, fs.ErrNotExist)
+
+	writeToken("  \n", modTime.Add(time.Minute))
+
+	_, err = fs.ReadFile(fsys, "hello.txt")
+	require.Error(t, err)
+}
+
+func TestNewTokenFileAuth_RelativePath(t *testing.T) {
+	t.Chdir(t.TempDir())
+
+	require.NoError(t, os.WriteFile("token", []byte("secret\n"), 0o600))
+
+	auth := NewTokenFileAuth("token").(*tokenFileAuth)
+
+	token, err := auth.getToken()
+	require.NoError(t, err)
+	assert.Equal(t, "secret", token)
+}
+
+func TestClientCredentialsAuth(t *testing.T) {
+	var issued atomic.Int32
+
+	tokenSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, secret, ok := r.BasicAuth()
+		if !ok || id != "client" || secret != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+
+			return
+		}
+
+		n := issued.Add(1)
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"access_token": "token" + strconv.Itoa(int(n)),
+			"token_type":   "Bearer",
+			"expires_in":   3600,
+		})
+	}))
+	t.Cleanup(tokenSrv.Close)
+
+	// the first token is considered revoked by the server
+	srv := setupAuthHTTP(t, "Bearer token2")
+
+	fsys, err := New(tests.MustURL(srv.URL + "/"))
+	require.NoError(t, err)
+
+	fsys = WithAuthMethodFS(NewClientCredentialsAuth(&clientcredentials.Config{
+		ClientID:     "client",
+		ClientSecret: "secret",
+		TokenURL:     tokenSrv.URL + "/token",
+	}), fsimpl.WithContextFS(t.Context(), fsys))
+
+	b, err := fs.ReadFile(fsys, "hello.txt")
+	require.NoError(t, err)
+	assert.Equal(t, "Bearer token2", string(b))
+	assert.Equal(t, int32(2), issued.Load())
+
+	// the token is cached
+	b, err = fs.ReadFile(fsys, "hello.txt")
+	require.NoError(t, err)
+	assert.Equal(t, "Bearer token2", string(b))
+	assert.Equal(t, int32(2), issued.Load())
+
+	fsys = WithAuthMethodFS(NewClientCredentialsAuth(&clientcredentials.Config{
+		ClientID:     "client",
+		ClientSecret: "wrong",
+		TokenURL:     tokenSrv.URL + "/token",
+	}), fsys)
+
+	_, err = fs.ReadFile(fsys, "hello.txt")
+	require.Error(t, err)
+}
@@ -0,0 +1,128 @@
+package httpfs
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+)
+
+// acceptEncoding is sent with GET requests, listing the content codings that
+// can be decoded
+const acceptEncoding = "gzip, br, zstd"
+
+// ResponseInfo describes how the server presented a file. It is returned by the
+// Sys method of the fs.FileInfo for files in this filesystem, when any of its
+// fields are set.
+type ResponseInfo struct {
+	// Filename is the filename suggested by the server in the
+	// Content-Disposition header, if any.
+	Filename string
+	// Charset is the value of the charset parameter in the Content-Type
+	// header, if any.
+	Charset string
+	// ContentEncoding is the Content-Encoding the response was sent with. The
+	// file's content is always decoded.
+	ContentEncoding string
+}
+
+// responseInfo returns a *ResponseInfo for the given response header, or nil
+// if there is nothing to report
+func responseInfo(hdr http.Header) *ResponseInfo {
+	ri := ResponseInfo{ContentEncoding: hdr.Get("Content-Encoding")}
+
+	// best-effort - malformed headers are ignored
+	if ct := hdr.Get("Content-Type"); ct != "" {
+		if _, params, err := mime.ParseMediaType(ct); err == nil {
+			ri.Charset = params["charset"]
+		}
+	}
+
+	if cd := hdr.Get("Content-Disposition"); cd != "" {
+		if _, params, err := mime.ParseMediaType(cd); err == nil {
+			ri.Filename = params["filename"]
+		}
+	}
+
+	if ri == (ResponseInfo{}) {
+		return nil
+	}
+
+	return &ri
+}
+
+// contentCodings returns the content codings listed in the Content-Encoding
+// header, in the order they were applied. The "identity" coding is omitted.
+func contentCodings(hdr http.Header) []string {
+	codings := []string{}
+
+	for _, v := range hdr.Values("Content-Encoding") {
+		for c := range strings.SplitSeq(v, ",") {
+			c = strings.ToLower(strings.TrimSpace(c))
+			if c != "" && c != "identity" {
+				codings = append(codings, c)
+			}
+		}
+	}
+
+	return codings
+}
+
+// decodeBody wraps body with decoders for each of the given content codings,
+// in reverse order of application
+func decodeBody(body io.ReadCloser, codings []string) (io.ReadCloser, error) {
+	for i := len(codings) - 1; i >= 0; i-- {
+		var (
+			r   io.Reader
+			err error
+		)
+
+		closeFn := func() {}
+
+		switch codings[i] {
+		case "gzip", "x-gzip":
+			r, err = gzip.NewReader(body)
+		case "br":
+			r = brotli.NewReader(body)
+		case "zstd":
+			var zr *zstd.Decoder
+
+			zr, err = zstd.NewReader(body)
+			if err == nil {
+				r = zr
+				closeFn = zr.Close
+			}
+		default:
+			err = fmt.Errorf("unsupported content encoding %q", codings[i])
+		}
+
+		if err != nil {
+			body.Close()
+
+			return nil, err
+		}
+
+		body = &decodedBody{Reader: r, body: body, closeFn: closeFn}
+	}
+
+	return body, nil
+}
+
+// decodedBody reads from a decoder, closing both the decoder and the
+// underlying body when closed
+type decodedBody struct {
+	io.Reader
+	body    io.Closer
+	closeFn func()
+}
+
+func (b *decodedBody) Close() error {
+	b.closeFn()
+
+	return b.body.Close()
+}
@@ -2,12 +2,14 @@ package httpfs
 
 import (
 	"context"
+	"crypto/tls"
 	"errors"
 	"fmt"
 	"io"
 	"io/fs"
 	"net/http"
 	"net/url"
+	"path"
 	"slices"
 	"time"
 
@@ -20,6 +22,15 @@ type httpFS struct {
 	base    *url.URL
 	client  *http.Client
 	headers http.Header
+	tls     *tls.Config
+	auth    AuthMethod
+	// clientErr records a failure to configure the HTTP client, returned when
+	// the filesystem is used
+	clientErr error
+	manifest  *manifestLoader
+	socket    string
+	// mroot is the filesystem's root within the manifest
+	mroot string
 }
 
 // New provides a filesystem (an fs.FS) for the HTTP (or HTTPS) endpoint
@@ -27,29 +38,83 @@ type httpFS struct {
 // 'https' URL schemes. All reads are made with the GET method, while stat calls
 // are made with the HEAD method (with a fallback to GET).
 //
+// The 'http+unix' scheme can be used to read from an HTTP server listening on
+// a Unix domain socket. The socket path is given with the "socket" query
+// parameter, or in the URL's authority.
+//
+// The TLS configuration can be set with the "ca_file", "cert_file", "key_file",
+// and "insecure_skip_verify" query parameters, which are not sent to the
+// server, or with WithTLSConfigFS.
+//
+// When the "manifest" query parameter is set, the named file (relative to the
+// base URL) is read as a manifest listing the files available, which is used to
+// support ReadDir, Stat, and Glob. See the package documentation for details.
+//
+// Basic authentication credentials may be given in the URL's userinfo. Other
+// authentication methods can be set with WithAuthMethodFS.
+//
 // A context can be given by using WithContextFS.
 // HTTP Headers can be provided by using WithHeaderFS.
 func New(u *url.URL) (fs.FS, error) {
-	return &httpFS{
+	tlsConfig, err := internal.TLSConfigFromURL(u)
+	if err != nil {
+		return nil, fmt.Errorf("invalid TLS configuration: %w", err)
+	}
+
+	fsys := &httpFS{
 		ctx:     context.Background(),
-		client:  http.DefaultClient,
 		base:    u,
 		headers: http.Header{},
-	}, nil
+		tls:     tlsConfig,
+	}
+
+	if u.User != nil {
+		password, _ := u.User.Password()
+		fsys.auth = NewBasicAuth(u.User.Username(), password)
+	}
+
+	if u.Scheme == schemeHTTPUnix {
+		fsys.socket, err = unixSocketPath(u)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	fsys.client, err = fsys.configureClient(http.DefaultClient)
+	if err != nil {
+		return nil, err
+	}
+
+	if name := u.Query().Get(manifestParam); name != "" {
+		mu, err := fsys.fileURL(name)
+		if err != nil {
+			return nil, fmt.Errorf("invalid manifest %q: %w", name, err)
+		}
+
+		fsys.manifest = &manifestLoader{name: name, u: mu}
+		fsys.mroot = "."
+	}
+
+	return fsys, nil
 }
 
 // FS is used to register this filesystem with an fsimpl.FSMux
 //
 //nolint:gochecknoglobals
-var FS = fsimpl.FSProviderFunc(New, "http", "https")
+var FS = fsimpl.FSProviderFunc(New, "http", "https", schemeHTTPUnix)
 
 var (
 	_ fs.FS                     = (*httpFS)(nil)
 	_ fs.ReadFileFS             = (*httpFS)(nil)
 	_ fs.SubFS                  = (*httpFS)(nil)
+	_ fs.ReadDirFS              = (*httpFS)(nil)
+	_ fs.StatFS                 = (*httpFS)(nil)
+	_ fs.GlobFS                 = (*httpFS)(nil)
 	_ internal.WithContexter    = (*httpFS)(nil)
 	_ internal.WithHeaderer     = (*httpFS)(nil)
 	_ internal.WithHTTPClienter = (*httpFS)(nil)
+	_ internal.WithTLSConfiger  = (*httpFS)(nil)
+	_ withAuthMethoder          = (*httpFS)(nil)
 )
 
 func (f httpFS) URL() string {
@@ -92,11 +157,53 @@ func (f *httpFS) WithHTTPClient(client *http.Client) fs.FS {
 	}
 
 	fsys := *f
-	fsys.client = client
+	fsys.client, fsys.clientErr = fsys.configureClient(client)
+
+	return &fsys
+}
+
+func (f *httpFS) WithTLSConfig(config *tls.Config) fs.FS {
+	if config == nil {
+		return f
+	}
+
+	fsys := *f
+	fsys.tls = config
+	fsys.client, fsys.clientErr = fsys.configureClient(fsys.client)
+
+	return &fsys
+}
+
+func (f *httpFS) WithAuthMethod(auth AuthMethod) fs.FS {
+	if auth == nil {
+		return f
+	}
+
+	fsys := *f
+	fsys.auth = auth
 
 	return &fsys
 }
 
+// configureClient returns a copy of client configured for the filesystem's
+// TLS configuration and Unix domain socket, where set.
+func (f *httpFS) configureClient(client *http.Client) (*http.Client, error) {
+	if f.tls != nil {
+		c, err := internal.HTTPClientWithTLSConfig(client, f.tls)
+		if err != nil {
+			return nil, fmt.Errorf("unable to apply TLS configuration to HTTP client: %w", err)
+		}
+
+		client = c
+	}
+
+	if f.socket != "" {
+		return unixSocketClient(f.socket, client)
+	}
+
+	return client, nil
+}
+
 func (f httpFS) Open(name string) (fs.File, error) {
 	if !fs.ValidPath(name) {
 		return nil, &fs.PathError{
@@ -106,18 +213,56 @@ func (f httpFS) Open(name string) (fs.File, error) {
 		}
 	}
 
-	u, err := internal.SubURL(f.base, name)
+	if f.clientErr != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: f.clientErr}
+	}
+
+	if f.manifest != nil {
+		return f.openManifest(name)
+	}
+
+	return f.openURL(name)
+}
+
+// openURL opens the named file directly, without consulting the manifest
+func (f *httpFS) openURL(name string) (*httpFile, error) {
+	u, err := f.fileURL(name)
 	if err != nil {
 		return nil, err
 	}
 
+	return f.newFile(name, u), nil
+}
+
+func (f *httpFS) newFile(name string, u *url.URL) *httpFile {
 	return &httpFile{
 		ctx:    f.ctx,
 		u:      u,
 		client: f.client,
 		name:   name,
 		hdr:    f.headers,
-	}, nil
+		auth:   f.auth,
+	}
+}
+
+// fileURL returns the URL to request the named file from
+func (f *httpFS) fileURL(name string) (*url.URL, error) {
+	u, err := internal.SubURL(f.base, name)
+	if err != nil {
+		return nil, err
+	}
+
+	u = internal.RemoveTLSParams(u)
+	u = removeManifestParam(u)
+
+	// credentials are sent by the auth method instead
+	u.User = nil
+
+	if f.socket != "" {
+		u = unixRequestURL(u)
+	}
+
+	return u, nil
 }
 
 func (f httpFS) ReadFile(name string) ([]byte, error) {
@@ -138,6 +283,17 @@ func (f httpFS) ReadFile(name string) ([]byte, error) {
 func (f httpFS) Sub(name string) (fs.FS, error) {
 	fsys := f
 
+	if f.manifest != nil {
+		if !fs.ValidPath(name) {
+			return nil, &fs.PathError{Op: "sub", Path: name, Err: fs.ErrInvalid}
+		}
+
+		fsys.mroot = path.Join(f.mroot, name)
+
+		// paths in the manifest are directories, so must be resolved as such
+		name += "/"
+	}
+
 	u, err := internal.SubURL(f.base, name)
 	if err != nil {
 		return nil, err
@@ -155,22 +311,30 @@ type httpFile struct {
 	u      *url.URL
 	client *http.Client
 	hdr    http.Header
+	auth   AuthMethod
+	// mfi is the file's info from the manifest, if any
+	mfi    fs.FileInfo
+	sha256 []byte
 	name   string
 }
 
 var _ fs.File = (*httpFile)(nil)
 
 func (f *httpFile) request(method string) (io.ReadCloser, error) {
-	req, err := http.NewRequestWithContext(f.ctx, method, f.u.String(), nil)
+	resp, err := f.do(method)
 	if err != nil {
 		return nil, err
 	}
 
-	req.Header = f.hdr
+	// the credentials may have expired or been rotated, so refresh and retry
+	if r, ok := f.auth.(authRefresher); ok && resp.StatusCode == http.StatusUnauthorized {
+		resp.Body.Close()
+		r.Refresh()
 
-	resp, err := f.client.Do(req)
-	if err != nil {
-		return nil, err
+		resp, err = f.do(method)
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	modTime := time.Time{}
@@ -179,7 +343,20 @@ func (f *httpFile) request(method string) (io.ReadCloser, error) {
 		modTime, _ = http.ParseTime(mod)
 	}
 
-	f.fi = internal.FileInfo(f.name, resp.ContentLength, 0o444, modTime, resp.Header.Get("Content-Type"))
+	// the Content-Length of an encoded response isn't the size of the file
+	size := resp.ContentLength
+	codings := contentCodings(resp.Header)
+
+	if len(codings) > 0 {
+		size = -1
+	}
+
+	var sys any
+	if ri := responseInfo(resp.Header); ri != nil {
+		sys = ri
+	}
+
+	f.fi = internal.FileInfoWithSys(f.name, size, 0o444, modTime, resp.Header.Get("Content-Type"), sys)
 
 	if resp.StatusCode == 0 || resp.StatusCode >= 400 {
 		resp.Body.Close()
@@ -187,8 +364,52 @@ func (f *httpFile) request(method string) (io.ReadCloser, error) {
 		return nil, httpError(method, resp.StatusCode)
 	}
 
+	if method == http.MethodHead || len(codings) == 0 {
+		// The response body must be closed later
+		return resp.Body, nil
+	}
+
+	body, err := decodeBody(resp.Body, codings)
+	if err != nil {
+		return nil, fmt.Errorf("http %s: %w", method, err)
+	}
+
 	// The response body must be closed later
-	return resp.Body, nil
+	return body, nil
+}
+
+func (f *httpFile) do(method string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(f.ctx, method, f.u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// the headers are shared between files, so must be copied before
+	// credentials are added
+	if f.hdr != nil {
+		req.Header = f.hdr.Clone()
+	} else {
+		req.Header = http.Header{}
+	}
+
+	// Setting Accept-Encoding disables the transport's transparent gzip
+	// decoding, so that other codings can be decoded too. HEAD requests ask for
+	// the unencoded representation, so that its size is reported.
+	if req.Header.Get("Accept-Encoding") == "" {
+		if method == http.MethodHead {
+			req.Header.Set("Accept-Encoding", "identity")
+		} else {
+			req.Header.Set("Accept-Encoding", acceptEncoding)
+		}
+	}
+
+	if f.auth != nil {
+		if err := f.auth.Authenticate(req); err != nil {
+			return nil, fmt.Errorf("authenticate: %w", err)
+		}
+	}
+
+	return f.client.Do(req)
 }
 
 func (f *httpFile) Close() error {
@@ -206,6 +427,10 @@ func (f *httpFile) Read(p []byte) (int, error) {
 			return 0, err
 		}
 
+		if f.sha256 != nil {
+			body = newVerifyingReader(f.name, body, f.sha256)
+		}
+
 		f.body = body
 	}
 
@@ -213,6 +438,12 @@ func (f *httpFile) Read(p []byte) (int, error) {
 }
 
 func (f *httpFile) Stat() (fs.FileInfo, error) {
+	if f.mfi != nil {
+		return f.mfi, nil
+	}
+
+	// the size is -1 when the server doesn't send a length (or the response
+	// is encoded), as it can only be known by reading the whole body
 	body, err := f.request(http.MethodHead)
 	if err == nil {
 		defer body.Close()
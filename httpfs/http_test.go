@@ -1,20 +1,32 @@
 package httpfs
 
 import (
+	"bytes"
+	"compress/gzip"
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"encoding/json"
+	"encoding/pem"
 	"fmt"
 	"io"
 	"io/fs"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
 	"strconv"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/andybalholm/brotli"
 	"github.com/hairyhenderson/go-fsimpl"
 	"github.com/hairyhenderson/go-fsimpl/internal/tests"
+	"github.com/klauspost/compress/zstd"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -171,6 +183,240 @@ func TestHttpFS_Stat_NoFallbackForOtherErrors(t *testing.T) {
 	assert.Equal(t, "HEAD", he.method)
 }
 
+func TestHttpFS_UnixSocket(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Unix domain sockets not reliably supported on Windows")
+	}
+
+	socket := filepath.Join(t.TempDir(), "http.sock")
+
+	l, err := net.Listen("unix", socket)
+	require.NoError(t, err)
+
+	srv := &httptest.Server{
+		Listener: l,
+		Config: &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+
+			_ = json.NewEncoder(w).Encode(map[string]any{
+				"path":  r.URL.Path,
+				"query": r.URL.Query(),
+			})
+		})},
+	}
+	srv.Start()
+	t.Cleanup(srv.Close)
+
+	ctx := t.Context()
+
+	_, err = New(tests.MustURL("http+unix:///foo/"))
+	require.Error(t, err)
+
+	fsys, err := New(tests.MustURL("http+unix:///v1/?socket=" + url.QueryEscape(socket) + "&foo=bar"))
+	require.NoError(t, err)
+
+	fsys = fsimpl.WithContextFS(ctx, fsys)
+
+	body, err := fs.ReadFile(fsys, "info?baz=qux")
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"path":"/v1/info","query":{"foo":["bar"],"baz":["qux"]}}`, string(body))
+
+	fi, err := fs.Stat(fsys, "info")
+	require.NoError(t, err)
+	assert.Equal(t, "application/json", fsimpl.ContentType(fi))
+
+	// the socket path can also be given in the authority
+	fsys, err = New(&url.URL{Scheme: "http+unix", Host: socket, Path: "/"})
+	require.NoError(t, err)
+
+	// a custom client's transport must be configured to dial the socket too
+	fsys = fsimpl.WithHTTPClientFS(&http.Client{Timeout: 5 * time.Second}, fsys)
+
+	body, err = fs.ReadFile(fsys, "sub/path")
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"path":"/sub/path","query":{}}`, string(body))
+
+	// other transports can't dial the socket, so aren't silently used
+	fsys = fsimpl.WithHTTPClientFS(&http.Client{Transport: http.NewFileTransport(http.Dir("."))}, fsys)
+
+	_, err = fs.ReadFile(fsys, "sub/path")
+	require.Error(t, err)
+}
+
+func TestHttpFS_TLSConfig(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(r.URL.Query())
+	}))
+	t.Cleanup(srv.Close)
+
+	ctx := t.Context()
+
+	// the server's certificate isn't trusted by default
+	fsys, err := New(tests.MustURL(srv.URL + "/"))
+	require.NoError(t, err)
+
+	_, err = fs.ReadFile(fsimpl.WithContextFS(ctx, fsys), "foo")
+	require.Error(t, err)
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	err = os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{
+		Type: "CERTIFICATE", Bytes: srv.Certificate().Raw,
+	}), 0o600)
+	require.NoError(t, err)
+
+	fsys, err = New(tests.MustURL(srv.URL + "/?ca_file=" + url.QueryEscape(caFile) + "&foo=bar"))
+	require.NoError(t, err)
+
+	// TLS params must not be sent to the server
+	body, err := fs.ReadFile(fsimpl.WithContextFS(ctx, fsys), "foo")
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"foo":["bar"]}`, string(body))
+
+	_, err = New(tests.MustURL(srv.URL + "/?ca_file=/bogus.pem"))
+	require.Error(t, err)
+
+	pool := x509.NewCertPool()
+	pool.AddCert(srv.Certificate())
+
+	fsys, err = New(tests.MustURL(srv.URL + "/"))
+	require.NoError(t, err)
+
+	fsys = fsimpl.WithTLSConfigFS(&tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}, fsys)
+
+	// the TLS config must also be applied to a client set later
+	fsys = fsimpl.WithHTTPClientFS(&http.Client{Timeout: 5 * time.Second}, fsys)
+
+	body, err = fs.ReadFile(fsimpl.WithContextFS(ctx, fsys), "foo?a=b")
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"a":["b"]}`, string(body))
+
+	// the TLS config can't be applied to other transports, so they aren't
+	// silently used without it
+	fsys = fsimpl.WithHTTPClientFS(&http.Client{Transport: http.NewFileTransport(http.Dir("."))}, fsys)
+
+	_, err = fs.ReadFile(fsimpl.WithContextFS(ctx, fsys), "foo")
+	require.Error(t, err)
+}
+
+func encodeBody(t *testing.T, coding string, data []byte) []byte {
+	t.Helper()
+
+	buf := &bytes.Buffer{}
+
+	switch coding {
+	case "gzip":
+		w := gzip.NewWriter(buf)
+		_, err := w.Write(data)
+		require.NoError(t, err)
+		require.NoError(t, w.Close())
+	case "br":
+		w := brotli.NewWriter(buf)
+		_, err := w.Write(data)
+		require.NoError(t, err)
+		require.NoError(t, w.Close())
+	case "zstd":
+		w, err := zstd.NewWriter(buf)
+		require.NoError(t, err)
+		_, err = w.Write(data)
+		require.NoError(t, err)
+		require.NoError(t, w.Close())
+	default:
+		// unknown codings are left as-is
+		return data
+	}
+
+	return buf.Bytes()
+}
+
+func TestHttpFS_ContentEncoding(t *testing.T) {
+	content := []byte(strings.Repeat("hello world\n", 100))
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// always encodes the body, regardless of Accept-Encoding
+		codings := strings.Split(r.URL.Query().Get("enc"), ",")
+		body := content
+
+		for _, c := range codings {
+			body = encodeBody(t, c, body)
+		}
+
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Header().Set("Content-Encoding", strings.Join(codings, ", "))
+		w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+
+		if r.Method == http.MethodHead {
+			return
+		}
+
+		_, _ = w.Write(body)
+	}))
+	t.Cleanup(srv.Close)
+
+	fsys, err := New(tests.MustURL(srv.URL + "/"))
+	require.NoError(t, err)
+
+	fsys = fsimpl.WithContextFS(t.Context(), fsys)
+
+	for _, enc := range []string{"gzip", "br", "zstd", "gzip,zstd"} {
+		t.Run(enc, func(t *testing.T) {
+			name := "file.txt?enc=" + enc
+
+			b, err := fs.ReadFile(fsys, name)
+			require.NoError(t, err)
+			assert.Equal(t, content, b)
+
+			// the decoded size isn't known without reading the whole body
+			fi, err := fs.Stat(fsys, name)
+			require.NoError(t, err)
+			assert.Equal(t, int64(-1), fi.Size())
+			assert.Equal(t, "text/plain; charset=utf-8", fsimpl.ContentType(fi))
+
+			ri, ok := fi.Sys().(*ResponseInfo)
+			require.True(t, ok)
+			assert.Equal(t, "utf-8", ri.Charset)
+			assert.Equal(t, strings.ReplaceAll(enc, ",", ", "), ri.ContentEncoding)
+		})
+	}
+
+	_, err = fs.ReadFile(fsys, "file.txt?enc=bogus")
+	require.Error(t, err)
+}
+
+func TestHttpFS_ContentEncoding_Negotiated(t *testing.T) {
+	content := []byte(`{"msg": "hi there"}`)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Content-Disposition", `attachment; filename="report.json"`)
+
+		// only encode when the client accepts it
+		if !strings.Contains(r.Header.Get("Accept-Encoding"), "zstd") {
+			_, _ = w.Write(content)
+
+			return
+		}
+
+		w.Header().Set("Content-Encoding", "zstd")
+		_, _ = w.Write(encodeBody(t, "zstd", content))
+	}))
+	t.Cleanup(srv.Close)
+
+	fsys, err := New(tests.MustURL(srv.URL + "/"))
+	require.NoError(t, err)
+
+	fsys = fsimpl.WithContextFS(t.Context(), fsys)
+
+	b, err := fs.ReadFile(fsys, "download")
+	require.NoError(t, err)
+	assert.Equal(t, content, b)
+
+	// the HEAD request asks for the unencoded representation
+	fi, err := fs.Stat(fsys, "download")
+	require.NoError(t, err)
+	assert.Equal(t, int64(len(content)), fi.Size())
+	assert.Equal(t, &ResponseInfo{Filename: "report.json"}, fi.Sys())
+}
+
 func setupExampleHTTPServer() *httptest.Server {
 	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
 		lmod, _ := time.Parse(time.RFC3339, "2021-04-01T12:00:00Z")
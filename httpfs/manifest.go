@@ -0,0 +1,393 @@
+package httpfs
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"io/fs"
+	"net/url"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hairyhenderson/go-fsimpl/internal"
+)
+
+// manifestParam is the query parameter naming the manifest file, relative to
+// the base URL
+const manifestParam = "manifest"
+
+// manifestJSON is the format of the manifest file
+type manifestJSON struct {
+	Files []manifestFileJSON `json:"files"`
+}
+
+type manifestFileJSON struct {
+	Size        *int64    `json:"size"`
+	ModTime     time.Time `json:"modTime"`
+	Path        string    `json:"path"`
+	ContentType string    `json:"contentType"`
+	SHA256      string    `json:"sha256"`
+}
+
+// manifest is a parsed manifest, indexed for lookups
+type manifest struct {
+	files map[string]manifestEntry
+	dirs  map[string][]fs.DirEntry
+}
+
+type manifestEntry struct {
+	fi     fs.FileInfo
+	sha256 []byte
+	// sized is false when the manifest doesn't list the file's size
+	sized bool
+}
+
+func parseManifest(r io.Reader) (*manifest, error) {
+	mj := manifestJSON{}
+
+	dec := json.NewDecoder(r)
+	if err := dec.Decode(&mj); err != nil {
+		return nil, fmt.Errorf("decode manifest: %w", err)
+	}
+
+	m := &manifest{
+		files: map[string]manifestEntry{},
+		dirs:  map[string][]fs.DirEntry{".": {}},
+	}
+
+	for _, f := range mj.Files {
+		p := strings.TrimPrefix(f.Path, "/")
+		if !fs.ValidPath(p) || p == "." {
+			return nil, fmt.Errorf("invalid path %q in manifest", f.Path)
+		}
+
+		if _, ok := m.files[p]; ok {
+			return nil, fmt.Errorf("duplicate path %q in manifest", f.Path)
+		}
+
+		if _, ok := m.dirs[p]; ok {
+			return nil, fmt.Errorf("path %q in manifest is also a directory", f.Path)
+		}
+
+		entry := manifestEntry{sized: f.Size != nil}
+
+		size := int64(-1)
+		if f.Size != nil {
+			size = *f.Size
+		}
+
+		if f.SHA256 != "" {
+			sum, err := hex.DecodeString(f.SHA256)
+			if err != nil || len(sum) != sha256.Size {
+				return nil, fmt.Errorf("invalid sha256 %q for %q in manifest", f.SHA256, f.Path)
+			}
+
+			entry.sha256 = sum
+		}
+
+		entry.fi = internal.FileInfo(path.Base(p), size, 0o444, f.ModTime, f.ContentType)
+		m.files[p] = entry
+
+		if err := m.addParents(p, entry.fi); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, entries := range m.dirs {
+		slices.SortFunc(entries, func(a, b fs.DirEntry) int {
+			return strings.Compare(a.Name(), b.Name())
+		})
+	}
+
+	return m, nil
+}
+
+// addParents adds the entry for p to its parent directory, creating parent
+// directories as needed
+func (m *manifest) addParents(p string, fi fs.FileInfo) error {
+	entry := internal.FileInfoDirEntry(fi)
+
+	for {
+		dir := path.Dir(p)
+
+		if _, ok := m.files[dir]; ok {
+			return fmt.Errorf("path %q in manifest is also a file", dir)
+		}
+
+		_, exists := m.dirs[dir]
+		m.dirs[dir] = append(m.dirs[dir], entry)
+
+		if exists || dir == "." {
+			return nil
+		}
+
+		p = dir
+		entry = internal.FileInfoDirEntry(internal.DirInfo(path.Base(dir), time.Time{}))
+	}
+}
+
+// manifestLoader fetches the manifest once, and is shared between copies of
+// the filesystem
+type manifestLoader struct {
+	m    *manifest
+	u    *url.URL
+	name string
+	mu   sync.Mutex
+}
+
+// load returns the manifest, fetching it with the given filesystem's client
+// and configuration the first time. Errors are not cached, so that transient
+// failures can be retried.
+func (l *manifestLoader) load(f *httpFS) (*manifest, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.m != nil {
+		return l.m, nil
+	}
+
+	file := f.newFile(l.name, l.u)
+	defer file.Close()
+
+	m, err := parseManifest(file)
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: l.name, Err: err}
+	}
+
+	l.m = m
+
+	return m, nil
+}
+
+// removeManifestParam returns a copy of u with the manifest parameter removed
+func removeManifestParam(u *url.URL) *url.URL {
+	out := *u
+
+	if out.RawQuery != "" {
+		q := out.Query()
+		if q.Has(manifestParam) {
+			q.Del(manifestParam)
+			out.RawQuery = q.Encode()
+		}
+	}
+
+	return &out
+}
+
+func (f *httpFS) loadManifest(op, name string) (*manifest, string, error) {
+	if f.manifest == nil {
+		return nil, "", &fs.PathError{Op: op, Path: name, Err: errors.ErrUnsupported}
+	}
+
+	if !fs.ValidPath(name) {
+		return nil, "", &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+
+	if f.clientErr != nil {
+		return nil, "", &fs.PathError{Op: op, Path: name, Err: f.clientErr}
+	}
+
+	m, err := f.manifest.load(f)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return m, path.Join(f.mroot, name), nil
+}
+
+// openManifest opens the named file or directory, as listed in the manifest
+func (f *httpFS) openManifest(name string) (fs.File, error) {
+	m, p, err := f.loadManifest("open", name)
+	if err != nil {
+		return nil, err
+	}
+
+	if entries, ok := m.dirs[p]; ok {
+		return &manifestDir{
+			fi:      internal.DirInfo(path.Base(name), time.Time{}),
+			entries: entries,
+		}, nil
+	}
+
+	entry, ok := m.files[p]
+	if !ok {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+
+	file, err := f.openURL(name)
+	if err != nil {
+		return nil, err
+	}
+
+	if entry.sized {
+		file.mfi = entry.fi
+	}
+
+	file.sha256 = entry.sha256
+
+	return file, nil
+}
+
+func (f httpFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	m, p, err := f.loadManifest("readdir", name)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, ok := m.dirs[p]
+	if !ok {
+		if _, ok := m.files[p]; ok {
+			return nil, &fs.PathError{Op: "readdir", Path: name, Err: fmt.Errorf("not a directory")}
+		}
+
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrNotExist}
+	}
+
+	return slices.Clone(entries), nil
+}
+
+func (f httpFS) Stat(name string) (fs.FileInfo, error) {
+	if f.manifest != nil {
+		m, p, err := f.loadManifest("stat", name)
+		if err != nil {
+			return nil, err
+		}
+
+		if _, ok := m.dirs[p]; ok {
+			return internal.DirInfo(path.Base(name), time.Time{}), nil
+		}
+
+		if entry, ok := m.files[p]; ok && entry.sized {
+			return entry.fi, nil
+		}
+	}
+
+	file, err := f.Open(name)
+	if err != nil {
+		return nil, err
+	}
+
+	defer file.Close()
+
+	return file.Stat()
+}
+
+func (f httpFS) Glob(pattern string) ([]string, error) {
+	m, _, err := f.loadManifest("glob", ".")
+	if err != nil {
+		return nil, err
+	}
+
+	// check the pattern is well-formed
+	if _, err := path.Match(pattern, ""); err != nil {
+		return nil, err
+	}
+
+	matches := []string{}
+
+	for dir, entries := range m.dirs {
+		rel, ok := relPath(f.mroot, dir)
+		if !ok {
+			continue
+		}
+
+		for _, entry := range entries {
+			name := path.Join(rel, entry.Name())
+			if ok, _ := path.Match(pattern, name); ok {
+				matches = append(matches, name)
+			}
+		}
+	}
+
+	slices.Sort(matches)
+
+	return matches, nil
+}
+
+// relPath returns p relative to root, if it's within root
+func relPath(root, p string) (string, bool) {
+	switch {
+	case root == ".":
+		return p, true
+	case p == root:
+		return ".", true
+	case strings.HasPrefix(p, root+"/"):
+		return strings.TrimPrefix(p, root+"/"), true
+	default:
+		return "", false
+	}
+}
+
+// manifestDir is a directory listed in the manifest
+type manifestDir struct {
+	fi      fs.FileInfo
+	entries []fs.DirEntry
+	offset  int
+}
+
+var _ fs.ReadDirFile = (*manifestDir)(nil)
+
+func (d *manifestDir) Stat() (fs.FileInfo, error) { return d.fi, nil }
+func (d *manifestDir) Close() error               { return nil }
+
+func (d *manifestDir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.fi.Name(), Err: errors.New("is a directory")}
+}
+
+func (d *manifestDir) ReadDir(n int) ([]fs.DirEntry, error) {
+	remaining := d.entries[d.offset:]
+
+	if n <= 0 {
+		d.offset = len(d.entries)
+
+		return slices.Clone(remaining), nil
+	}
+
+	if len(remaining) == 0 {
+		return nil, io.EOF
+	}
+
+	n = min(n, len(remaining))
+	d.offset += n
+
+	return slices.Clone(remaining[:n]), nil
+}
+
+// verifyingReader checks that the content read from r matches the expected
+// SHA-256 hash once it has been read completely
+type verifyingReader struct {
+	r        io.ReadCloser
+	h        hash.Hash
+	name     string
+	expected []byte
+}
+
+func newVerifyingReader(name string, r io.ReadCloser, expected []byte) *verifyingReader {
+	return &verifyingReader{r: r, h: sha256.New(), name: name, expected: expected}
+}
+
+func (v *verifyingReader) Read(p []byte) (int, error) {
+	n, err := v.r.Read(p)
+	v.h.Write(p[:n])
+
+	if errors.Is(err, io.EOF) && !bytes.Equal(v.h.Sum(nil), v.expected) {
+		return n, &fs.PathError{
+			Op: "read", Path: v.name,
+			Err: fmt.Errorf("sha256 mismatch: expected %x, got %x", v.expected, v.h.Sum(nil)),
+		}
+	}
+
+	return n, err
+}
+
+func (v *verifyingReader) Close() error {
+	return v.r.Close()
+}
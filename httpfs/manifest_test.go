@@ -0,0 +1,224 @@
+package httpfs
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/hairyhenderson/go-fsimpl"
+	"github.com/hairyhenderson/go-fsimpl/internal/tests"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupManifestHTTP(t *testing.T, files map[string]string, hashes map[string]string) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+
+	modTime, _ := time.Parse(time.RFC3339, "2021-04-01T12:00:00Z")
+
+	mj := manifestJSON{}
+
+	for name, content := range files {
+		size := int64(len(content))
+
+		sum := sha256.Sum256([]byte(content))
+		hash := hex.EncodeToString(sum[:])
+
+		if h, ok := hashes[name]; ok {
+			hash = h
+		}
+
+		mj.Files = append(mj.Files, manifestFileJSON{
+			Path:    name,
+			Size:    &size,
+			ModTime: modTime,
+			SHA256:  hash,
+		})
+	}
+
+	manifestBody, err := json.Marshal(mj)
+	require.NoError(t, err)
+
+	requests := &atomic.Int32{}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+
+		if r.URL.Query().Has(manifestParam) {
+			w.WriteHeader(http.StatusBadRequest)
+
+			return
+		}
+
+		if r.URL.Path == "/site/index.json" {
+			_, _ = w.Write(manifestBody)
+
+			return
+		}
+
+		content, ok := files[r.URL.Path[len("/site/"):]]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+
+			return
+		}
+
+		_, _ = w.Write([]byte(content))
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv, requests
+}
+
+func TestHttpFS_Manifest(t *testing.T) {
+	files := map[string]string{
+		"index.html":        "<html></html>",
+		"docs/intro.md":     "# Intro",
+		"docs/api/v1.md":    "# API v1",
+		"docs/api/v2.md":    "# API v2",
+		"assets/logo.svg":   "<svg></svg>",
+		"assets/styles.css": "body {}",
+	}
+
+	srv, requests := setupManifestHTTP(t, files, nil)
+
+	fsys, err := New(tests.MustURL(srv.URL + "/site/?manifest=index.json"))
+	require.NoError(t, err)
+
+	fsys = fsimpl.WithContextFS(t.Context(), fsys)
+
+	require.NoError(t, fstest.TestFS(fsys,
+		"index.html", "docs/intro.md", "docs/api/v1.md", "docs/api/v2.md",
+		"assets/logo.svg", "assets/styles.css"))
+
+	// the manifest is only fetched once, and Stat doesn't need HEAD requests
+	requests.Store(0)
+
+	fi, err := fs.Stat(fsys, "docs/api/v1.md")
+	require.NoError(t, err)
+	assert.Equal(t, "v1.md", fi.Name())
+	assert.Equal(t, int64(len("# API v1")), fi.Size())
+
+	entries, err := fs.ReadDir(fsys, "docs")
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Equal(t, "api", entries[0].Name())
+	assert.True(t, entries[0].IsDir())
+	assert.Equal(t, "intro.md", entries[1].Name())
+
+	matches, err := fs.Glob(fsys, "docs/*/*.md")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"docs/api/v1.md", "docs/api/v2.md"}, matches)
+
+	assert.Equal(t, int32(0), requests.Load())
+
+	_, err = fs.Stat(fsys, "docs/bogus.md")
+	require.ErrorIs(t, err, fs.ErrNotExist)
+
+	_, err = fsys.Open("bogus.txt")
+	require.ErrorIs(t, err, fs.ErrNotExist)
+
+	// a sub-filesystem resolves paths relative to the manifest root
+	sub, err := fs.Sub(fsys, "docs/api")
+	require.NoError(t, err)
+
+	b, err := fs.ReadFile(sub, "v2.md")
+	require.NoError(t, err)
+	assert.Equal(t, "# API v2", string(b))
+
+	matches, err = fs.Glob(sub, "*.md")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"v1.md", "v2.md"}, matches)
+}
+
+func TestHttpFS_Manifest_HashMismatch(t *testing.T) {
+	files := map[string]string{
+		"good.txt": "hello",
+		"bad.txt":  "tampered",
+	}
+
+	sum := sha256.Sum256([]byte("original"))
+
+	srv, _ := setupManifestHTTP(t, files, map[string]string{
+		"bad.txt": hex.EncodeToString(sum[:]),
+	})
+
+	fsys, err := New(tests.MustURL(srv.URL + "/site/?manifest=index.json"))
+	require.NoError(t, err)
+
+	fsys = fsimpl.WithContextFS(t.Context(), fsys)
+
+	b, err := fs.ReadFile(fsys, "good.txt")
+	require.NoError(t, err)
+	assert.Equal(t, "hello", string(b))
+
+	_, err = fs.ReadFile(fsys, "bad.txt")
+	require.ErrorContains(t, err, "sha256 mismatch")
+
+	f, err := fsys.Open("bad.txt")
+	require.NoError(t, err)
+
+	defer f.Close()
+
+	_, err = io.ReadAll(f)
+	require.Error(t, err)
+}
+
+func TestHttpFS_NoManifest(t *testing.T) {
+	srv := setupHTTP(t)
+
+	fsys, err := New(tests.MustURL(srv.URL + "/"))
+	require.NoError(t, err)
+
+	_, err = fs.ReadDir(fsys, ".")
+	require.ErrorIs(t, err, errors.ErrUnsupported)
+
+	_, err = fs.Glob(fsys, "*.txt")
+	require.ErrorIs(t, err, errors.ErrUnsupported)
+
+	// Stat still works without a manifest
+	fi, err := fs.Stat(fsimpl.WithContextFS(t.Context(), fsys), "hello.txt")
+	require.NoError(t, err)
+	assert.Equal(t, int64(11), fi.Size())
+}
+
+func TestParseManifest(t *testing.T) {
+	testdata := []struct {
+		in  string
+		err bool
+	}{
+		{`{"files": []}`, false},
+		{`{"files": [{"path": "/a/b.txt", "size": 1}]}`, false},
+		{`not json`, true},
+		{`{"files": [{"path": "../a.txt"}]}`, true},
+		{`{"files": [{"path": "a.txt"}, {"path": "a.txt"}]}`, true},
+		{`{"files": [{"path": "a"}, {"path": "a/b.txt"}]}`, true},
+		{`{"files": [{"path": "a/b.txt"}, {"path": "a"}]}`, true},
+		{`{"files": [{"path": "a.txt", "sha256": "abc"}]}`, true},
+	}
+
+	for _, d := range testdata {
+		_, err := parseManifest(strings.NewReader(d.in))
+		if d.err {
+			assert.Error(t, err, d.in)
+		} else {
+			assert.NoError(t, err, d.in)
+		}
+	}
+
+	m, err := parseManifest(strings.NewReader(`{"files": [{"path": "/a/b.txt", "size": 1}]}`))
+	require.NoError(t, err)
+	assert.Contains(t, m.files, "a/b.txt")
+	assert.Len(t, m.dirs["."], 1)
+	assert.Len(t, m.dirs["a"], 1)
+}
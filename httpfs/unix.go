@@ -0,0 +1,78 @@
+package httpfs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+)
+
+// schemeHTTPUnix is the URL scheme for HTTP served over a Unix domain socket
+const schemeHTTPUnix = "http+unix"
+
+// unixSocketParam is the query parameter that may be used to specify the path
+// to the Unix domain socket. It is stripped from requests sent to the server.
+const unixSocketParam = "socket"
+
+// unixSocketPath returns the path to the Unix domain socket referenced by u,
+// either from the "socket" query parameter or from the (unescaped) authority.
+func unixSocketPath(u *url.URL) (string, error) {
+	if socket := u.Query().Get(unixSocketParam); socket != "" {
+		return socket, nil
+	}
+
+	if u.Host != "" {
+		return url.PathUnescape(u.Host)
+	}
+
+	return "", errors.New("http+unix URL must specify a socket path, either in the authority or with the \"socket\" query parameter")
+}
+
+// unixSocketClient returns a copy of client with a transport that dials the
+// Unix domain socket at socket for every request. An error is returned if the
+// client's transport isn't an *http.Transport, since it can't be reconfigured.
+func unixSocketClient(socket string, client *http.Client) (*http.Client, error) {
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	var tr *http.Transport
+
+	switch t := client.Transport.(type) {
+	case nil:
+		tr = http.DefaultTransport.(*http.Transport).Clone()
+	case *http.Transport:
+		tr = t.Clone()
+	default:
+		return nil, fmt.Errorf("can not dial Unix domain socket with HTTP transport of type %T", t)
+	}
+
+	dialer := &net.Dialer{}
+	tr.DialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+		return dialer.DialContext(ctx, "unix", socket)
+	}
+
+	c := *client
+	c.Transport = tr
+
+	return &c, nil
+}
+
+// unixRequestURL converts a http+unix URL into the plain HTTP URL that is
+// sent to the server - the host is irrelevant as the socket is dialed
+// directly, and the socket parameter is removed from the query.
+func unixRequestURL(u *url.URL) *url.URL {
+	ru := *u
+	ru.Scheme = "http"
+	ru.Host = "localhost"
+
+	if ru.RawQuery != "" {
+		q := ru.Query()
+		q.Del(unixSocketParam)
+		ru.RawQuery = q.Encode()
+	}
+
+	return &ru
+}
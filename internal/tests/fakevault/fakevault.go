@@ -5,6 +5,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"sync"
 	"testing"
 
 	"github.com/hashicorp/vault/api"
@@ -126,3 +127,60 @@ func FakeVault(t *testing.T, handler http.Handler) *api.Client {
 
 	return c
 }
+
+// WrappingHandler returns a handler for a fake Vault server's response
+// wrapping endpoints. The given responses are keyed by wrapping token, and
+// each was created by creationPath. As with Vault, each wrapping token can
+// only be unwrapped once.
+func WrappingHandler(t *testing.T, creationPath string, responses map[string]map[string]any) http.Handler {
+	t.Helper()
+
+	mu := sync.Mutex{}
+	invalid := func(w http.ResponseWriter) {
+		w.WriteHeader(http.StatusBadRequest)
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"errors": []string{"wrapping token is not valid or does not exist"},
+		})
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/sys/wrapping/lookup", func(w http.ResponseWriter, r *http.Request) {
+		body := map[string]string{}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+
+		mu.Lock()
+		_, ok := responses[body["token"]]
+		mu.Unlock()
+
+		if !ok {
+			invalid(w)
+
+			return
+		}
+
+		_ = json.NewEncoder(w).Encode(map[string]any{"data": map[string]any{
+			"creation_path": creationPath,
+			"creation_ttl":  300,
+		}})
+	})
+	mux.HandleFunc("/v1/sys/wrapping/unwrap", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPut, r.Method)
+
+		token := r.Header.Get("X-Vault-Token")
+
+		mu.Lock()
+		resp, ok := responses[token]
+		delete(responses, token)
+		mu.Unlock()
+
+		if !ok {
+			invalid(w)
+
+			return
+		}
+
+		_ = json.NewEncoder(w).Encode(resp)
+	})
+
+	return mux
+}
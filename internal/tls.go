@@ -0,0 +1,166 @@
+package internal
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+)
+
+// WithTLSConfiger is an fs.FS that can be configured with a custom *tls.Config
+type WithTLSConfiger interface {
+	WithTLSConfig(config *tls.Config) fs.FS
+}
+
+// URL query parameters understood by TLSConfigFromURL
+const (
+	TLSParamCAFile             = "ca_file"
+	TLSParamCertFile           = "cert_file"
+	TLSParamKeyFile            = "key_file"
+	TLSParamInsecureSkipVerify = "insecure_skip_verify"
+)
+
+// TLSParams returns the URL query parameters understood by TLSConfigFromURL.
+func TLSParams() []string {
+	return []string{
+		TLSParamCAFile,
+		TLSParamCertFile,
+		TLSParamKeyFile,
+		TLSParamInsecureSkipVerify,
+	}
+}
+
+// TLSConfigFromURL builds a *tls.Config from the TLS-related query parameters
+// in u. If none are set, a nil config (and no error) is returned, so that
+// callers can retain their default TLS behaviour.
+//
+// The returned config always requires TLS 1.2 or later. There's no parameter
+// to change this - a config with a different MinVersion can be set with
+// fsimpl.WithTLSConfigFS instead.
+func TLSConfigFromURL(u *url.URL) (*tls.Config, error) {
+	if u == nil || u.RawQuery == "" {
+		return nil, nil
+	}
+
+	q := u.Query()
+
+	caFile := q.Get(TLSParamCAFile)
+	certFile := q.Get(TLSParamCertFile)
+	keyFile := q.Get(TLSParamKeyFile)
+	insecure := q.Get(TLSParamInsecureSkipVerify)
+
+	if caFile == "" && certFile == "" && keyFile == "" && insecure == "" {
+		return nil, nil
+	}
+
+	cfg := &tls.Config{MinVersion: tls.VersionTLS12}
+
+	if caFile != "" {
+		pem, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, fmt.Errorf("read %s: %w", TLSParamCAFile, err)
+		}
+
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no valid certificates found in %s %q", TLSParamCAFile, caFile)
+		}
+
+		cfg.RootCAs = pool
+	}
+
+	if (certFile == "") != (keyFile == "") {
+		return nil, fmt.Errorf("%s and %s must be set together", TLSParamCertFile, TLSParamKeyFile)
+	}
+
+	if certFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("load client certificate: %w", err)
+		}
+
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+
+	if insecure != "" {
+		skip, err := strconv.ParseBool(insecure)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value for %s: %w", TLSParamInsecureSkipVerify, err)
+		}
+
+		//nolint:gosec
+		cfg.InsecureSkipVerify = skip
+	}
+
+	return cfg, nil
+}
+
+// RemoveTLSParams returns a copy of u with the TLS-related query parameters
+// removed, so that they aren't sent on to remote servers.
+func RemoveTLSParams(u *url.URL) *url.URL {
+	out := *u
+	if out.RawQuery == "" {
+		return &out
+	}
+
+	q := out.Query()
+	found := false
+
+	for _, p := range TLSParams() {
+		if q.Has(p) {
+			q.Del(p)
+
+			found = true
+		}
+	}
+
+	// avoid re-encoding (and so re-ordering) the query when not needed
+	if found {
+		out.RawQuery = q.Encode()
+	}
+
+	return &out
+}
+
+// HTTPClientWithTLSConfig returns a copy of client whose transport is
+// configured with config. If config is nil, client is returned unchanged. If
+// client is nil, http.DefaultClient is used as a base. An error is returned if
+// the client's transport isn't an *http.Transport, since it can't be safely
+// reconfigured.
+func HTTPClientWithTLSConfig(client *http.Client, config *tls.Config) (*http.Client, error) {
+	if config == nil {
+		return client, nil
+	}
+
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	var tr *http.Transport
+
+	switch t := client.Transport.(type) {
+	case nil:
+		dt, ok := http.DefaultTransport.(*http.Transport)
+		if !ok {
+			return nil, errors.New("http.DefaultTransport is not an *http.Transport")
+		}
+
+		tr = dt.Clone()
+	case *http.Transport:
+		tr = t.Clone()
+	default:
+		return nil, fmt.Errorf("can not set TLS configuration on HTTP transport of type %T", t)
+	}
+
+	tr.TLSClientConfig = config.Clone()
+
+	c := *client
+	c.Transport = tr
+
+	return &c, nil
+}
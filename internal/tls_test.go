@@ -0,0 +1,92 @@
+package internal
+
+import (
+	"crypto/tls"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hairyhenderson/go-fsimpl/internal/tests"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTLSConfigFromURL(t *testing.T) {
+	cfg, err := TLSConfigFromURL(tests.MustURL("https://example.com/?foo=bar"))
+	require.NoError(t, err)
+	assert.Nil(t, cfg)
+
+	cfg, err = TLSConfigFromURL(tests.MustURL("https://example.com/?insecure_skip_verify=true"))
+	require.NoError(t, err)
+	assert.True(t, cfg.InsecureSkipVerify)
+	assert.Nil(t, cfg.RootCAs)
+
+	_, err = TLSConfigFromURL(tests.MustURL("https://example.com/?insecure_skip_verify=maybe"))
+	require.Error(t, err)
+
+	_, err = TLSConfigFromURL(tests.MustURL("https://example.com/?cert_file=/foo.pem"))
+	require.Error(t, err)
+
+	_, err = TLSConfigFromURL(tests.MustURL("https://example.com/?ca_file=/does/not/exist.pem"))
+	require.Error(t, err)
+
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte("hello"))
+	}))
+	t.Cleanup(srv.Close)
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	err = os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{
+		Type: "CERTIFICATE", Bytes: srv.Certificate().Raw,
+	}), 0o600)
+	require.NoError(t, err)
+
+	cfg, err = TLSConfigFromURL(tests.MustURL(srv.URL + "/?ca_file=" + url.QueryEscape(caFile)))
+	require.NoError(t, err)
+	require.NotNil(t, cfg.RootCAs)
+
+	client, err := HTTPClientWithTLSConfig(nil, cfg)
+	require.NoError(t, err)
+	assert.NotSame(t, http.DefaultClient, client)
+
+	resp, err := client.Get(srv.URL)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+}
+
+func TestHTTPClientWithTLSConfig(t *testing.T) {
+	client, err := HTTPClientWithTLSConfig(nil, nil)
+	require.NoError(t, err)
+	assert.Nil(t, client)
+
+	base := &http.Client{Transport: &http.Transport{}}
+	client, err = HTTPClientWithTLSConfig(base, nil)
+	require.NoError(t, err)
+	assert.Same(t, base, client)
+
+	cfg := &tls.Config{MinVersion: tls.VersionTLS13}
+	client, err = HTTPClientWithTLSConfig(base, cfg)
+	require.NoError(t, err)
+	assert.NotSame(t, base, client)
+	assert.NotSame(t, base.Transport, client.Transport)
+	assert.Equal(t, uint16(tls.VersionTLS13), client.Transport.(*http.Transport).TLSClientConfig.MinVersion)
+
+	_, err = HTTPClientWithTLSConfig(&http.Client{Transport: http.NewFileTransport(http.Dir("."))}, cfg)
+	require.Error(t, err)
+}
+
+func TestRemoveTLSParams(t *testing.T) {
+	u := tests.MustURL("https://example.com/foo?z=1&a=2")
+	assert.Equal(t, "https://example.com/foo?z=1&a=2", RemoveTLSParams(u).String())
+
+	u = tests.MustURL("https://example.com/foo?z=1&ca_file=/ca.pem&insecure_skip_verify=true")
+	assert.Equal(t, "https://example.com/foo?z=1", RemoveTLSParams(u).String())
+
+	// the original URL is unchanged
+	assert.Equal(t, "z=1&ca_file=/ca.pem&insecure_skip_verify=true", u.RawQuery)
+}
@@ -13,12 +13,19 @@ import (
 // FileInfo creates a static fs.FileInfo with the given properties.
 // The result is also a fs.DirEntry and can be safely cast.
 func FileInfo(name string, size int64, mode fs.FileMode, modTime time.Time, contentType string) fs.FileInfo {
+	return FileInfoWithSys(name, size, mode, modTime, contentType, nil)
+}
+
+// FileInfoWithSys creates a static fs.FileInfo like FileInfo, whose Sys method
+// returns sys.
+func FileInfoWithSys(name string, size int64, mode fs.FileMode, modTime time.Time, contentType string, sys any) fs.FileInfo {
 	return &staticFileInfo{
 		name:        name,
 		size:        size,
 		mode:        mode,
 		modTime:     modTime,
 		contentType: contentType,
+		sys:         sys,
 	}
 }
 
@@ -30,6 +37,7 @@ func DirInfo(name string, modTime time.Time) fs.FileInfo {
 
 type staticFileInfo struct {
 	modTime     time.Time
+	sys         any
 	name        string
 	contentType string
 	size        int64
@@ -48,7 +56,7 @@ func (fi staticFileInfo) Mode() fs.FileMode           { return fi.mode }
 func (fi *staticFileInfo) ModTime() time.Time         { return fi.modTime }
 func (fi staticFileInfo) Name() string                { return fi.name }
 func (fi staticFileInfo) Size() int64                 { return fi.size }
-func (fi staticFileInfo) Sys() any                    { return nil }
+func (fi staticFileInfo) Sys() any                    { return fi.sys }
 func (fi *staticFileInfo) Info() (fs.FileInfo, error) { return fi, nil }
 func (fi staticFileInfo) Type() fs.FileMode           { return fi.Mode().Type() }
 
@@ -0,0 +1,93 @@
+// Package vaultwrap contains helpers for unwrapping Vault response-wrapped
+// data, shared by vaultfs and vaultauth
+package vaultwrap
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/hashicorp/vault/api"
+)
+
+// ErrCreationPath is returned when a wrapping token was not created by the
+// expected path, which may indicate that the token was intercepted and
+// replaced.
+var ErrCreationPath = errors.New("wrapping token creation path mismatch")
+
+// AnyCreationPath can be given to [Unwrap] as the expected creation path to
+// accept wrapping tokens created by any path, skipping the check.
+const AnyCreationPath = "*"
+
+// Unwrap unwraps the response wrapped by token, after checking that the token
+// was created at a path matching creationPath (a [path.Match] pattern). The
+// creation path must be given, so that the check can't be skipped by accident -
+// use [AnyCreationPath] to skip it explicitly.
+//
+// The given client isn't modified, and doesn't need a token - the wrapping
+// token is used to authenticate.
+func Unwrap(ctx context.Context, client *api.Client, token, creationPath string) (*api.Secret, error) {
+	token = strings.TrimSpace(token)
+	if token == "" {
+		return nil, errors.New("wrapping token must not be empty")
+	}
+
+	if creationPath == "" {
+		return nil, fmt.Errorf("an expected creation path must be given (or %q to accept any)", AnyCreationPath)
+	}
+
+	// headers are cloned so that the namespace is retained
+	c, err := client.CloneWithHeaders()
+	if err != nil {
+		return nil, fmt.Errorf("clone vault client: %w", err)
+	}
+
+	c.SetToken(token)
+
+	if creationPath != AnyCreationPath {
+		err = checkCreationPath(ctx, c, token, creationPath)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	secret, err := c.Logical().UnwrapWithContext(ctx, "")
+	if err != nil {
+		return nil, fmt.Errorf("unwrap: %w", err)
+	}
+
+	if secret == nil {
+		return nil, errors.New("unwrap: no wrapped response found")
+	}
+
+	return secret, nil
+}
+
+// checkCreationPath looks up the wrapping token (without unwrapping it) and
+// verifies its creation path
+func checkCreationPath(ctx context.Context, c *api.Client, token, expected string) error {
+	lookup, err := c.Logical().WriteWithContext(ctx, "sys/wrapping/lookup",
+		map[string]any{"token": token})
+	if err != nil {
+		return fmt.Errorf("lookup wrapping token: %w", err)
+	}
+
+	if lookup == nil || lookup.Data == nil {
+		return errors.New("lookup wrapping token: empty response")
+	}
+
+	actual, _ := lookup.Data["creation_path"].(string)
+
+	ok, err := path.Match(strings.Trim(expected, "/"), strings.Trim(actual, "/"))
+	if err != nil {
+		return fmt.Errorf("invalid expected creation path %q: %w", expected, err)
+	}
+
+	if !ok {
+		return fmt.Errorf("%w: expected %q, got %q", ErrCreationPath, expected, actual)
+	}
+
+	return nil
+}
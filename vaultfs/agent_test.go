@@ -0,0 +1,122 @@
+package vaultfs
+
+import (
+	"encoding/json"
+	"io"
+	"io/fs"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/hairyhenderson/go-fsimpl/internal/tests"
+	"github.com/hairyhenderson/go-fsimpl/vaultfs/vaultauth"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// agentVault is a fake Vault Agent listener, which records the tokens sent
+// with each request, and whether any token was revoked
+type agentVault struct {
+	tokens  []string
+	revoked bool
+	mu      sync.Mutex
+}
+
+func (v *agentVault) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	v.mu.Lock()
+	v.tokens = append(v.tokens, r.Header.Get("X-Vault-Token"))
+	v.mu.Unlock()
+
+	switch r.URL.Path {
+	case "/v1/sys/internal/ui/mounts":
+		_ = json.NewEncoder(w).Encode(map[string]any{"data": map[string]any{
+			"secret": map[string]any{"secret/": map[string]any{"type": "kv"}},
+		}})
+	case "/v1/secret/foo":
+		_ = json.NewEncoder(w).Encode(map[string]any{"data": map[string]any{"value": "foo"}})
+	case "/v1/auth/token/revoke-self":
+		v.mu.Lock()
+		v.revoked = true
+		v.mu.Unlock()
+
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func (v *agentVault) sentTokens() []string {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	out := v.tokens
+	v.tokens = nil
+
+	return out
+}
+
+func TestAgentAutoAuth_UnixSocket(t *testing.T) {
+	// socket paths are limited in length, so t.TempDir may be too long
+	dir, err := os.MkdirTemp("", "vaultfs")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = os.RemoveAll(dir) })
+
+	sock := filepath.Join(dir, "agent.sock")
+
+	l, err := net.Listen("unix", sock)
+	require.NoError(t, err)
+
+	agent := &agentVault{}
+	srv := httptest.NewUnstartedServer(agent)
+	srv.Listener = l
+	srv.Start()
+	t.Cleanup(srv.Close)
+
+	// a token in the environment must not be sent to the agent
+	t.Setenv("VAULT_TOKEN", "ignored")
+
+	fsys, err := New(tests.MustURL("vault:///secret/?agent_addr=unix://" + sock))
+	require.NoError(t, err)
+
+	fsys = vaultauth.WithAuthMethod(vaultauth.NewAgentAutoAuth(), fsys)
+
+	b, err := fs.ReadFile(fsys, "foo")
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"value":"foo"}`, string(b))
+
+	assert.Equal(t, []string{"", ""}, agent.sentTokens())
+	assert.False(t, agent.revoked)
+}
+
+func TestTokenFileAuth_Rotation(t *testing.T) {
+	agent := &agentVault{}
+	srv := httptest.NewServer(agent)
+	t.Cleanup(srv.Close)
+
+	fsys, err := New(tests.MustURL("vault:///secret/?agent_addr=" + srv.URL))
+	require.NoError(t, err)
+
+	sink := filepath.Join(t.TempDir(), "token")
+	require.NoError(t, os.WriteFile(sink, []byte("token1\n"), 0o600))
+
+	fsys = WithSessionFS(vaultauth.WithAuthMethod(vaultauth.NewTokenFileAuth(sink), fsys))
+
+	_, err = fs.ReadFile(fsys, "foo")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"token1", "token1"}, agent.sentTokens())
+
+	// the agent rotates the token
+	require.NoError(t, os.WriteFile(sink, []byte("token2\n"), 0o600))
+
+	_, err = fs.ReadFile(fsys, "foo")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"token2", "token2"}, agent.sentTokens())
+
+	// the agent's token is never revoked
+	require.NoError(t, fsys.(io.Closer).Close())
+	assert.False(t, agent.revoked)
+}
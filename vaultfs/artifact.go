@@ -0,0 +1,111 @@
+package vaultfs
+
+import (
+	"slices"
+	"strings"
+)
+
+// artifact describes the useful part of the response from a write-style
+// endpoint (such as a certificate issued by the PKI secret engine), which is
+// returned as the file's content instead of the whole response
+type artifact struct {
+	// extract returns the artifact from the response data, or nil if it's
+	// not present (for example when a non-PEM format was requested)
+	extract     func(data map[string]any) []byte
+	contentType string
+}
+
+//nolint:gochecknoglobals
+var (
+	pemBundleArtifact = &artifact{extract: pemBundle, contentType: "application/x-pem-file"}
+	sshSignedKey      = &artifact{extract: stringField("signed_key"), contentType: "text/plain"}
+	totpCode          = &artifact{extract: stringField("code"), contentType: "text/plain"}
+)
+
+// findArtifact returns the artifact for the secret engine endpoint described
+// by mi, or nil if the whole response should be returned
+func findArtifact(mi *mountInfo) *artifact {
+	if mi == nil || mi.MountOutput == nil {
+		return nil
+	}
+
+	parts := strings.Split(strings.Trim(mi.secretPath, "/"), "/")
+
+	switch mi.Type {
+	case "pki":
+		if isPKIIssuePath(parts) {
+			return pemBundleArtifact
+		}
+	case "ssh":
+		if len(parts) == 2 && parts[0] == "sign" {
+			return sshSignedKey
+		}
+	case "totp":
+		if len(parts) == 2 && parts[0] == "code" {
+			return totpCode
+		}
+	}
+
+	return nil
+}
+
+// isPKIIssuePath reports whether the path (relative to the PKI mount) issues
+// or signs a certificate, such as "issue/<role>", "sign/<role>", or
+// "issuer/<ref>/sign-intermediate"
+func isPKIIssuePath(parts []string) bool {
+	if len(parts) > 2 && parts[0] == "issuer" {
+		parts = parts[2:]
+	}
+
+	if len(parts) == 2 && parts[0] == "root" && parts[1] == "sign-intermediate" {
+		return true
+	}
+
+	return len(parts) > 0 && slices.Contains(
+		[]string{"issue", "sign", "sign-verbatim", "sign-intermediate"}, parts[0])
+}
+
+// pemBundle returns the certificate, followed by its CA chain and private key
+// (when present), as a PEM bundle
+func pemBundle(data map[string]any) []byte {
+	cert, _ := data["certificate"].(string)
+	cert = strings.TrimSpace(cert)
+
+	if !strings.HasPrefix(cert, "-----BEGIN ") {
+		return nil
+	}
+
+	blocks := []string{cert}
+
+	chain, _ := data["ca_chain"].([]any)
+	if len(chain) == 0 {
+		chain = []any{data["issuing_ca"]}
+	}
+
+	for _, c := range chain {
+		s, _ := c.(string)
+		s = strings.TrimSpace(s)
+
+		if s != "" && !slices.Contains(blocks, s) {
+			blocks = append(blocks, s)
+		}
+	}
+
+	if key, _ := data["private_key"].(string); strings.TrimSpace(key) != "" {
+		blocks = append(blocks, strings.TrimSpace(key))
+	}
+
+	return []byte(strings.Join(blocks, "\n") + "\n")
+}
+
+// stringField returns a function that extracts the named string field
+func stringField(name string) func(data map[string]any) []byte {
+	return func(data map[string]any) []byte {
+		s, ok := data[name].(string)
+		if !ok || s == "" {
+			return nil
+		}
+
+		return []byte(s)
+	}
+}
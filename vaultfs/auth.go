@@ -43,6 +43,22 @@ type authLogouter interface {
 	Logout(ctx context.Context, client *api.Client)
 }
 
+// an optional interface for auth methods whose token is managed outside of
+// vaultfs (such as by Vault Agent), and may be replaced at any time. When
+// ReloadToken returns true, Login is called again before each request to pick
+// up the current token, and the token is never renewed by vaultfs.
+type authTokenReloader interface {
+	ReloadToken() bool
+}
+
+// reloadsToken reports whether the token from auth must be reloaded before
+// each request
+func reloadsToken(auth api.AuthMethod) bool {
+	r, ok := auth.(authTokenReloader)
+
+	return ok && r.ReloadToken()
+}
+
 // AuthMethod is an authentication method that vaultfs can use to acquire a
 // token.
 //
@@ -370,6 +386,20 @@ func remoteAuth(ctx context.Context, client *api.Client, mount, extra string, va
 	return secret, nil
 }
 
+// logout discards the client's token. Auth methods which implement
+// authLogouter manage their own logout (the token auth method uses this to
+// avoid revoking a token that vaultfs didn't acquire), otherwise the token is
+// revoked.
+func logout(ctx context.Context, client *api.Client, auth api.AuthMethod) {
+	if lauth, ok := auth.(authLogouter); ok {
+		lauth.Logout(ctx, client)
+
+		return
+	}
+
+	revokeToken(ctx, client)
+}
+
 func revokeToken(ctx context.Context, client *api.Client) {
 	_, _ = client.Logical().WriteWithContext(ctx, "auth/token/revoke-self", nil)
 
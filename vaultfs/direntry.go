@@ -0,0 +1,64 @@
+package vaultfs
+
+import (
+	"io/fs"
+	"runtime"
+	"sync"
+)
+
+// lazyDirEntry is a directory entry for a secret, which is only read when Info
+// is called. The result is cached.
+//
+// The entry's file holds its own reference to the client, as the directory is
+// usually closed before Info is called, and the token must not be revoked
+// until the secret has been read.
+type lazyDirEntry struct {
+	fi    fs.FileInfo
+	err   error
+	child *vaultFile
+	name  string
+	once  sync.Once
+}
+
+// newLazyDirEntry returns a lazy entry for the child file, which is closed
+// after Info is called, or when the entry is garbage collected if it never is
+func newLazyDirEntry(child *vaultFile) *lazyDirEntry {
+	e := &lazyDirEntry{name: child.name, child: child}
+
+	// closing may log out, so isn't done in the cleanup goroutine itself
+	runtime.AddCleanup(e, func(child *vaultFile) {
+		go func() { _ = child.Close() }()
+	}, child)
+
+	return e
+}
+
+var _ fs.DirEntry = (*lazyDirEntry)(nil)
+
+func (e *lazyDirEntry) Name() string {
+	return e.name
+}
+
+// IsDir always returns false, as Vault lists directories with a trailing
+// slash, and these are never lazy
+func (e *lazyDirEntry) IsDir() bool {
+	return false
+}
+
+func (e *lazyDirEntry) Type() fs.FileMode {
+	return 0
+}
+
+func (e *lazyDirEntry) Info() (fs.FileInfo, error) {
+	e.once.Do(func() {
+		e.fi, e.err = e.child.Stat()
+
+		_ = e.child.Close()
+	})
+
+	return e.fi, e.err
+}
+
+func (e *lazyDirEntry) String() string {
+	return fs.FormatDirEntry(e)
+}
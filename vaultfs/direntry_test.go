@@ -0,0 +1,196 @@
+package vaultfs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hairyhenderson/go-fsimpl/internal/tests"
+	"github.com/hairyhenderson/go-fsimpl/internal/tests/fakevault"
+	"github.com/hairyhenderson/go-fsimpl/vaultfs/vaultauth"
+	"github.com/hashicorp/vault/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type dirVault struct {
+	mountLookups, reads, inflight, maxInflight, logins, revokes atomic.Int32
+}
+
+// loginAuthMethod logs in with a new token each time, which vaultfs revokes
+// when it's no longer needed (unlike tokens from the token auth method)
+type loginAuthMethod struct {
+	logins *atomic.Int32
+}
+
+func (m *loginAuthMethod) Login(_ context.Context, _ *api.Client) (*api.Secret, error) {
+	n := m.logins.Add(1)
+
+	return &api.Secret{Auth: &api.SecretAuth{ClientToken: fmt.Sprintf("token%d", n)}}, nil
+}
+
+// setupDirVault starts a fake Vault server with a directory of n secrets,
+// which tracks how many secrets are read concurrently
+func setupDirVault(t *testing.T, n int, failing string) (fs.FS, *dirVault) {
+	t.Helper()
+
+	dv := &dirVault{}
+
+	keys := make([]string, n)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("s%02d", i)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/sys/internal/ui/mounts", func(w http.ResponseWriter, _ *http.Request) {
+		dv.mountLookups.Add(1)
+
+		_ = json.NewEncoder(w).Encode(map[string]any{"data": map[string]any{
+			"secret": map[string]any{"secret/": map[string]any{"type": "kv"}},
+		}})
+	})
+	mux.HandleFunc("/v1/auth/token/revoke-self", func(w http.ResponseWriter, _ *http.Request) {
+		dv.revokes.Add(1)
+
+		w.WriteHeader(http.StatusNoContent)
+	})
+	mux.HandleFunc("/v1/secret/dir/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("list") == "true" {
+			if r.URL.Path != "/v1/secret/dir/" {
+				w.WriteHeader(http.StatusNotFound)
+
+				return
+			}
+
+			_ = json.NewEncoder(w).Encode(map[string]any{"data": map[string]any{
+				"keys": append(keys, "sub/"),
+			}})
+
+			return
+		}
+
+		dv.reads.Add(1)
+
+		cur := dv.inflight.Add(1)
+		defer dv.inflight.Add(-1)
+
+		for {
+			prev := dv.maxInflight.Load()
+			if cur <= prev || dv.maxInflight.CompareAndSwap(prev, cur) {
+				break
+			}
+		}
+
+		time.Sleep(10 * time.Millisecond)
+
+		name := strings.TrimPrefix(r.URL.Path, "/v1/secret/dir/")
+		if name == failing {
+			w.WriteHeader(http.StatusForbidden)
+
+			return
+		}
+
+		_ = json.NewEncoder(w).Encode(map[string]any{"data": map[string]any{"name": name}})
+	})
+
+	client := newRefCountedClient(fakevault.FakeVault(t, mux))
+
+	return vaultauth.WithAuthMethod(&loginAuthMethod{logins: &dv.logins},
+		newWithVaultClient(tests.MustURL("vault:///secret/"), client)), dv
+}
+
+func TestReadDir_Concurrent(t *testing.T) {
+	fsys, dv := setupDirVault(t, 20, "")
+	fsys = WithMaxConcurrencyFS(4, fsys)
+
+	des, err := fs.ReadDir(fsys, "dir")
+	require.NoError(t, err)
+	require.Len(t, des, 21)
+
+	assert.Equal(t, "s00", des[0].Name())
+	assert.True(t, des[20].IsDir())
+
+	fi, err := des[7].Info()
+	require.NoError(t, err)
+	assert.Equal(t, int64(len(`{"name":"s07"}`)), fi.Size())
+
+	assert.Equal(t, int32(20), dv.reads.Load())
+	assert.LessOrEqual(t, dv.maxInflight.Load(), int32(4))
+	assert.Greater(t, dv.maxInflight.Load(), int32(1))
+
+	// the mount info is looked up once for the directory, not for each child
+	assert.Equal(t, int32(1), dv.mountLookups.Load())
+}
+
+func TestReadDir_SerialByDefault(t *testing.T) {
+	fsys, dv := setupDirVault(t, 5, "")
+
+	des, err := fs.ReadDir(fsys, "dir")
+	require.NoError(t, err)
+	require.Len(t, des, 6)
+
+	assert.Equal(t, int32(1), dv.maxInflight.Load())
+
+	t.Setenv("VAULT_MAX_CONCURRENCY", "3")
+
+	fsys, _ = setupDirVault(t, 5, "")
+	assert.Equal(t, 3, fsys.(*vaultFS).maxConcurrency)
+
+	// invalid values are ignored
+	assert.Equal(t, 3, WithMaxConcurrencyFS(0, fsys).(*vaultFS).maxConcurrency)
+}
+
+func TestReadDir_ConcurrentError(t *testing.T) {
+	fsys, _ := setupDirVault(t, 10, "s03")
+	fsys = WithMaxConcurrencyFS(4, fsys)
+
+	_, err := fs.ReadDir(fsys, "dir")
+	require.Error(t, err)
+}
+
+func TestWithLazyDirEntriesFS(t *testing.T) {
+	fsys, dv := setupDirVault(t, 10, "s03")
+	fsys = WithLazyDirEntriesFS(fsys)
+
+	des, err := fs.ReadDir(fsys, "dir")
+	require.NoError(t, err)
+	require.Len(t, des, 11)
+
+	// no secrets are read until Info is called
+	assert.Equal(t, int32(0), dv.reads.Load())
+	assert.Equal(t, "s01", des[1].Name())
+	assert.False(t, des[1].IsDir())
+	assert.Equal(t, fs.FileMode(0), des[1].Type())
+
+	fi, err := des[1].Info()
+	require.NoError(t, err)
+	assert.Equal(t, "s01", fi.Name())
+	assert.Equal(t, int64(len(`{"name":"s01"}`)), fi.Size())
+	assert.Equal(t, int32(1), dv.reads.Load())
+
+	// the info is cached
+	_, err = des[1].Info()
+	require.NoError(t, err)
+	assert.Equal(t, int32(1), dv.reads.Load())
+
+	// errors are only returned by Info
+	_, err = des[3].Info()
+	require.Error(t, err)
+
+	// the token used for the listing is kept until each entry has been read
+	assert.Equal(t, int32(1), dv.logins.Load())
+	assert.Equal(t, int32(0), dv.revokes.Load())
+
+	for _, de := range des {
+		_, _ = de.Info()
+	}
+
+	assert.Equal(t, int32(1), dv.logins.Load())
+	assert.Equal(t, int32(1), dv.revokes.Load())
+}
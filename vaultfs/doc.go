@@ -37,8 +37,105 @@
 //
 //	vault:///secret/mysecret?version=5
 //
+// The version that was read, when it was created, and the secret's custom
+// metadata are available from the [KVSecretInfo] returned by the Sys method of
+// the file's [fs.FileInfo]. To read the secret's full metadata document
+// instead of its data (including the current and oldest versions, and
+// settings such as "max_versions" and "cas_required"), set the "metadata"
+// query parameter to "true":
+//
+//	vault:///secret/mysecret?metadata=true
+//
 // See the [Vault Secret Engine Docs] for more details.
 //
+// # Response Wrapping
+//
+// To read a [response-wrapped] secret, read "sys/wrapping/unwrap" with the
+// wrapping token in the "token" query parameter. The wrapping token is used to
+// authenticate, so no login is needed. To detect wrapping tokens that have been
+// intercepted and replaced, the "creation_path" query parameter must be set to
+// the path that the token must have been created by. For example:
+//
+//	vault:///sys/wrapping/unwrap?token=hvs.xxx&creation_path=secret/data/app/db
+//
+// To accept tokens created by any path, set "creation_path" to "*". The token
+// is removed from the URL and file name, so that it isn't included in errors.
+//
+// Wrapping tokens can only be used once, so a wrapped secret can only be read
+// by one opened file (Stat and Read on that file are consistent, though calling
+// [fs.Stat] and then [fs.ReadFile] will fail). To authenticate with a wrapped token instead, see
+// [vaultauth.NewWrappedTokenAuth].
+//
+// # Output Formats
+//
+// By default, a secret's data is read as a JSON object. To read a single field
+// from the secret instead, name it in the URL fragment, or with the "field"
+// query parameter (which is convenient when other query parameters are set).
+// The raw value is returned, with the content type "text/plain" (values which
+// aren't strings are rendered as JSON). For example, to read the "password"
+// field of the "secret/app/db" secret:
+//
+//	vault:///secret/app/db#password
+//	vault:///secret/app/db?version=5&field=password
+//
+// The whole secret can also be rendered in a different format by setting the
+// "accept" query parameter to one of "json" (the default), "yaml", "dotenv",
+// or "properties" (or the corresponding content type, such as
+// "application/yaml"). Set this parameter on the base URL to use the format
+// for all files in the filesystem.
+//
+// The "field", "accept", and "metadata" parameters are not sent to Vault.
+//
+// # Issuing Certificates and Signing Keys
+//
+// Some endpoints issue or sign something rather than reading a secret. For
+// these, only the useful artifact is returned as the file's content (unless a
+// field or format is selected):
+//
+//   - PKI "issue", "sign", "sign-verbatim", and "sign-intermediate": the
+//     certificate, its CA chain, and the private key (when one was generated),
+//     as a PEM bundle with the content type "application/x-pem-file"
+//   - SSH "sign": the signed public key, as "text/plain"
+//   - TOTP "code": the generated code, as "text/plain"
+//
+// Query parameters can only be strings (repeated parameters are sent as a
+// list), so use [WithRequestParamsFS] to send numbers, booleans, lists, or
+// values which are awkward to put in a URL, such as CSRs and public keys. For
+// example, to sign an SSH public key:
+//
+//	fsys = vaultfs.WithRequestParamsFS("ssh/sign/ops", map[string]any{
+//		"public_key":       pubKey,
+//		"valid_principals": []string{"root"},
+//	}, fsys)
+//	signed, err := fs.ReadFile(fsys, "ssh/sign/ops")
+//
+// # Namespaces
+//
+// To read from a [Vault Enterprise] (or OpenBao) namespace, set the
+// "namespace" query parameter on the base URL, or use [WithNamespaceFS]. The
+// namespace is used for all requests, including logins. For example:
+//
+//	vault:///secret/?namespace=team1
+//
+// By default, the namespace is set by the $VAULT_NAMESPACE environment
+// variable.
+//
+// # Leases
+//
+// Dynamic secrets (such as credentials from the database or AWS secret
+// engines) are issued with a lease. The lease's ID, duration, and renewability
+// are available from the [LeaseInfo] returned by the Sys method of the file's
+// [fs.FileInfo], and the file's modification time is the time the lease was
+// issued. Note that each opened file reads a new secret, so a new lease is
+// created for each.
+//
+// By default, leases are revoked by Vault along with the token that created
+// them, so credentials read with [fs.ReadFile] remain valid after it returns.
+// Use [WithLeaseRenewalFS] to renew leases while files are open and revoke
+// them explicitly when files are closed. In session mode (see [WithSessionFS])
+// leases are also revoked when files are closed, and the leases of files left
+// open are revoked when the filesystem is closed.
+//
 // # Authentication
 //
 // A number of authentication methods are supported and documented in detail
@@ -54,10 +151,31 @@
 // closed. This ensures that a minimal number of tokens are acquired, however
 // this also means that tokens may be leaked if all opened files are not closed.
 //
+// Long-running processes can use [WithSessionFS] to keep a single token for
+// the lifetime of the filesystem instead. The token is renewed in the
+// background before it expires, a new token is acquired when renewal fails,
+// and the token is only revoked when the filesystem is closed.
+//
 // See the [vaultauth] docs for details on each auth method.
 //
 // For help in deciding which auth method to use, consult the [Vault Auth Docs].
 //
+// # Vault Agent
+//
+// To read through a [Vault Agent] or Vault Proxy API listener, set its address
+// with the "agent_addr" query parameter (or $VAULT_AGENT_ADDR). Unix sockets
+// are supported, as well as TCP listeners:
+//
+//	vault:///secret/?agent_addr=unix:///run/vault/agent.sock
+//	vault:///secret/?agent_addr=http://127.0.0.1:8100
+//
+// When the listener is configured with "use_auto_auth_token", use
+// [vaultauth.NewAgentAutoAuth] so that no token is sent, and the agent's own
+// token is used. Alternately, [vaultauth.NewTokenFileAuth] reads the agent's
+// token from its file sink (again before each request, so that rotated tokens
+// are picked up). Tokens from these auth methods are owned by the agent, and
+// are never renewed or revoked by vaultfs.
+//
 // # Permissions
 //
 // The correct capabilities must be allowed for the authenticated credentials.
@@ -68,6 +186,15 @@
 // See [Vault Capabilities Docs] for more details on how to configure these on
 // your Vault server.
 //
+// # Directory Listings
+//
+// Listing a directory (with [fs.ReadDir]) reads each secret in the directory,
+// to find its size and modification time. By default secrets are read one at
+// a time, which can be slow for large directories. Use [WithMaxConcurrencyFS]
+// (or set $VAULT_MAX_CONCURRENCY) to read several secrets concurrently, or use
+// [WithLazyDirEntriesFS] to only read a secret when its entry's Info method is
+// called.
+//
 // # Environment Variables
 //
 // A number of environment variables are understood by the Go Vault client that
@@ -79,4 +206,7 @@
 // [Vault Client Environment Variable Docs]: https://vaultproject.io/docs/commands#environment-variables
 // [Vault Secret Engine Docs]: https://vaultproject.io/docs/secrets
 // [Hashicorp Vault]: https://vaultproject.io
+// [response-wrapped]: https://developer.hashicorp.com/vault/docs/concepts/response-wrapping
+// [Vault Agent]: https://developer.hashicorp.com/vault/docs/agent-and-proxy/agent
+// [Vault Enterprise]: https://developer.hashicorp.com/vault/docs/enterprise/namespaces
 package vaultfs
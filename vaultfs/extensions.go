@@ -37,3 +37,162 @@ func WithConfig(config *api.Config, fsys fs.FS) fs.FS {
 
 	return fsys
 }
+
+type withSessioner interface {
+	WithSession() fs.FS
+}
+
+// WithSessionFS puts the filesystem fsys into long-lived session mode, if the
+// filesystem supports it (i.e. is a [FS], or some other type with a
+// WithSession method).
+//
+// By default, the Vault token is revoked as soon as the last open file is
+// closed, so a long-running process may need to log in again for nearly every
+// read. In session mode, a single token is acquired on first use and shared by
+// all files. When the token is renewable, it is renewed in the background
+// before it expires, and when it can't be renewed any further (or renewal
+// fails), vaultfs logs in again with the configured auth method.
+//
+// The leases of dynamic secrets are revoked when their files are closed. The
+// token is only revoked when the filesystem is closed, along with the leases
+// of any files still open, so the returned filesystem implements [io.Closer],
+// and must be closed when no longer needed:
+//
+//	fsys = vaultfs.WithSessionFS(fsys)
+//	defer fsys.(io.Closer).Close()
+//
+// Note that the session is shared by all filesystems derived from the returned
+// filesystem (for example with [fsimpl.WithContextFS]), and closing any of
+// them closes the session.
+func WithSessionFS(fsys fs.FS) fs.FS {
+	if sfsys, ok := fsys.(withSessioner); ok {
+		return sfsys.WithSession()
+	}
+
+	return fsys
+}
+
+type withLeaseRenewaler interface {
+	WithLeaseRenewal() fs.FS
+}
+
+// WithLeaseRenewalFS configures the filesystem fsys to manage the leases of
+// dynamic secrets (such as database or AWS credentials), if the filesystem
+// supports it (i.e. is a [FS], or some other type with a WithLeaseRenewal
+// method).
+//
+// Renewable leases are renewed in the background for as long as the file is
+// open. As leases are revoked when the file is closed, the credentials can
+// only be relied on while the file is open - so files should be held open for
+// as long as the credentials are in use.
+//
+// By default, leases are not renewed, and are only revoked along with the
+// token that created them (which happens when the last open file is closed,
+// unless the token was supplied with the token auth method).
+//
+// Details of a file's lease are available from the [LeaseInfo] returned by
+// the Sys method of the file's [fs.FileInfo].
+func WithLeaseRenewalFS(fsys fs.FS) fs.FS {
+	if lfsys, ok := fsys.(withLeaseRenewaler); ok {
+		return lfsys.WithLeaseRenewal()
+	}
+
+	return fsys
+}
+
+type withNamespacer interface {
+	WithNamespace(namespace string) fs.FS
+}
+
+// WithNamespaceFS sets the Vault Enterprise (or OpenBao) namespace for the
+// filesystem fsys, if the filesystem supports it (i.e. is a [FS], or some
+// other type with a WithNamespace method). The namespace is used for reading
+// secrets, for looking up mount information, and for logging in.
+//
+// This overrides the $VAULT_NAMESPACE environment variable, and allows a
+// process to read from several namespaces at once. The namespace can also be
+// set with the "namespace" URL query parameter, such as:
+//
+//	vault:///secret/?namespace=team1
+//
+// An empty namespace is ignored.
+func WithNamespaceFS(namespace string, fsys fs.FS) fs.FS {
+	if nfsys, ok := fsys.(withNamespacer); ok {
+		return nfsys.WithNamespace(namespace)
+	}
+
+	return fsys
+}
+
+type withMaxConcurrencyer interface {
+	WithMaxConcurrency(n int) fs.FS
+}
+
+// WithMaxConcurrencyFS sets the maximum number of secrets read concurrently
+// during a directory listing, if the filesystem supports it. Each secret in a
+// directory must be read to find its size, so listing large directories
+// serially can be slow. Values <= 0 are ignored. The default is controlled by
+// the VAULT_MAX_CONCURRENCY environment variable, falling back to 1 (serial)
+// if unset.
+func WithMaxConcurrencyFS(n int, fsys fs.FS) fs.FS {
+	if mfsys, ok := fsys.(withMaxConcurrencyer); ok {
+		return mfsys.WithMaxConcurrency(n)
+	}
+
+	return fsys
+}
+
+type withLazyDirEntrieser interface {
+	WithLazyDirEntries() fs.FS
+}
+
+// WithLazyDirEntriesFS configures the filesystem fsys to return directory
+// entries which only read the secret when their Info method is called, if the
+// filesystem supports it. This makes listing directories fast when only the
+// names of the secrets are needed.
+//
+// Note that the secret may have been removed by the time Info is called, in
+// which case an error wrapping [fs.ErrNotExist] is returned. The token used to
+// list the directory is also used to read the secrets, so it isn't revoked
+// until Info has been called on each entry (or the entries are garbage
+// collected).
+func WithLazyDirEntriesFS(fsys fs.FS) fs.FS {
+	if lfsys, ok := fsys.(withLazyDirEntrieser); ok {
+		return lfsys.WithLazyDirEntries()
+	}
+
+	return fsys
+}
+
+type withRequestParamser interface {
+	WithRequestParams(name string, params map[string]any) fs.FS
+}
+
+// WithRequestParamsFS sets typed parameters to send in the request body when
+// the named file is read from the filesystem fsys, if the filesystem supports
+// it (i.e. is a [FS], or some other type with a WithRequestParams method).
+// Each call sets the parameters for one file, replacing any previously set for
+// that file.
+//
+// URL query parameters can only be strings (repeated parameters are sent as
+// lists), so this is useful for endpoints which take numbers, booleans, or
+// lists, or for values which are awkward in a URL, such as PEM-encoded CSRs.
+// Byte slices are sent as strings. When the same parameter is also set in the
+// URL, the typed parameter takes precedence. For example:
+//
+//	fsys = vaultfs.WithRequestParamsFS("pki/sign/web", map[string]any{
+//		"csr":       csrPEM,
+//		"alt_names": []string{"www.example.com", "example.com"},
+//		"ttl":       3600,
+//	}, fsys)
+//	cert, err := fs.ReadFile(fsys, "pki/sign/web")
+//
+// The name is the file's name as passed to Open, without any query string or
+// fragment.
+func WithRequestParamsFS(name string, params map[string]any, fsys fs.FS) fs.FS {
+	if pfsys, ok := fsys.(withRequestParamser); ok {
+		return pfsys.WithRequestParams(name, params)
+	}
+
+	return fsys
+}
@@ -0,0 +1,234 @@
+package vaultfs
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// outputFormat describes a format that secrets can be rendered in
+type outputFormat struct {
+	encode      func(data map[string]any) ([]byte, error)
+	contentType string
+	names       []string
+}
+
+//nolint:gochecknoglobals
+var outputFormats = []*outputFormat{
+	{
+		names:       []string{"json"},
+		contentType: "application/json",
+		encode:      func(data map[string]any) ([]byte, error) { return json.Marshal(data) },
+	},
+	{
+		names:       []string{"yaml", "yml"},
+		contentType: "application/yaml",
+		encode:      encodeYAML,
+	},
+	{
+		names:       []string{"dotenv", "env"},
+		contentType: "text/plain",
+		encode:      encodeDotenv,
+	},
+	{
+		names:       []string{"properties"},
+		contentType: "text/x-java-properties",
+		encode:      encodeProperties,
+	},
+}
+
+// findOutputFormat finds the output format with the given name or content
+// type. An empty value selects JSON, the default.
+func findOutputFormat(accept string) (*outputFormat, error) {
+	if accept == "" {
+		return outputFormats[0], nil
+	}
+
+	accept = strings.ToLower(strings.TrimSpace(accept))
+
+	for _, f := range outputFormats {
+		if accept == f.contentType || slices.Contains(f.names, accept) {
+			return f, nil
+		}
+	}
+
+	return nil, fmt.Errorf("unsupported output format %q: %w", accept, fs.ErrInvalid)
+}
+
+// render renders the secret's data as the file's content, returning the
+// content and its content type. When no field or format is selected, the
+// artifact of a write-style endpoint is returned, if there is one.
+func (f *vaultFile) render(data map[string]any) ([]byte, string, error) {
+	if f.field != "" {
+		v, ok := data[f.field]
+		if !ok {
+			return nil, "", fmt.Errorf("field %q not found in secret: %w", f.field, fs.ErrNotExist)
+		}
+
+		s, err := scalarString(v)
+		if err != nil {
+			return nil, "", err
+		}
+
+		return []byte(s), "text/plain", nil
+	}
+
+	if f.format == nil && f.artifact != nil {
+		if b := f.artifact.extract(data); b != nil {
+			return b, f.artifact.contentType, nil
+		}
+	}
+
+	format := f.format
+	if format == nil {
+		format = outputFormats[0]
+	}
+
+	if data == nil {
+		return nil, format.contentType, nil
+	}
+
+	b, err := format.encode(data)
+	if err != nil {
+		return nil, "", fmt.Errorf("unexpected failure to render vault secret as %s: %w", format.names[0], err)
+	}
+
+	return b, format.contentType, nil
+}
+
+// scalarString renders a single value from a secret. Strings are returned
+// as-is, and compound values are rendered as JSON.
+func scalarString(v any) (string, error) {
+	switch v := v.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case json.Number:
+		return v.String(), nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	default:
+		b, err := json.Marshal(v)
+		if err != nil {
+			return "", fmt.Errorf("unexpected failure to marshal value: %w", err)
+		}
+
+		return string(b), nil
+	}
+}
+
+func encodeYAML(data map[string]any) ([]byte, error) {
+	return yaml.Marshal(yamlValue(data))
+}
+
+// yamlValue converts json.Number values (as returned by the Vault client) to
+// numbers, which would otherwise be rendered as strings
+func yamlValue(v any) any {
+	switch v := v.(type) {
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i
+		}
+
+		if f, err := v.Float64(); err == nil {
+			return f
+		}
+
+		return v.String()
+	case map[string]any:
+		out := make(map[string]any, len(v))
+		for k, val := range v {
+			out[k] = yamlValue(val)
+		}
+
+		return out
+	case []any:
+		out := make([]any, len(v))
+		for i, val := range v {
+			out[i] = yamlValue(val)
+		}
+
+		return out
+	default:
+		return v
+	}
+}
+
+// encodeDotenv renders the secret as KEY=value lines, sorted by key. Values
+// are double-quoted (with escapes) when necessary.
+func encodeDotenv(data map[string]any) ([]byte, error) {
+	buf := &bytes.Buffer{}
+
+	for _, k := range slices.Sorted(maps.Keys(data)) {
+		s, err := scalarString(data[k])
+		if err != nil {
+			return nil, err
+		}
+
+		if strings.ContainsAny(s, " \t\r\n\"'\\#$`=") {
+			r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", `\r`, "$", `\$`, "`", "\\`")
+			s = `"` + r.Replace(s) + `"`
+		}
+
+		fmt.Fprintf(buf, "%s=%s\n", k, s)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// encodeProperties renders the secret in the Java properties format, sorted by
+// key
+func encodeProperties(data map[string]any) ([]byte, error) {
+	buf := &bytes.Buffer{}
+
+	for _, k := range slices.Sorted(maps.Keys(data)) {
+		s, err := scalarString(data[k])
+		if err != nil {
+			return nil, err
+		}
+
+		fmt.Fprintf(buf, "%s=%s\n", escapeProperty(k, true), escapeProperty(s, false))
+	}
+
+	return buf.Bytes(), nil
+}
+
+// escapeProperty escapes a properties key or value. Separators and comment
+// characters only need escaping in keys, and spaces only need escaping in keys
+// and at the start of values.
+func escapeProperty(s string, key bool) string {
+	var sb strings.Builder
+
+	for i, c := range s {
+		switch c {
+		case '\\':
+			sb.WriteString(`\\`)
+		case '\n':
+			sb.WriteString(`\n`)
+		case '\r':
+			sb.WriteString(`\r`)
+		case '\t':
+			sb.WriteString(`\t`)
+		case '\f':
+			sb.WriteString(`\f`)
+		case '=', ':', '#', '!', ' ':
+			if key || (c == ' ' && i == 0) {
+				sb.WriteRune('\\')
+			}
+
+			sb.WriteRune(c)
+		default:
+			sb.WriteRune(c)
+		}
+	}
+
+	return sb.String()
+}
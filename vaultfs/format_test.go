@@ -0,0 +1,156 @@
+package vaultfs
+
+import (
+	"encoding/json"
+	"io/fs"
+	"testing"
+
+	"github.com/hairyhenderson/go-fsimpl"
+	"github.com/hairyhenderson/go-fsimpl/internal/tests"
+	"github.com/hairyhenderson/go-fsimpl/internal/tests/fakevault"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFieldSelection(t *testing.T) {
+	v := newRefCountedClient(fakevault.Server(t))
+
+	fsys := WithAuthMethod(TokenAuthMethod("blargh"),
+		newWithVaultClient(tests.MustURL("vault:///secret/"), v))
+
+	for _, name := range []string{"foo#value", "foo?field=value"} {
+		b, err := fs.ReadFile(fsys, name)
+		require.NoError(t, err)
+		assert.Equal(t, "foo", string(b))
+
+		fi, err := fs.Stat(fsys, name)
+		require.NoError(t, err)
+		assert.Equal(t, "text/plain", fsimpl.ContentType(fi))
+		assert.Equal(t, int64(3), fi.Size())
+	}
+
+	_, err := fs.ReadFile(fsys, "foo#bogus")
+	require.ErrorIs(t, err, fs.ErrNotExist)
+
+	_, err = fs.Stat(fsys, "foo?field=bogus")
+	require.ErrorIs(t, err, fs.ErrNotExist)
+}
+
+func TestOutputFormats(t *testing.T) {
+	v := newRefCountedClient(fakevault.Server(t))
+
+	fsys := WithAuthMethod(TokenAuthMethod("blargh"),
+		newWithVaultClient(tests.MustURL("vault:///secret/"), v))
+
+	testdata := []struct {
+		name, expected, contentType string
+	}{
+		{"foo?accept=json", `{"value":"foo"}`, "application/json"},
+		{"foo?accept=yaml", "value: foo\n", "application/yaml"},
+		{"foo?accept=application/yaml", "value: foo\n", "application/yaml"},
+		{"foo?accept=dotenv", "value=foo\n", "text/plain"},
+		{"foo?accept=properties", "value=foo\n", "text/x-java-properties"},
+	}
+
+	for _, d := range testdata {
+		b, err := fs.ReadFile(fsys, d.name)
+		require.NoError(t, err)
+		assert.Equal(t, d.expected, string(b), d.name)
+
+		fi, err := fs.Stat(fsys, d.name)
+		require.NoError(t, err)
+		assert.Equal(t, d.contentType, fsimpl.ContentType(fi), d.name)
+	}
+
+	_, err := fsys.Open("foo?accept=text/html")
+	require.ErrorIs(t, err, fs.ErrInvalid)
+
+	// the format can be set for the whole filesystem
+	fsys = WithAuthMethod(TokenAuthMethod("blargh"),
+		newWithVaultClient(tests.MustURL("vault:///secret/?accept=yaml"), v))
+
+	b, err := fs.ReadFile(fsys, "foo/bar")
+	require.NoError(t, err)
+	assert.Equal(t, "value: foo\n", string(b))
+
+	des, err := fs.ReadDir(fsys, "foo")
+	require.NoError(t, err)
+
+	fi, err := des[0].Info()
+	require.NoError(t, err)
+	assert.Equal(t, "application/yaml", fsimpl.ContentType(fi))
+}
+
+func TestEncodeFormats(t *testing.T) {
+	data := map[string]any{
+		"user":     "admin",
+		"password": `p@ss w"rd$`,
+		"port":     json.Number("5432"),
+		"ratio":    json.Number("0.5"),
+		"enabled":  true,
+		"tags":     []any{"a", "b"},
+		"empty":    nil,
+		"key=x":    "#comment",
+		"lines":    "one\ntwo",
+	}
+
+	b, err := encodeDotenv(data)
+	require.NoError(t, err)
+	assert.Equal(t, `empty=
+enabled=true
+key=x="#comment"
+lines="one\ntwo"
+password="p@ss w\"rd\$"
+port=5432
+ratio=0.5
+tags="[\"a\",\"b\"]"
+user=admin
+`, string(b))
+
+	b, err = encodeProperties(data)
+	require.NoError(t, err)
+	assert.Equal(t, `empty=
+enabled=true
+key\=x=#comment
+lines=one\ntwo
+password=p@ss w"rd$
+port=5432
+ratio=0.5
+tags=["a","b"]
+user=admin
+`, string(b))
+
+	b, err = encodeYAML(data)
+	require.NoError(t, err)
+	assert.Equal(t, `empty: null
+enabled: true
+key=x: '#comment'
+lines: |-
+    one
+    two
+password: p@ss w"rd$
+port: 5432
+ratio: 0.5
+tags:
+    - a
+    - b
+user: admin
+`, string(b))
+}
+
+func TestFindOutputFormat(t *testing.T) {
+	f, err := findOutputFormat("")
+	require.NoError(t, err)
+	assert.Equal(t, "application/json", f.contentType)
+
+	f, err = findOutputFormat(" YML ")
+	require.NoError(t, err)
+	assert.Equal(t, "application/yaml", f.contentType)
+
+	f, err = findOutputFormat("text/x-java-properties")
+	require.NoError(t, err)
+	assert.Equal(t, "properties", f.names[0])
+
+	_, err = findOutputFormat("xml")
+	require.Error(t, err)
+}
@@ -0,0 +1,102 @@
+package vaultfs
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"path"
+	"strconv"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+)
+
+// KVSecretInfo describes a secret read from a K/V Version 2 secret engine. It
+// is returned by the Sys method of the [fs.FileInfo] for these secrets, which
+// allows tooling to audit the age and rotation of secrets.
+type KVSecretInfo struct {
+	// CreatedTime is when the version of the secret that was read was created.
+	// When reading metadata, this is when the secret was first created.
+	CreatedTime time.Time
+
+	// CustomMetadata is the secret's custom metadata, if any.
+	CustomMetadata map[string]string
+
+	// Version is the version of the secret that was read. When reading
+	// metadata, this is the secret's current version.
+	Version int
+}
+
+func kvSecretInfo(kvsec *api.KVSecret) *KVSecretInfo {
+	info := &KVSecretInfo{CustomMetadata: customMetadata(kvsec.CustomMetadata)}
+
+	if kvsec.VersionMetadata != nil {
+		info.CreatedTime = kvsec.VersionMetadata.CreatedTime
+		info.Version = kvsec.VersionMetadata.Version
+	}
+
+	return info
+}
+
+// kvMetadataInfo extracts the info from a KV v2 metadata document, along with
+// the time the secret was last updated
+func kvMetadataInfo(data map[string]any) (*KVSecretInfo, time.Time) {
+	info := &KVSecretInfo{}
+
+	if s, err := scalarString(data["current_version"]); err == nil {
+		info.Version, _ = strconv.Atoi(s)
+	}
+
+	if cm, ok := data["custom_metadata"].(map[string]any); ok {
+		info.CustomMetadata = customMetadata(cm)
+	}
+
+	info.CreatedTime = parseTime(data["created_time"])
+
+	return info, parseTime(data["updated_time"])
+}
+
+func customMetadata(in map[string]any) map[string]string {
+	if len(in) == 0 {
+		return nil
+	}
+
+	out := make(map[string]string, len(in))
+	for k, v := range in {
+		out[k] = fmt.Sprint(v)
+	}
+
+	return out
+}
+
+func parseTime(v any) time.Time {
+	s, ok := v.(string)
+	if !ok {
+		return time.Time{}
+	}
+
+	t, err := time.Parse(time.RFC3339Nano, s)
+	if err != nil {
+		return time.Time{}
+	}
+
+	return t
+}
+
+// kv2metadata reads the metadata document of a KV v2 secret. The raw document
+// is returned (rather than using the KVv2 client's GetMetadata), so that it
+// can be rendered as-is.
+func (f *vaultFile) kv2metadata(ctx context.Context, mi *mountInfo) (*api.Secret, error) {
+	p := path.Join(mi.name, "metadata", mi.secretPath)
+
+	s, err := f.client.Logical().ReadWithContext(ctx, p)
+	if err != nil {
+		return nil, fmt.Errorf("read metadata: %w", err)
+	}
+
+	if s == nil || s.Data == nil {
+		return nil, fmt.Errorf("no metadata at %q: %w", p, fs.ErrNotExist)
+	}
+
+	return s, nil
+}
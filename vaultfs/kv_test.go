@@ -0,0 +1,133 @@
+package vaultfs
+
+import (
+	"encoding/json"
+	"io/fs"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/hairyhenderson/go-fsimpl"
+	"github.com/hairyhenderson/go-fsimpl/internal/tests"
+	"github.com/hairyhenderson/go-fsimpl/internal/tests/fakevault"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupKVv2Vault(t *testing.T) fs.FS {
+	t.Helper()
+
+	writeJSON := func(w http.ResponseWriter, v any) {
+		_ = json.NewEncoder(w).Encode(v)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/sys/internal/ui/mounts", func(w http.ResponseWriter, _ *http.Request) {
+		writeJSON(w, map[string]any{"data": map[string]any{
+			"secret": map[string]any{
+				"kv/":     map[string]any{"type": "kv", "options": map[string]any{"version": "2"}},
+				"secret/": map[string]any{"type": "kv"},
+			},
+		}})
+	})
+	mux.HandleFunc("/v1/kv/data/app", func(w http.ResponseWriter, _ *http.Request) {
+		writeJSON(w, map[string]any{"data": map[string]any{
+			"data": map[string]any{"password": "hunter2"},
+			"metadata": map[string]any{
+				"created_time":    "2024-03-01T10:00:00Z",
+				"custom_metadata": map[string]any{"owner": "payments"},
+				"deletion_time":   "",
+				"destroyed":       false,
+				"version":         3,
+			},
+		}})
+	})
+	mux.HandleFunc("/v1/kv/metadata/app", func(w http.ResponseWriter, _ *http.Request) {
+		writeJSON(w, map[string]any{"data": map[string]any{
+			"cas_required":         true,
+			"created_time":         "2023-01-01T00:00:00Z",
+			"current_version":      3,
+			"custom_metadata":      map[string]any{"owner": "payments"},
+			"delete_version_after": "0s",
+			"max_versions":         10,
+			"oldest_version":       1,
+			"updated_time":         "2024-03-01T10:00:00Z",
+		}})
+	})
+	mux.HandleFunc("/", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		writeJSON(w, map[string]any{"errors": []string{}})
+	})
+	mux.HandleFunc("/v1/secret/foo", func(w http.ResponseWriter, _ *http.Request) {
+		writeJSON(w, map[string]any{"data": map[string]any{"value": "foo"}})
+	})
+
+	client := newRefCountedClient(fakevault.FakeVault(t, mux))
+
+	return WithAuthMethod(TokenAuthMethod("blargh"),
+		newWithVaultClient(tests.MustURL("vault:///"), client))
+}
+
+func TestKVv2SecretInfo(t *testing.T) {
+	fsys := setupKVv2Vault(t)
+
+	fi, err := fs.Stat(fsys, "kv/app")
+	require.NoError(t, err)
+
+	created := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+
+	assert.Equal(t, created, fi.ModTime())
+	assert.Equal(t, &KVSecretInfo{
+		CreatedTime:    created,
+		CustomMetadata: map[string]string{"owner": "payments"},
+		Version:        3,
+	}, fi.Sys())
+}
+
+func TestKVv2Metadata(t *testing.T) {
+	fsys := setupKVv2Vault(t)
+
+	b, err := fs.ReadFile(fsys, "kv/app?metadata=true")
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"cas_required": true,
+		"created_time": "2023-01-01T00:00:00Z",
+		"current_version": 3,
+		"custom_metadata": {"owner": "payments"},
+		"delete_version_after": "0s",
+		"max_versions": 10,
+		"oldest_version": 1,
+		"updated_time": "2024-03-01T10:00:00Z"
+	}`, string(b))
+
+	fi, err := fs.Stat(fsys, "kv/app?metadata=true")
+	require.NoError(t, err)
+	assert.Equal(t, "application/json", fsimpl.ContentType(fi))
+	assert.Equal(t, int64(len(b)), fi.Size())
+	assert.Equal(t, time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC), fi.ModTime())
+	assert.Equal(t, &KVSecretInfo{
+		CreatedTime:    time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC),
+		CustomMetadata: map[string]string{"owner": "payments"},
+		Version:        3,
+	}, fi.Sys())
+
+	// fields can be selected from the metadata
+	b, err = fs.ReadFile(fsys, "kv/app?metadata=true#oldest_version")
+	require.NoError(t, err)
+	assert.Equal(t, "1", string(b))
+
+	_, err = fs.ReadFile(fsys, "kv/bogus?metadata=true")
+	require.ErrorIs(t, err, fs.ErrNotExist)
+
+	// metadata is only available for KV v2 secrets
+	_, err = fs.ReadFile(fsys, "secret/foo?metadata=true")
+	require.ErrorIs(t, err, fs.ErrInvalid)
+
+	// metadata=false reads the secret as usual
+	b, err = fs.ReadFile(fsys, "kv/app?metadata=false")
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"password":"hunter2"}`, string(b))
+
+	_, err = fsys.Open("kv/app?metadata=maybe")
+	require.ErrorIs(t, err, fs.ErrInvalid)
+}
@@ -0,0 +1,138 @@
+package vaultfs
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+)
+
+// LeaseInfo describes the lease of a dynamic secret (such as database or AWS
+// credentials). It is returned by the Sys method of the [fs.FileInfo] for
+// files which were read from a secret engine that issues leases.
+type LeaseInfo struct {
+	// IssueTime is when the secret was read (and the lease was created).
+	// This is also the file's modification time.
+	IssueTime time.Time
+
+	// ExpireTime is when the lease expires, as of the last renewal.
+	ExpireTime time.Time
+
+	// LeaseID is the ID of the lease, which can be used to renew or revoke it.
+	LeaseID string
+
+	// LeaseDuration is the duration of the lease, as of the last renewal.
+	LeaseDuration time.Duration
+
+	// Renewable is true if the lease can be renewed.
+	Renewable bool
+}
+
+// lease tracks the lease of a dynamic secret read by a file
+type lease struct {
+	watcher *api.LifetimeWatcher
+	info    LeaseInfo
+	mu      sync.Mutex
+	revoked bool
+}
+
+func newLease(secret *api.Secret, issued time.Time) *lease {
+	d := time.Duration(secret.LeaseDuration) * time.Second
+
+	return &lease{info: LeaseInfo{
+		IssueTime:     issued,
+		ExpireTime:    issued.Add(d),
+		LeaseID:       secret.LeaseID,
+		LeaseDuration: d,
+		Renewable:     secret.Renewable,
+	}}
+}
+
+// Info returns a snapshot of the lease's current state.
+func (l *lease) Info() *LeaseInfo {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	info := l.info
+
+	return &info
+}
+
+// startRenewal renews the lease in the background, until it can't be renewed
+// any further, or until it's revoked
+func (l *lease) startRenewal(ctx context.Context, client *api.Client, secret *api.Secret) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.revoked || l.watcher != nil || !l.info.Renewable {
+		return nil
+	}
+
+	watcher, err := client.NewLifetimeWatcher(&api.LifetimeWatcherInput{Secret: secret})
+	if err != nil {
+		return fmt.Errorf("create lease lifetime watcher: %w", err)
+	}
+
+	l.watcher = watcher
+	id := l.info.LeaseID
+
+	go watcher.Start()
+
+	go func() {
+		for {
+			select {
+			case r := <-watcher.RenewCh():
+				l.renewed(r)
+			case err := <-watcher.DoneCh():
+				if err != nil {
+					slog.WarnContext(ctx, "vaultfs: lease renewal failed",
+						slog.String("lease_id", id), slog.Any("error", err))
+				}
+
+				return
+			}
+		}
+	}()
+
+	return nil
+}
+
+func (l *lease) renewed(r *api.RenewOutput) {
+	if r == nil || r.Secret == nil {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.info.LeaseDuration = time.Duration(r.Secret.LeaseDuration) * time.Second
+	l.info.ExpireTime = r.RenewedAt.Add(l.info.LeaseDuration)
+	l.info.Renewable = r.Secret.Renewable
+}
+
+// revoke stops renewal and revokes the lease. Subsequent calls do nothing.
+func (l *lease) revoke(ctx context.Context, client *api.Client) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.revoked {
+		return nil
+	}
+
+	l.revoked = true
+
+	if l.watcher != nil {
+		l.watcher.Stop()
+		l.watcher = nil
+	}
+
+	err := client.Sys().RevokeWithContext(ctx, l.info.LeaseID)
+	if err != nil {
+		return fmt.Errorf("revoke lease %q: %w", l.info.LeaseID, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,185 @@
+package vaultfs
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hairyhenderson/go-fsimpl/internal/tests"
+	"github.com/hairyhenderson/go-fsimpl/internal/tests/fakevault"
+	"github.com/hairyhenderson/go-fsimpl/vaultfs/vaultauth"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type leaseVault struct {
+	revoked  []string
+	reads    atomic.Int32
+	renewals atomic.Int32
+	mu       sync.Mutex
+}
+
+func (v *leaseVault) revokedLeases() []string {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	return append([]string{}, v.revoked...)
+}
+
+// setupLeaseVault starts a fake Vault server with a database secret engine,
+// which issues new credentials with a short, renewable lease on each read
+func setupLeaseVault(t *testing.T) (fs.FS, *leaseVault) {
+	t.Helper()
+
+	lv := &leaseVault{}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/sys/internal/ui/mounts", func(w http.ResponseWriter, _ *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]any{"data": map[string]any{
+			"secret": map[string]any{"database/": map[string]any{"type": "database"}},
+		}})
+	})
+	mux.HandleFunc("/v1/database/creds/app", func(w http.ResponseWriter, _ *http.Request) {
+		n := lv.reads.Add(1)
+
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"lease_id":       fmt.Sprintf("database/creds/app/lease%d", n),
+			"lease_duration": 1,
+			"renewable":      true,
+			"data":           map[string]any{"username": fmt.Sprintf("user%d", n)},
+		})
+	})
+	mux.HandleFunc("/v1/sys/leases/renew", func(w http.ResponseWriter, r *http.Request) {
+		lv.renewals.Add(1)
+
+		body := map[string]any{}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"lease_id":       body["lease_id"],
+			"lease_duration": 1,
+			"renewable":      true,
+		})
+	})
+	mux.HandleFunc("/v1/sys/leases/revoke", func(w http.ResponseWriter, r *http.Request) {
+		body := map[string]string{}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+
+		lv.mu.Lock()
+		lv.revoked = append(lv.revoked, body["lease_id"])
+		lv.mu.Unlock()
+
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	client := newRefCountedClient(fakevault.FakeVault(t, mux))
+	fsys := vaultauth.WithAuthMethod(vaultauth.NewTokenAuth("foo"),
+		newWithVaultClient(tests.MustURL("vault:///database/"), client))
+
+	return fsys, lv
+}
+
+func TestLeaseInfo(t *testing.T) {
+	fsys, lv := setupLeaseVault(t)
+
+	f, err := fsys.Open("creds/app")
+	require.NoError(t, err)
+
+	fi, err := f.Stat()
+	require.NoError(t, err)
+
+	info, ok := fi.Sys().(*LeaseInfo)
+	require.True(t, ok)
+	assert.Equal(t, "database/creds/app/lease1", info.LeaseID)
+	assert.Equal(t, time.Second, info.LeaseDuration)
+	assert.True(t, info.Renewable)
+	assert.Equal(t, info.IssueTime, fi.ModTime())
+	assert.Equal(t, info.IssueTime.Add(time.Second), info.ExpireTime)
+
+	// Read returns the same credentials as Stat described
+	b, err := io.ReadAll(f)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"username":"user1"}`, string(b))
+	assert.Equal(t, int32(1), lv.reads.Load())
+
+	require.NoError(t, f.Close())
+	assert.Equal(t, int32(0), lv.renewals.Load())
+}
+
+func TestLease_NotRevokedByDefault(t *testing.T) {
+	fsys, lv := setupLeaseVault(t)
+
+	// the credentials must still be usable after ReadFile returns
+	b, err := fs.ReadFile(fsys, "creds/app")
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"username":"user1"}`, string(b))
+
+	_, err = fs.Stat(fsys, "creds/app")
+	require.NoError(t, err)
+
+	assert.Empty(t, lv.revokedLeases())
+	assert.Equal(t, int32(0), lv.renewals.Load())
+}
+
+func TestWithLeaseRenewalFS(t *testing.T) {
+	fsys, lv := setupLeaseVault(t)
+	fsys = WithLeaseRenewalFS(fsys)
+
+	f, err := fsys.Open("creds/app")
+	require.NoError(t, err)
+
+	fi, err := f.Stat()
+	require.NoError(t, err)
+
+	issued := fi.Sys().(*LeaseInfo).IssueTime
+
+	// the lease is renewed while the file is open
+	require.Eventually(t, func() bool {
+		return lv.renewals.Load() >= 2
+	}, 5*time.Second, 50*time.Millisecond)
+
+	fi, err = f.Stat()
+	require.NoError(t, err)
+
+	info := fi.Sys().(*LeaseInfo)
+	assert.Equal(t, issued, info.IssueTime)
+	assert.True(t, info.ExpireTime.After(issued.Add(time.Second)))
+
+	// and revoked when the file is closed
+	require.NoError(t, f.Close())
+	assert.Equal(t, []string{"database/creds/app/lease1"}, lv.revokedLeases())
+
+	renewals := lv.renewals.Load()
+
+	time.Sleep(1500 * time.Millisecond)
+	assert.Equal(t, renewals, lv.renewals.Load())
+}
+
+func TestWithSessionFS_RevokesLeases(t *testing.T) {
+	fsys, lv := setupLeaseVault(t)
+	fsys = WithSessionFS(fsys)
+
+	f, err := fsys.Open("creds/app")
+	require.NoError(t, err)
+
+	_, err = io.ReadAll(f)
+	require.NoError(t, err)
+
+	// leases of closed files are revoked straight away, and no longer tracked
+	_, err = fs.ReadFile(fsys, "creds/app")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"database/creds/app/lease2"}, lv.revokedLeases())
+	assert.Len(t, fsys.(*vaultFS).session.leases, 1)
+
+	// files left open have their leases revoked when the session is closed
+	require.NoError(t, fsys.(io.Closer).Close())
+	assert.Equal(t,
+		[]string{"database/creds/app/lease2", "database/creds/app/lease1"},
+		lv.revokedLeases())
+}
@@ -0,0 +1,103 @@
+package vaultfs
+
+import (
+	"fmt"
+	"io/fs"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+const (
+	// fieldParam is the URL query parameter used to select a single field
+	// from a secret, as an alternative to the URL fragment
+	fieldParam = "field"
+
+	// acceptParam is the URL query parameter used to select the format the
+	// secret is rendered in
+	acceptParam = "accept"
+
+	// metadataParam is the URL query parameter used to read a KV v2 secret's
+	// metadata instead of its data
+	metadataParam = "metadata"
+)
+
+// fileParams holds the parameters which control how a file is read, rather
+// than being sent to Vault
+type fileParams struct {
+	// a single field to select from the secret, or the format to render the
+	// whole secret in (nil when not set explicitly)
+	format *outputFormat
+	field  string
+
+	// read the metadata of a KV v2 secret
+	metadata bool
+}
+
+// parseFileParams removes the parameters that control how the file is read
+// (and the fragment) from u, as these must not be sent to Vault
+func parseFileParams(u *url.URL) (*url.URL, fileParams, error) {
+	out := *u
+	params := fileParams{field: out.Fragment}
+
+	out.Fragment = ""
+	out.RawFragment = ""
+
+	q := out.Query()
+	if !q.Has(fieldParam) && !q.Has(acceptParam) && !q.Has(metadataParam) {
+		return &out, params, nil
+	}
+
+	if params.field == "" {
+		params.field = q.Get(fieldParam)
+	}
+
+	var err error
+
+	if q.Has(acceptParam) {
+		params.format, err = findOutputFormat(q.Get(acceptParam))
+		if err != nil {
+			return nil, params, err
+		}
+	}
+
+	if q.Has(metadataParam) {
+		params.metadata, err = strconv.ParseBool(q.Get(metadataParam))
+		if err != nil {
+			return nil, params, fmt.Errorf("invalid %s parameter %q: %w", metadataParam, q.Get(metadataParam), fs.ErrInvalid)
+		}
+	}
+
+	q.Del(fieldParam)
+	q.Del(acceptParam)
+	q.Del(metadataParam)
+	out.RawQuery = q.Encode()
+
+	return &out, params, nil
+}
+
+// requestParamsKey returns the key that typed request parameters for the
+// named file are stored under - the name without any query or fragment
+func requestParamsKey(name string) string {
+	name, _, _ = strings.Cut(name, "#")
+	name, _, _ = strings.Cut(name, "?")
+
+	return strings.Trim(name, "/")
+}
+
+// normalizeRequestParams copies params, converting byte slices (such as PEM
+// encoded CSRs read from files) to strings, as these would otherwise be
+// base64-encoded in the JSON request body
+func normalizeRequestParams(params map[string]any) map[string]any {
+	out := make(map[string]any, len(params))
+
+	for k, v := range params {
+		if b, ok := v.([]byte); ok {
+			v = string(b)
+		}
+
+		out[k] = v
+	}
+
+	return out
+}
@@ -0,0 +1,192 @@
+package vaultfs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"slices"
+	"sync"
+
+	"github.com/hashicorp/vault/api"
+)
+
+// session holds the state of a long-lived session, shared between all copies
+// of a filesystem created with [WithSessionFS]. A single token is acquired on
+// first use, renewed in the background for as long as it's renewable, and only
+// discarded when the filesystem is closed.
+type session struct {
+	// ctx is used for background renewal and re-authentication, and is not
+	// cancelled when the context of the operation that logged in is
+	ctx     context.Context
+	client  *api.Client
+	auth    api.AuthMethod
+	watcher *api.LifetimeWatcher
+
+	// leases of dynamic secrets read during the session by files which are
+	// still open, to be revoked when the session is closed
+	leases []*lease
+
+	mu     sync.Mutex
+	closed bool
+}
+
+// ensureToken makes sure that client has a token, logging in with auth if
+// necessary. Safe for concurrent use.
+func (s *session) ensureToken(ctx context.Context, client *api.Client, auth api.AuthMethod) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return fs.ErrClosed
+	}
+
+	if client.Token() != "" && !reloadsToken(auth) {
+		return nil
+	}
+
+	return s.login(ctx, client, auth)
+}
+
+// login acquires a new token and starts watching its lifetime. Must be called
+// with s.mu held.
+func (s *session) login(ctx context.Context, client *api.Client, auth api.AuthMethod) error {
+	secret, err := auth.Login(ctx, client)
+	if err != nil {
+		return fmt.Errorf("vault login failure: %w", err)
+	}
+
+	if secret == nil || secret.Auth == nil {
+		return errors.New("vault login failure: no auth info returned")
+	}
+
+	client.SetToken(secret.Auth.ClientToken)
+
+	// a previous login's watcher (for instance on a client derived with a
+	// different namespace) is replaced, so it must be stopped to avoid
+	// renewing its token forever
+	if s.watcher != nil {
+		s.watcher.Stop()
+		s.watcher = nil
+	}
+
+	s.ctx = context.WithoutCancel(ctx)
+	s.client = client
+	s.auth = auth
+
+	// tokens that can't be renewed (such as a token supplied with $VAULT_TOKEN)
+	// are used as-is until they expire, and tokens managed elsewhere are left
+	// to be renewed by their owner
+	if !secret.Auth.Renewable || secret.Auth.LeaseDuration <= 0 || reloadsToken(auth) {
+		return nil
+	}
+
+	watcher, err := client.NewLifetimeWatcher(&api.LifetimeWatcherInput{Secret: secret})
+	if err != nil {
+		return fmt.Errorf("create token lifetime watcher: %w", err)
+	}
+
+	s.watcher = watcher
+
+	go watcher.Start()
+	go s.watch(s.ctx, watcher)
+
+	return nil
+}
+
+// watch waits for the token's renewal to stop - either because it can't be
+// renewed any further, or because renewal failed - and then logs in again. If
+// that fails, the token is cleared so that the next operation tries again.
+func (s *session) watch(ctx context.Context, watcher *api.LifetimeWatcher) {
+	for {
+		select {
+		case <-watcher.RenewCh():
+			slog.DebugContext(ctx, "vaultfs: renewed session token")
+		case err := <-watcher.DoneCh():
+			s.mu.Lock()
+			defer s.mu.Unlock()
+
+			// the watcher was stopped, or replaced by a newer login
+			if s.closed || s.watcher != watcher {
+				return
+			}
+
+			s.watcher = nil
+
+			if err != nil {
+				slog.WarnContext(s.ctx, "vaultfs: session token renewal failed, logging in again",
+					slog.Any("error", err))
+			}
+
+			s.client.ClearToken()
+
+			if err := s.login(s.ctx, s.client, s.auth); err != nil {
+				slog.WarnContext(s.ctx, "vaultfs: session re-authentication failed",
+					slog.Any("error", err))
+			}
+
+			return
+		}
+	}
+}
+
+// trackLease records a lease to be revoked when the session is closed.
+func (s *session) trackLease(l *lease) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.leases = append(s.leases, l)
+}
+
+// untrackLease forgets a lease which has been revoked along with its file, so
+// that it isn't kept until the session is closed.
+func (s *session) untrackLease(l *lease) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.leases = slices.DeleteFunc(s.leases, func(tl *lease) bool { return tl == l })
+}
+
+func (s *session) isClosed() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.closed
+}
+
+// close stops renewal, revokes any leases acquired during the session, and
+// discards the session's token. Subsequent calls return fs.ErrClosed.
+func (s *session) close(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return fs.ErrClosed
+	}
+
+	s.closed = true
+
+	if s.watcher != nil {
+		s.watcher.Stop()
+		s.watcher = nil
+	}
+
+	if s.client == nil || s.client.Token() == "" {
+		return nil
+	}
+
+	// leases must be revoked before the token, as they can't be revoked
+	// once the token is gone
+	errs := make([]error, 0, len(s.leases))
+
+	for _, l := range s.leases {
+		errs = append(errs, l.revoke(ctx, s.client))
+	}
+
+	s.leases = nil
+
+	logout(ctx, s.client, s.auth)
+
+	return errors.Join(errs...)
+}
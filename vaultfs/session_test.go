@@ -0,0 +1,202 @@
+package vaultfs
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"io/fs"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hairyhenderson/go-fsimpl/internal/tests"
+	"github.com/hairyhenderson/go-fsimpl/internal/tests/fakevault"
+	"github.com/hairyhenderson/go-fsimpl/vaultfs/vaultauth"
+	"github.com/hashicorp/vault/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type sessionCounts struct {
+	logins, renewals, revocations atomic.Int32
+
+	// renewals of tokens acquired in a namespace
+	nsRenewals atomic.Int32
+}
+
+// sessionVault is a fake Vault server which issues renewable tokens with a
+// very short TTL, and counts logins, renewals, and revocations
+func sessionVault(t *testing.T, renewOK bool) (*api.Client, *sessionCounts) {
+	t.Helper()
+
+	counts := &sessionCounts{}
+
+	writeJSON := func(w http.ResponseWriter, v any) {
+		_ = json.NewEncoder(w).Encode(v)
+	}
+
+	auth := func(token string) map[string]any {
+		return map[string]any{"auth": map[string]any{
+			"client_token":   token,
+			"renewable":      true,
+			"lease_duration": 1,
+		}}
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/sys/internal/ui/mounts", func(w http.ResponseWriter, _ *http.Request) {
+		writeJSON(w, map[string]any{"data": map[string]any{
+			"secret": map[string]any{"secret/": map[string]any{"type": "kv"}},
+		}})
+	})
+	mux.HandleFunc("/v1/auth/test/login", func(w http.ResponseWriter, _ *http.Request) {
+		counts.logins.Add(1)
+		writeJSON(w, auth("session-token"))
+	})
+	mux.HandleFunc("/v1/auth/token/renew-self", func(w http.ResponseWriter, r *http.Request) {
+		counts.renewals.Add(1)
+
+		if r.Header.Get("X-Vault-Namespace") != "" {
+			counts.nsRenewals.Add(1)
+		}
+
+		if !renewOK {
+			w.WriteHeader(http.StatusInternalServerError)
+
+			return
+		}
+
+		writeJSON(w, auth("session-token"))
+	})
+	mux.HandleFunc("/v1/auth/token/revoke-self", func(w http.ResponseWriter, _ *http.Request) {
+		counts.revocations.Add(1)
+		w.WriteHeader(http.StatusNoContent)
+	})
+	mux.HandleFunc("/v1/secret/foo", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "session-token", r.Header.Get("X-Vault-Token"))
+		writeJSON(w, map[string]any{"data": map[string]any{"value": "foo"}})
+	})
+
+	return fakevault.FakeVault(t, mux), counts
+}
+
+type testLoginAuthMethod struct{}
+
+func (testLoginAuthMethod) Login(ctx context.Context, client *api.Client) (*api.Secret, error) {
+	return client.Logical().WriteWithContext(ctx, "auth/test/login", nil)
+}
+
+func TestWithSessionFS(t *testing.T) {
+	client, counts := sessionVault(t, true)
+
+	fsys := vaultauth.WithAuthMethod(testLoginAuthMethod{},
+		newWithVaultClient(tests.MustURL("vault:///secret/"), newRefCountedClient(client)))
+	fsys = WithSessionFS(fsys)
+
+	// each read opens and closes a file, but the token is kept
+	for range 3 {
+		b, err := fs.ReadFile(fsys, "foo")
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"value":"foo"}`, string(b))
+	}
+
+	assert.Equal(t, int32(1), counts.logins.Load())
+	assert.Equal(t, int32(0), counts.revocations.Load())
+	assert.Equal(t, "session-token", client.Token())
+
+	// the token is renewed in the background before it expires
+	require.Eventually(t, func() bool {
+		return counts.renewals.Load() >= 2
+	}, 5*time.Second, 50*time.Millisecond)
+
+	assert.Equal(t, int32(1), counts.logins.Load())
+
+	closer, ok := fsys.(io.Closer)
+	require.True(t, ok)
+
+	require.NoError(t, closer.Close())
+	assert.Equal(t, int32(1), counts.revocations.Load())
+	assert.Empty(t, client.Token())
+
+	require.ErrorIs(t, closer.Close(), fs.ErrClosed)
+
+	_, err := fsys.Open("foo")
+	require.ErrorIs(t, err, fs.ErrClosed)
+}
+
+func TestWithSessionFS_RenewalFailure(t *testing.T) {
+	client, counts := sessionVault(t, false)
+
+	fsys := vaultauth.WithAuthMethod(testLoginAuthMethod{},
+		newWithVaultClient(tests.MustURL("vault:///secret/"), newRefCountedClient(client)))
+	fsys = WithSessionFS(fsys)
+
+	defer fsys.(io.Closer).Close()
+
+	_, err := fs.ReadFile(fsys, "foo")
+	require.NoError(t, err)
+
+	// when the token can't be renewed, vaultfs logs in again
+	require.Eventually(t, func() bool {
+		return counts.logins.Load() >= 2
+	}, 5*time.Second, 50*time.Millisecond)
+
+	assert.GreaterOrEqual(t, counts.renewals.Load(), int32(1))
+
+	_, err = fs.ReadFile(fsys, "foo")
+	require.NoError(t, err)
+}
+
+func TestWithSessionFS_Namespace(t *testing.T) {
+	client, counts := sessionVault(t, true)
+
+	fsys := vaultauth.WithAuthMethod(testLoginAuthMethod{},
+		newWithVaultClient(tests.MustURL("vault:///secret/"), newRefCountedClient(client)))
+	fsys = WithSessionFS(fsys)
+	nsfsys := WithNamespaceFS("team1", fsys)
+
+	defer fsys.(io.Closer).Close()
+
+	_, err := fs.ReadFile(fsys, "foo")
+	require.NoError(t, err)
+
+	// the namespaced client has no token yet, so logs in again
+	_, err = fs.ReadFile(nsfsys, "foo")
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), counts.logins.Load())
+
+	// only the latest login's token is renewed
+	baseRenewals := counts.renewals.Load() - counts.nsRenewals.Load()
+
+	require.Eventually(t, func() bool {
+		return counts.nsRenewals.Load() >= 3
+	}, 5*time.Second, 50*time.Millisecond)
+
+	// allow for a renewal in flight when the first watcher was stopped
+	assert.LessOrEqual(t, counts.renewals.Load()-counts.nsRenewals.Load(), baseRenewals+1)
+}
+
+func TestWithSessionFS_TokenNotRevoked(t *testing.T) {
+	client, counts := sessionVault(t, true)
+
+	// tokens vaultfs didn't acquire must not be renewed or revoked
+	fsys := vaultauth.WithAuthMethod(vaultauth.NewTokenAuth("session-token"),
+		newWithVaultClient(tests.MustURL("vault:///secret/"), newRefCountedClient(client)))
+	fsys = WithSessionFS(fsys)
+
+	_, err := fs.ReadFile(fsys, "foo")
+	require.NoError(t, err)
+
+	require.NoError(t, fsys.(io.Closer).Close())
+
+	assert.Equal(t, int32(0), counts.logins.Load())
+	assert.Equal(t, int32(0), counts.renewals.Load())
+	assert.Equal(t, int32(0), counts.revocations.Load())
+	assert.Empty(t, client.Token())
+}
+
+func TestVaultFS_CloseWithoutSession(t *testing.T) {
+	fsys := newWithVaultClient(tests.MustURL("vault:///secret/"), nil)
+	require.NoError(t, fsys.Close())
+}
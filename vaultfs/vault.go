@@ -3,14 +3,16 @@ package vaultfs
 import (
 	"bytes"
 	"context"
-	"encoding/json"
+	"crypto/tls"
 	"errors"
 	"fmt"
 	"io"
 	"io/fs"
 	"log/slog"
+	"maps"
 	"net/http"
 	"net/url"
+	"os"
 	"path"
 	"strconv"
 	"strings"
@@ -21,6 +23,7 @@ import (
 	"github.com/hairyhenderson/go-fsimpl/internal"
 	"github.com/hairyhenderson/go-fsimpl/vaultfs/vaultauth"
 	"github.com/hashicorp/vault/api"
+	"golang.org/x/sync/errgroup"
 )
 
 type vaultFS struct {
@@ -29,6 +32,32 @@ type vaultFS struct {
 	auth api.AuthMethod
 
 	client *refCountedClient
+
+	// clientErr records a failure to configure the client (see
+	// [fsimpl.WithTLSConfigFS]), returned when files are opened
+	clientErr error
+
+	// session is set in long-lived session mode (see [WithSessionFS])
+	session *session
+
+	// typed parameters to send when reading files, keyed by file name (see
+	// [WithRequestParamsFS])
+	requestParams map[string]map[string]any
+
+	// the Vault Enterprise/OpenBao namespace (see [WithNamespaceFS])
+	namespace string
+
+	// the maximum number of secrets read concurrently by ReadDir (see
+	// [WithMaxConcurrencyFS])
+	maxConcurrency int
+
+	// renew leases of dynamic secrets while files are open, and revoke them
+	// when files are closed (see [WithLeaseRenewalFS])
+	renewLeases bool
+
+	// return directory entries which only read secrets when Info is called
+	// (see [WithLazyDirEntriesFS])
+	lazyDirEntries bool
 }
 
 // New creates a filesystem for the Vault endpoint rooted at u.
@@ -41,6 +70,17 @@ type vaultFS struct {
 //   - [vaultauth.WithAuthMethod] (set the auth method)
 //   - [fsimpl.WithContextFS] (inject a context)
 //   - [fsimpl.WithHeaderFS] (inject custom HTTP headers)
+//   - [fsimpl.WithTLSConfigFS] (set the TLS configuration)
+//   - [WithSessionFS] (keep a long-lived, renewed token)
+//   - [WithLeaseRenewalFS] (renew and revoke dynamic secret leases)
+//   - [WithNamespaceFS] (set the Vault namespace)
+//   - [WithMaxConcurrencyFS] (read directory entries concurrently)
+//   - [WithLazyDirEntriesFS] (defer reading directory entries' info)
+//   - [WithRequestParamsFS] (send typed parameters when reading a file)
+//
+// The namespace can also be set with the "namespace" query parameter, and the
+// address of a Vault Agent or Vault Proxy listener with the "agent_addr" query
+// parameter.
 func New(u *url.URL) (fs.FS, error) {
 	if u == nil {
 		return nil, errors.New("url must not be nil")
@@ -64,13 +104,50 @@ func New(u *url.URL) (fs.FS, error) {
 		return nil, fmt.Errorf("vault client creation failed: %w", err)
 	}
 
+	// the namespace and agent address are applied to the client, and must
+	// not be sent to Vault as parameters
+	namespace := u.Query().Get(namespaceParam)
+	u = removeParams(u, namespaceParam, agentAddrParam)
+
 	fsys := newWithVaultClient(u, nil)
 	fsys = WithClient(c, fsys).(*vaultFS)
 	fsys.auth = vaultauth.NewTokenAuth("")
 
+	if namespace != "" {
+		fsys = fsys.WithNamespace(namespace).(*vaultFS)
+	}
+
 	return fsys, nil
 }
 
+const (
+	// namespaceParam is the URL query parameter used to set the Vault
+	// namespace
+	namespaceParam = "namespace"
+
+	// agentAddrParam is the URL query parameter used to set the address of a
+	// Vault Agent or Vault Proxy listener, overriding $VAULT_AGENT_ADDR
+	agentAddrParam = "agent_addr"
+)
+
+// removeParams returns a copy of u without the named query parameters
+func removeParams(u *url.URL, names ...string) *url.URL {
+	out := *u
+
+	q := out.Query()
+	n := len(q)
+
+	for _, name := range names {
+		q.Del(name)
+	}
+
+	if len(q) != n {
+		out.RawQuery = q.Encode()
+	}
+
+	return &out
+}
+
 func newWithVaultClient(u *url.URL, client *refCountedClient) *vaultFS {
 	base := *u
 	if base.Path == "" || base.Path == "/" {
@@ -80,18 +157,43 @@ func newWithVaultClient(u *url.URL, client *refCountedClient) *vaultFS {
 	}
 
 	return &vaultFS{
-		ctx:    context.Background(),
-		client: client,
-		base:   &base,
+		ctx:            context.Background(),
+		client:         client,
+		base:           &base,
+		maxConcurrency: defaultMaxConcurrency(),
 	}
 }
 
+// defaultMaxConcurrency reads VAULT_MAX_CONCURRENCY from the environment,
+// returning 1 if unset or invalid.
+func defaultMaxConcurrency() int {
+	if s := os.Getenv("VAULT_MAX_CONCURRENCY"); s != "" {
+		if n, err := strconv.Atoi(s); err == nil && n > 0 {
+			return n
+		}
+	}
+
+	return 1
+}
+
 func vaultConfig(u *url.URL) (*api.Config, error) {
 	config := api.DefaultConfig()
 	if config.Error != nil {
 		return nil, config.Error
 	}
 
+	tlsConfig, err := internal.TLSConfigFromURL(u)
+	if err != nil {
+		return nil, fmt.Errorf("invalid TLS configuration: %w", err)
+	}
+
+	if tlsConfig != nil {
+		config.HttpClient, err = internal.HTTPClientWithTLSConfig(config.HttpClient, tlsConfig)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	// handle compound URL scheme not supported by the client, but only if the
 	// URL has a host part set - otherwise use the scheme from $VAULT_ADDR, as
 	// set by api.DefaultConfig() above
@@ -102,6 +204,15 @@ func vaultConfig(u *url.URL) (*api.Config, error) {
 		}
 
 		config.Address = scheme + "://" + u.Host
+
+		// the client prefers the agent address, which would otherwise
+		// override the address in the URL
+		config.AgentAddress = ""
+	}
+
+	// a Vault Agent or Vault Proxy listener, which may be a Unix socket
+	if agentAddr := u.Query().Get(agentAddrParam); agentAddr != "" {
+		config.AgentAddress = agentAddr
 	}
 
 	return config, nil
@@ -113,12 +224,19 @@ func vaultConfig(u *url.URL) (*api.Config, error) {
 var FS = fsimpl.FSProviderFunc(New, "vault", "vault+http", "vault+https")
 
 var (
-	_ fs.FS                  = (*vaultFS)(nil)
-	_ fs.ReadFileFS          = (*vaultFS)(nil)
-	_ internal.WithContexter = (*vaultFS)(nil)
-	_ internal.WithHeaderer  = (*vaultFS)(nil)
-	_ withClienter           = (*vaultFS)(nil)
-	_ withConfiger           = (*vaultFS)(nil)
+	_ fs.FS                    = (*vaultFS)(nil)
+	_ fs.ReadFileFS            = (*vaultFS)(nil)
+	_ internal.WithContexter   = (*vaultFS)(nil)
+	_ internal.WithHeaderer    = (*vaultFS)(nil)
+	_ internal.WithTLSConfiger = (*vaultFS)(nil)
+	_ withClienter             = (*vaultFS)(nil)
+	_ withConfiger             = (*vaultFS)(nil)
+	_ withSessioner            = (*vaultFS)(nil)
+	_ withLeaseRenewaler       = (*vaultFS)(nil)
+	_ withNamespacer           = (*vaultFS)(nil)
+	_ withMaxConcurrencyer     = (*vaultFS)(nil)
+	_ withLazyDirEntrieser     = (*vaultFS)(nil)
+	_ io.Closer                = (*vaultFS)(nil)
 )
 
 func (f vaultFS) URL() string {
@@ -157,6 +275,10 @@ func (f *vaultFS) WithClient(client *api.Client) fs.FS {
 		return f
 	}
 
+	if f.namespace != "" {
+		client = client.WithNamespace(f.namespace)
+	}
+
 	fsys := *f
 	fsys.client = newRefCountedClient(client)
 
@@ -191,6 +313,42 @@ func (f *vaultFS) WithConfig(config *api.Config) fs.FS {
 	return f.WithClient(client)
 }
 
+// WithTLSConfig replaces the client with one configured to use the given TLS
+// configuration. Custom headers and any token already set are preserved.
+func (f *vaultFS) WithTLSConfig(config *tls.Config) fs.FS {
+	if config == nil {
+		return f
+	}
+
+	vconfig := f.client.CloneConfig()
+
+	hc, err := internal.HTTPClientWithTLSConfig(vconfig.HttpClient, config)
+	if err != nil {
+		fsys := *f
+		fsys.clientErr = fmt.Errorf("failed to apply TLS configuration: %w", err)
+
+		return &fsys
+	}
+
+	vconfig.HttpClient = hc
+
+	client, err := api.NewClient(vconfig)
+	if err != nil {
+		fsys := *f
+		fsys.clientErr = fmt.Errorf("failed to create vault client with TLS configuration: %w", err)
+
+		return &fsys
+	}
+
+	client.SetHeaders(f.client.Headers())
+
+	if token := f.client.Token(); token != "" {
+		client.SetToken(token)
+	}
+
+	return f.WithClient(client)
+}
+
 func (f vaultFS) WithAuthMethod(auth api.AuthMethod) fs.FS {
 	fsys := f
 	fsys.auth = auth
@@ -198,21 +356,138 @@ func (f vaultFS) WithAuthMethod(auth api.AuthMethod) fs.FS {
 	return &fsys
 }
 
+// WithSession returns a filesystem in long-lived session mode, where a single
+// token is shared by all files, renewed in the background, and only revoked
+// when the filesystem is closed.
+func (f vaultFS) WithSession() fs.FS {
+	fsys := f
+	fsys.session = &session{}
+
+	return &fsys
+}
+
+// WithNamespace returns a filesystem which reads from (and authenticates to)
+// the given Vault namespace. A copy of the client is used, so that tokens are
+// not shared with filesystems in other namespaces.
+func (f vaultFS) WithNamespace(namespace string) fs.FS {
+	if namespace == "" {
+		return &f
+	}
+
+	fsys := f
+	fsys.namespace = namespace
+
+	if f.client != nil {
+		fsys.client = newRefCountedClient(f.client.WithNamespace(namespace))
+	}
+
+	return &fsys
+}
+
+func (f vaultFS) WithMaxConcurrency(n int) fs.FS {
+	if n <= 0 {
+		return &f
+	}
+
+	fsys := f
+	fsys.maxConcurrency = n
+
+	return &fsys
+}
+
+func (f vaultFS) WithLazyDirEntries() fs.FS {
+	fsys := f
+	fsys.lazyDirEntries = true
+
+	return &fsys
+}
+
+// WithRequestParams returns a filesystem which sends the given parameters in
+// the request body when the named file is read.
+func (f vaultFS) WithRequestParams(name string, params map[string]any) fs.FS {
+	fsys := f
+	fsys.requestParams = maps.Clone(f.requestParams)
+
+	if fsys.requestParams == nil {
+		fsys.requestParams = map[string]map[string]any{}
+	}
+
+	fsys.requestParams[requestParamsKey(name)] = normalizeRequestParams(params)
+
+	return &fsys
+}
+
+// WithLeaseRenewal returns a filesystem which renews the leases of dynamic
+// secrets while their files are open, and revokes them when the files are
+// closed.
+func (f vaultFS) WithLeaseRenewal() fs.FS {
+	fsys := f
+	fsys.renewLeases = true
+
+	return &fsys
+}
+
+// Close ends the session started with [WithSessionFS], stopping token renewal
+// and revoking the token. It is a no-op when not in session mode.
+func (f vaultFS) Close() error {
+	if f.session == nil {
+		return nil
+	}
+
+	err := f.session.close(f.ctx)
+	if err != nil {
+		return &fs.PathError{Op: "close", Path: ".", Err: err}
+	}
+
+	return nil
+}
+
 func (f vaultFS) Open(name string) (fs.File, error) {
 	if !internal.ValidPath(name) {
 		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
 	}
 
+	if f.session != nil && f.session.isClosed() {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrClosed}
+	}
+
+	if f.clientErr != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: f.clientErr}
+	}
+
 	u, err := internal.SubURL(f.base, name)
 	if err != nil {
 		return nil, err
 	}
 
+	// TLS parameters configure the client, and must not be sent to Vault
+	u = internal.RemoveTLSParams(u)
+
+	u, params, err := parseFileParams(u)
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+
 	if f.auth == nil {
 		return nil, fmt.Errorf("missing vault auth method: %q", f.client.Token())
 	}
 
-	return newVaultFile(f.ctx, name, u, f.client, f.auth), nil
+	file := newVaultFile(f.ctx, name, u, fileOptions{
+		client:         f.client,
+		auth:           f.auth,
+		session:        f.session,
+		renewLeases:    f.renewLeases,
+		maxConcurrency: f.maxConcurrency,
+		lazyDirEntries: f.lazyDirEntries,
+	})
+	file.fileParams = params
+	file.requestParams = f.requestParams[requestParamsKey(name)]
+
+	if file.isUnwrap() {
+		file.takeWrappingToken()
+	}
+
+	return file, nil
 }
 
 // ReadFile implements fs.ReadFileFS
@@ -239,28 +514,53 @@ func (f vaultFS) ReadFile(name string) ([]byte, error) {
 
 // newVaultFile opens a vault file/dir for reading - if this file is not closed
 // a vault token may be leaked!
-func newVaultFile(ctx context.Context, name string, u *url.URL, client *refCountedClient, auth api.AuthMethod) *vaultFile {
+func newVaultFile(ctx context.Context, name string, u *url.URL, opts fileOptions) *vaultFile {
 	// add reference to shared client - will be removed on Close
-	client.AddRef()
+	opts.client.AddRef()
 
 	return &vaultFile{
-		ctx:    ctx,
-		name:   name,
-		u:      u,
-		client: client,
-		auth:   auth,
+		ctx:         ctx,
+		name:        name,
+		u:           u,
+		fileOptions: opts,
 	}
 }
 
+// fileOptions holds the filesystem's configuration, as shared by its files
+type fileOptions struct {
+	client         *refCountedClient
+	auth           api.AuthMethod
+	session        *session
+	maxConcurrency int
+	renewLeases    bool
+	lazyDirEntries bool
+}
+
 type vaultFile struct {
 	ctx  context.Context
 	name string
 
 	mountInfo *mountInfo
 
-	u      *url.URL
-	client *refCountedClient
-	auth   api.AuthMethod
+	u *url.URL
+	fileOptions
+
+	// the token to unwrap, when reading sys/wrapping/unwrap
+	wrappingToken string
+
+	// the response is cached so that Stat and Read are consistent, which
+	// matters especially for dynamic secrets
+	kvsecret *api.KVSecret
+	secret   *api.Secret
+	lease    *lease
+
+	fileParams
+
+	// typed parameters to send in the request body (see [WithRequestParamsFS])
+	requestParams map[string]any
+
+	// the useful part of a write-style endpoint's response, if any
+	artifact *artifact
 
 	body     io.ReadCloser
 	children []string
@@ -291,6 +591,25 @@ func isKVv2Mount(mi *mountInfo) bool {
 }
 
 func (f *vaultFile) request() (*api.KVSecret, *api.Secret, error) {
+	if f.kvsecret != nil || f.secret != nil {
+		return f.kvsecret, f.secret, nil
+	}
+
+	if f.isUnwrap() {
+		secret, err := f.unwrap()
+		if err != nil {
+			return nil, nil, err
+		}
+
+		f.secret = secret
+
+		if secret.LeaseID != "" {
+			f.trackLease(secret)
+		}
+
+		return nil, secret, nil
+	}
+
 	mountInfo, err := f.getMountInfo(f.ctx)
 	if err != nil {
 		return nil, nil, fmt.Errorf("get mount info: %w", err)
@@ -298,6 +617,17 @@ func (f *vaultFile) request() (*api.KVSecret, *api.Secret, error) {
 
 	// it's a KVv2 Get operation with the right type, version, and especially if
 	// the secret path is set - otherwise it might need to be a list operation
+	if mountInfo.secretPath != "" && isKVv2Mount(mountInfo) && f.metadata {
+		secret, merr := f.kv2metadata(f.ctx, mountInfo)
+		if merr != nil {
+			return nil, nil, fmt.Errorf("failed to get KV v2 secret metadata: %w", merr)
+		}
+
+		f.secret = secret
+
+		return nil, secret, nil
+	}
+
 	if mountInfo.secretPath != "" && isKVv2Mount(mountInfo) {
 		var kv *api.KVSecret
 
@@ -306,17 +636,49 @@ func (f *vaultFile) request() (*api.KVSecret, *api.Secret, error) {
 			return nil, nil, fmt.Errorf("failed to get KV v2 secret: %w", err)
 		}
 
+		f.kvsecret = kv
+
 		return kv, nil, nil
 	}
 
+	if mountInfo.secretPath != "" && f.metadata {
+		return nil, nil, fmt.Errorf("metadata can only be read from K/V version 2 secrets: %w", fs.ErrInvalid)
+	}
+
 	secret, err := f.rawRequest(http.MethodGet)
 	if err != nil {
 		return nil, nil, err
 	}
 
+	f.secret = secret
+	f.artifact = findArtifact(mountInfo)
+
+	if secret != nil && secret.LeaseID != "" {
+		f.trackLease(secret)
+	}
+
 	return nil, secret, nil
 }
 
+// trackLease keeps track of the lease of a dynamic secret, so that it can be
+// renewed while the file is open, and revoked when the file or the session is
+// closed
+func (f *vaultFile) trackLease(secret *api.Secret) {
+	f.lease = newLease(secret, time.Now())
+
+	if f.session != nil {
+		f.session.trackLease(f.lease)
+	}
+
+	if f.renewLeases {
+		err := f.lease.startRenewal(context.WithoutCancel(f.ctx), f.client.Client, secret)
+		if err != nil {
+			slog.WarnContext(f.ctx, "vaultfs: unable to renew lease",
+				slog.String("lease_id", secret.LeaseID), slog.Any("error", err))
+		}
+	}
+}
+
 func (f *vaultFile) kv2request(ctx context.Context, mount, secret string) (kv *api.KVSecret, err error) {
 	kv2client := f.client.KVv2(mount)
 
@@ -364,22 +726,28 @@ func (f *vaultFile) rawRequest(method string) (*api.Secret, error) {
 
 func (f *vaultFile) newRequest(method string) (*api.Request, error) {
 	values := f.u.Query()
-	if len(values) > 0 && method == http.MethodGet {
+	if (len(values) > 0 || len(f.requestParams) > 0) && method == http.MethodGet {
 		method = http.MethodPost
 	}
 
 	req := f.client.NewRequest(method, f.u.Path)
 	if method == http.MethodGet {
 		req.Params = values
-	} else if len(values) > 0 {
-		data := map[string]any{}
+	} else if len(values) > 0 || len(f.requestParams) > 0 {
+		data := make(map[string]any, len(values)+len(f.requestParams))
 
+		// repeated query parameters are sent as lists
 		for k, vs := range values {
-			for _, v := range vs {
-				data[k] = v
+			if len(vs) == 1 {
+				data[k] = vs[0]
+			} else {
+				data[k] = vs
 			}
 		}
 
+		// typed parameters take precedence
+		maps.Copy(data, f.requestParams)
+
 		err := req.SetJSONBody(data)
 		if err != nil {
 			return nil, err
@@ -390,7 +758,9 @@ func (f *vaultFile) newRequest(method string) (*api.Request, error) {
 }
 
 // Close the file. Will error on second call. Decrements the ref count on first
-// call and logs out of vault when the ref count reaches zero.
+// call and logs out of vault when the ref count reaches zero, unless in session
+// mode, where the token is kept until the filesystem is closed. When lease
+// renewal or session mode is enabled, the lease of a dynamic secret is revoked.
 func (f *vaultFile) Close() error {
 	// important to know the state of the file so that we don't
 	if f.closed.Load() == 1 {
@@ -400,23 +770,31 @@ func (f *vaultFile) Close() error {
 	// mark closed
 	f.closed.Store(1)
 
-	f.client.RemoveRef()
+	// the lease must be revoked before the token is
+	var lerr error
+	if f.lease != nil && (f.renewLeases || f.session != nil) {
+		lerr = f.lease.revoke(f.ctx, f.client.Client)
 
-	if f.client.Refs() == 0 {
-		// the token auth method manages its own logout, to avoid revoking the
-		// token, which shouldn't be managed here
-		if lauth, ok := f.auth.(authLogouter); ok {
-			lauth.Logout(f.ctx, f.client.Client)
-		} else {
-			revokeToken(f.ctx, f.client.Client)
+		if f.session != nil {
+			f.session.untrackLease(f.lease)
 		}
 	}
 
+	f.client.RemoveRef()
+
+	if f.client.Refs() == 0 && f.session == nil {
+		logout(f.ctx, f.client.Client, f.auth)
+	}
+
+	if lerr != nil {
+		lerr = &fs.PathError{Op: "close", Path: f.name, Err: lerr}
+	}
+
 	if f.body == nil {
-		return nil
+		return lerr
 	}
 
-	return f.body.Close()
+	return errors.Join(lerr, f.body.Close())
 }
 
 func (f *vaultFile) Read(p []byte) (int, error) {
@@ -429,21 +807,9 @@ func (f *vaultFile) Read(p []byte) (int, error) {
 		return 0, err
 	}
 
-	var b []byte
-
-	if (s != nil && s.Data != nil) || (kvsec != nil && kvsec.Data != nil) {
-		if kvsec != nil {
-			b, err = json.Marshal(kvsec.Data)
-		} else {
-			b, err = json.Marshal(s.Data)
-		}
-
-		if err != nil {
-			return 0, &fs.PathError{
-				Op: "read", Path: f.name,
-				Err: fmt.Errorf("unexpected failure to marshal vault secret: %w", err),
-			}
-		}
+	b, _, err := f.render(secretData(kvsec, s))
+	if err != nil {
+		return 0, &fs.PathError{Op: "read", Path: f.name, Err: err}
 	}
 
 	f.body = io.NopCloser(bytes.NewReader(b))
@@ -456,7 +822,7 @@ func (f *vaultFile) Stat() (fs.FileInfo, error) {
 	kvsec, secret, err := f.request()
 
 	rerr := &api.ResponseError{}
-	if errors.As(err, &rerr) && rerr.StatusCode != http.StatusNotFound {
+	if (errors.As(err, &rerr) && rerr.StatusCode != http.StatusNotFound) || (err != nil && f.isUnwrap()) {
 		return nil, &fs.PathError{
 			Op: "stat", Path: f.name,
 			Err: vaultFSError(err),
@@ -481,33 +847,52 @@ func (f *vaultFile) Stat() (fs.FileInfo, error) {
 	}
 
 	var (
-		b       []byte
 		modTime time.Time
+		sys     any
 	)
 
-	if kvsec != nil {
-		b, err = json.Marshal(kvsec.Data)
+	switch {
+	case kvsec != nil:
 		modTime = createdTimeFromData(kvsec)
-	} else {
-		b, err = json.Marshal(secret.Data)
+		sys = kvSecretInfo(kvsec)
+	case f.metadata:
+		sys, modTime = kvMetadataInfo(secret.Data)
 	}
 
+	if f.lease != nil {
+		info := f.lease.Info()
+		modTime = info.IssueTime
+		sys = info
+	}
+
+	b, contentType, err := f.render(secretData(kvsec, secret))
 	if err != nil {
-		return nil, &fs.PathError{
-			Op: "stat", Path: f.name,
-			Err: fmt.Errorf("malformed secret: %w", err),
-		}
+		return nil, &fs.PathError{Op: "stat", Path: f.name, Err: err}
 	}
 
-	return internal.FileInfo(
+	return internal.FileInfoWithSys(
 		strings.TrimSuffix(path.Base(f.name), "/"),
 		int64(len(b)),
 		0o444,
 		modTime,
-		"application/json",
+		contentType,
+		sys,
 	), nil
 }
 
+// secretData returns the data of whichever secret is set
+func secretData(kvsec *api.KVSecret, secret *api.Secret) map[string]any {
+	if kvsec != nil {
+		return kvsec.Data
+	}
+
+	if secret != nil {
+		return secret.Data
+	}
+
+	return nil
+}
+
 func (f *vaultFile) list() ([]string, error) {
 	mi, err := f.getMountInfo(f.ctx)
 	if err != nil {
@@ -558,7 +943,24 @@ func (f *vaultFile) childFile(childName string) *vaultFile {
 	u, _ := url.Parse(childName)
 	childURL := (&parent).ResolveReference(u)
 
-	return newVaultFile(f.ctx, childName, childURL, f.client, f.auth)
+	// children are rendered in the same way, but a field can't be selected
+	// from all of them
+	child := newVaultFile(f.ctx, childName, childURL, f.fileOptions)
+	child.fileParams = f.fileParams
+	child.field = ""
+
+	// children are in the same mount as the parent, so the mount info can be
+	// reused rather than looked up again for each child
+	if f.mountInfo != nil {
+		prefix := strings.TrimSuffix(f.u.Path, f.mountInfo.secretPath)
+		if strings.HasPrefix(childURL.Path, prefix) {
+			mi := *f.mountInfo
+			mi.secretPath = strings.TrimPrefix(childURL.Path, prefix)
+			child.mountInfo = &mi
+		}
+	}
+
+	return child
 }
 
 func (f *vaultFile) ReadDir(n int) ([]fs.DirEntry, error) {
@@ -572,40 +974,75 @@ func (f *vaultFile) ReadDir(n int) ([]fs.DirEntry, error) {
 		f.children = entries
 	}
 
-	dirents := []fs.DirEntry{}
+	end := len(f.children)
+	if n > 0 {
+		end = min(f.diridx+n, end)
+	}
 
-	for i := f.diridx; n <= 0 || (n > 0 && i < n+f.diridx); i++ {
-		// if we don't have enough children left
-		if i >= len(f.children) {
-			if n <= 0 {
-				break
-			}
+	dirents, err := f.dirEntries(f.children[f.diridx:end])
+	if err != nil {
+		return nil, &fs.PathError{Op: "readDir", Path: f.name, Err: err}
+	}
 
-			return dirents, io.EOF
-		}
+	f.diridx = end
+
+	// if we don't have enough children left
+	if n > 0 && len(dirents) < n {
+		return dirents, io.EOF
+	}
+
+	return dirents, nil
+}
 
-		childName := f.children[i]
+// dirEntries returns the directory entries for the named children. Secrets are
+// read (to find their size, etc) concurrently, bounded by maxConcurrency,
+// unless lazy directory entries are enabled.
+func (f *vaultFile) dirEntries(names []string) ([]fs.DirEntry, error) {
+	dirents := make([]fs.DirEntry, len(names))
+
+	limit := f.maxConcurrency
+	if limit <= 0 {
+		limit = 1
+	}
 
+	g, ctx := errgroup.WithContext(f.ctx)
+	g.SetLimit(limit)
+
+	for i, childName := range names {
 		// vault lists directories with trailing slashes
 		if strings.HasSuffix(childName, "/") {
 			fi := internal.DirInfo(childName[:len(childName)-1], time.Time{})
-			dirents = append(dirents, internal.FileInfoDirEntry(fi))
+			dirents[i] = internal.FileInfoDirEntry(fi)
 
 			continue
 		}
 
-		child := f.childFile(childName)
-		defer child.Close()
+		if f.lazyDirEntries {
+			dirents[i] = newLazyDirEntry(f.childFile(childName))
 
-		fi, err := child.Stat()
-		if err != nil {
-			return nil, &fs.PathError{Op: "readDir", Path: f.name, Err: err}
+			continue
 		}
 
-		dirents = append(dirents, internal.FileInfoDirEntry(fi))
+		g.Go(func() error {
+			child := f.childFile(childName)
+			child.ctx = ctx
+
+			defer child.Close()
+
+			fi, err := child.Stat()
+			if err != nil {
+				return err
+			}
+
+			dirents[i] = internal.FileInfoDirEntry(fi)
+
+			return nil
+		})
 	}
 
-	f.diridx += len(dirents)
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
 
 	return dirents, nil
 }
@@ -632,21 +1069,38 @@ func vaultFSError(err error) error {
 	return err
 }
 
+// ensureToken logs in, unless the client already has a token (and the auth
+// method doesn't need its token to be reloaded)
+func (f *vaultFile) ensureToken(ctx context.Context) error {
+	if f.session != nil {
+		return f.session.ensureToken(ctx, f.client.Client, f.auth)
+	}
+
+	if f.client.Token() != "" && !reloadsToken(f.auth) {
+		return nil
+	}
+
+	secret, err := f.auth.Login(ctx, f.client.Client)
+	if err != nil {
+		return fmt.Errorf("vault login failure: %w", err)
+	}
+
+	f.client.SetToken(secret.Auth.ClientToken)
+
+	return nil
+}
+
 // getMountInfo calls the undocumented sys/internal/ui/mounts endpoint to set
 // the file's mount metadata. This is used in preference to the sys/mounts
 // API because this one works read-only roles (!). The result is cached.
 func (f *vaultFile) getMountInfo(ctx context.Context) (*mountInfo, error) {
-	if f.mountInfo != nil {
-		return f.mountInfo, nil
+	err := f.ensureToken(ctx)
+	if err != nil {
+		return nil, err
 	}
 
-	if f.client.Token() == "" {
-		secret, err := f.auth.Login(ctx, f.client.Client)
-		if err != nil {
-			return nil, fmt.Errorf("vault login failure: %w", err)
-		}
-
-		f.client.SetToken(secret.Auth.ClientToken)
+	if f.mountInfo != nil {
+		return f.mountInfo, nil
 	}
 
 	resp, err := f.client.Logical().ReadRawWithContext(ctx, "sys/internal/ui/mounts")
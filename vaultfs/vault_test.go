@@ -2,6 +2,7 @@ package vaultfs
 
 import (
 	"context"
+	"crypto/tls"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -51,6 +52,22 @@ func TestVaultConfig(t *testing.T) {
 	config, err = vaultConfig(tests.MustURL("vault://vault.example.com"))
 	require.NoError(t, err)
 	assert.Equal(t, "https://vault.example.com", config.Address)
+
+	// the URL's authority overrides $VAULT_AGENT_ADDR...
+	t.Setenv("VAULT_AGENT_ADDR", "http://127.0.0.1:8100")
+
+	config, err = vaultConfig(tests.MustURL("vault://vault.example.com"))
+	require.NoError(t, err)
+	assert.Empty(t, config.AgentAddress)
+
+	config, err = vaultConfig(tests.MustURL("vault:///"))
+	require.NoError(t, err)
+	assert.Equal(t, "http://127.0.0.1:8100", config.AgentAddress)
+
+	// ...as does the agent_addr parameter
+	config, err = vaultConfig(tests.MustURL("vault:///?agent_addr=unix:///run/agent.sock"))
+	require.NoError(t, err)
+	assert.Equal(t, "unix:///run/agent.sock", config.AgentAddress)
 }
 
 func TestNew(t *testing.T) {
@@ -81,6 +98,70 @@ func TestNew(t *testing.T) {
 		// defaults to token auth method
 		assert.IsType(t, vaultauth.NewTokenAuth(""), vfs.auth)
 	}
+
+	fsys, err := New(tests.MustURL("vault:///secret/?namespace=team1&param=value"))
+	require.NoError(t, err)
+
+	vfs := fsys.(*vaultFS)
+	assert.Equal(t, "vault:///v1/secret/?param=value", vfs.base.String())
+	assert.Equal(t, "team1", vfs.namespace)
+	assert.Equal(t, "team1", vfs.client.Headers().Get(api.NamespaceHeaderName))
+}
+
+func TestWithNamespaceFS(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/sys/internal/ui/mounts", func(w http.ResponseWriter, r *http.Request) {
+		assert.NotEmpty(t, r.Header.Get(api.NamespaceHeaderName))
+
+		_ = json.NewEncoder(w).Encode(map[string]any{"data": map[string]any{
+			"secret": map[string]any{"secret/": map[string]any{"type": "kv"}},
+		}})
+	})
+	mux.HandleFunc("/v1/auth/test/login", func(w http.ResponseWriter, r *http.Request) {
+		ns := r.Header.Get(api.NamespaceHeaderName)
+		_ = json.NewEncoder(w).Encode(map[string]any{"auth": map[string]any{
+			"client_token": ns + "-token",
+		}})
+	})
+	mux.HandleFunc("/v1/secret/foo", func(w http.ResponseWriter, r *http.Request) {
+		ns := r.Header.Get(api.NamespaceHeaderName)
+		assert.Equal(t, ns+"-token", r.Header.Get("X-Vault-Token"))
+
+		_ = json.NewEncoder(w).Encode(map[string]any{"data": map[string]any{"ns": ns}})
+	})
+
+	client := fakevault.FakeVault(t, mux)
+
+	fsys := vaultauth.WithAuthMethod(testLoginAuthMethod{},
+		newWithVaultClient(tests.MustURL("vault:///secret/"), newRefCountedClient(client)))
+
+	// one process can read from several namespaces at once
+	fsys1 := WithNamespaceFS("team1", fsys)
+	fsys2 := WithNamespaceFS("team2/sub", fsys)
+
+	f1, err := fsys1.Open("foo")
+	require.NoError(t, err)
+
+	defer f1.Close()
+
+	b, err := fs.ReadFile(fsys2, "foo")
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"ns":"team2/sub"}`, string(b))
+
+	b, err = io.ReadAll(f1)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"ns":"team1"}`, string(b))
+
+	// the original client is untouched
+	assert.Empty(t, client.Namespace())
+	assert.Empty(t, client.Token())
+
+	// the namespace survives replacing the client
+	fsys1 = WithClient(fakevault.FakeVault(t, mux), fsys1)
+	assert.Equal(t, "team1", fsys1.(*vaultFS).client.Namespace())
+
+	// an empty namespace is ignored
+	assert.Equal(t, fsys1, WithNamespaceFS("", fsys1))
 }
 
 func TestWithContext(t *testing.T) {
@@ -671,3 +752,25 @@ func TestWithClient(t *testing.T) {
 	require.NoError(t, err)
 	assert.Equal(t, "application/json", fsimpl.ContentType(fi))
 }
+
+func TestWithTLSConfig(t *testing.T) {
+	cl := fakevault.Server(t)
+
+	config := cl.CloneConfig()
+	config.HttpClient = &http.Client{Transport: http.NewFileTransport(http.Dir("."))}
+
+	client, err := api.NewClient(config)
+	require.NoError(t, err)
+
+	fsys := WithAuthMethod(
+		TokenAuthMethod("blargh"),
+		newWithVaultClient(tests.MustURL("vault:///secret/"), newRefCountedClient(client)),
+	)
+
+	// the TLS config can't be applied to other transports, so the client isn't
+	// silently used without it
+	fsys = fsimpl.WithTLSConfigFS(&tls.Config{MinVersion: tls.VersionTLS12}, fsys)
+
+	_, err = fsys.Open("foo")
+	require.ErrorContains(t, err, "TLS configuration")
+}
@@ -0,0 +1,87 @@
+package vaultauth
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+
+	"github.com/hashicorp/vault/api"
+)
+
+// NewTokenFileAuth authenticates with the token in the file at path, such as a
+// [Vault Agent] or Vault Proxy auto-auth file sink. The file is read again
+// before each request, so that a token rotated by the agent is picked up.
+//
+// The token is owned by the agent, so it is never renewed, revoked, or logged
+// out by vaultfs.
+//
+// Response-wrapped and encrypted sinks are not supported.
+//
+// [Vault Agent]: https://developer.hashicorp.com/vault/docs/agent-and-proxy/autoauth/sinks/file
+func NewTokenFileAuth(path string) api.AuthMethod {
+	return &tokenFileAuthMethod{fsys: os.DirFS("/"), path: absPath(path)}
+}
+
+type tokenFileAuthMethod struct {
+	fsys fs.FS
+	path string
+}
+
+func (m *tokenFileAuthMethod) Login(_ context.Context, _ *api.Client) (*api.Secret, error) {
+	token, err := readTokenFile(m.fsys, m.path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading token from sink file: %w", err)
+	}
+
+	if token == "" {
+		return nil, fmt.Errorf("token sink file %q is empty", m.path)
+	}
+
+	return &api.Secret{Auth: &api.SecretAuth{ClientToken: token}}, nil
+}
+
+// Logout implements the vaultfs.authLogouter interface, as the token is owned
+// by the agent.
+func (m *tokenFileAuthMethod) Logout(_ context.Context, client *api.Client) {
+	client.ClearToken()
+}
+
+// ReloadToken implements the vaultfs.authTokenReloader interface, so that the
+// sink file is read again before each request.
+func (m *tokenFileAuthMethod) ReloadToken() bool {
+	return true
+}
+
+// NewAgentAutoAuth sends requests without a token, for use with a [Vault Agent]
+// or Vault Proxy API listener configured with "use_auto_auth_token", which
+// adds its own auto-auth token to requests. Set the listener's address with
+// $VAULT_AGENT_ADDR, or the "agent_addr" vaultfs URL query parameter (for
+// example "unix:///run/vault/agent.sock" or "http://127.0.0.1:8100").
+//
+// Any token set in $VAULT_TOKEN is not sent, since the listener would use it
+// instead of the auto-auth token. The agent's token is never renewed, revoked,
+// or logged out by vaultfs.
+//
+// [Vault Agent]: https://developer.hashicorp.com/vault/docs/agent-and-proxy/agent/apiproxy
+func NewAgentAutoAuth() api.AuthMethod {
+	return &agentAutoAuthMethod{}
+}
+
+type agentAutoAuthMethod struct{}
+
+func (m *agentAutoAuthMethod) Login(_ context.Context, _ *api.Client) (*api.Secret, error) {
+	return &api.Secret{Auth: &api.SecretAuth{}}, nil
+}
+
+// Logout implements the vaultfs.authLogouter interface, as there is no token
+// to revoke.
+func (m *agentAutoAuthMethod) Logout(_ context.Context, client *api.Client) {
+	client.ClearToken()
+}
+
+// ReloadToken implements the vaultfs.authTokenReloader interface, so that no
+// token is sent with any request.
+func (m *agentAutoAuthMethod) ReloadToken() bool {
+	return true
+}
@@ -0,0 +1,72 @@
+package vaultauth
+
+import (
+	"os"
+	"runtime"
+	"testing"
+	"testing/fstest"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTokenFileAuthMethod(t *testing.T) {
+	fsys := fstest.MapFS{
+		"run/vault/token": &fstest.MapFile{Data: []byte("token1\n")},
+	}
+
+	m := NewTokenFileAuth("/run/vault/token")
+	m.(*tokenFileAuthMethod).fsys = fsys
+
+	s, err := m.Login(t.Context(), nil)
+	require.NoError(t, err)
+	assert.Equal(t, "token1", s.Auth.ClientToken)
+
+	// the agent rotated the token
+	fsys["run/vault/token"] = &fstest.MapFile{Data: []byte("token2")}
+
+	s, err = m.Login(t.Context(), nil)
+	require.NoError(t, err)
+	assert.Equal(t, "token2", s.Auth.ClientToken)
+
+	fsys["run/vault/token"] = &fstest.MapFile{Data: []byte("\n")}
+
+	_, err = m.Login(t.Context(), nil)
+	require.Error(t, err)
+
+	delete(fsys, "run/vault/token")
+
+	_, err = m.Login(t.Context(), nil)
+	require.Error(t, err)
+
+	client, err := api.NewClient(nil)
+	require.NoError(t, err)
+
+	client.SetToken("token2")
+	m.(*tokenFileAuthMethod).Logout(t.Context(), client)
+	assert.Empty(t, client.Token())
+}
+
+func TestAgentAutoAuthMethod(t *testing.T) {
+	m := NewAgentAutoAuth()
+
+	s, err := m.Login(t.Context(), nil)
+	require.NoError(t, err)
+	assert.Empty(t, s.Auth.ClientToken)
+	assert.True(t, m.(*agentAutoAuthMethod).ReloadToken())
+}
+
+func TestTokenFileAuth_RelativePath(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("token files are read relative to the root, which isn't supported on Windows")
+	}
+
+	t.Chdir(t.TempDir())
+
+	require.NoError(t, os.WriteFile("sink", []byte("token1\n"), 0o600))
+
+	s, err := NewTokenFileAuth("sink").Login(t.Context(), nil)
+	require.NoError(t, err)
+	assert.Equal(t, "token1", s.Auth.ClientToken)
+}
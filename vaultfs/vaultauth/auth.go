@@ -4,8 +4,10 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"io/fs"
 	"path"
+	"path/filepath"
 	"strings"
 
 	"github.com/hashicorp/vault/api"
@@ -111,3 +113,36 @@ func (m *compositeAuthMethod) Logout(ctx context.Context, client *api.Client) {
 		client.ClearToken()
 	}
 }
+
+// readTokenFile reads a token (such as a JWT) from the file at p, trimming any
+// surrounding whitespace
+func readTokenFile(fsys fs.FS, p string) (string, error) {
+	f, err := fsys.Open(strings.TrimPrefix(p, "/"))
+	if err != nil {
+		return "", fmt.Errorf("unable to open token file: %w", err)
+	}
+	defer f.Close()
+
+	// JWTs can be large-ish, but anything over 64kB is suspicious
+	b, err := io.ReadAll(io.LimitReader(f, 64*1024))
+	if err != nil {
+		return "", fmt.Errorf("unable to read token file: %w", err)
+	}
+
+	token := strings.TrimSpace(string(b))
+	if token == "" {
+		return "", fmt.Errorf("token file %q is empty", p)
+	}
+
+	return token, nil
+}
+
+// absPath resolves p against the current working directory, as token files
+// are read relative to the root of the filesystem
+func absPath(p string) string {
+	if abs, err := filepath.Abs(p); err == nil {
+		return abs
+	}
+
+	return p
+}
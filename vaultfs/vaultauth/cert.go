@@ -0,0 +1,140 @@
+package vaultauth
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/hairyhenderson/go-fsimpl/internal"
+	"github.com/hashicorp/vault/api"
+)
+
+// NewCertAuth authenticates to Vault with the TLS certificate auth method.
+//
+// By default the client certificate configured on the Vault client is
+// presented (for example with $VAULT_CLIENT_CERT and $VAULT_CLIENT_KEY, the
+// "cert_file" and "key_file" URL query parameters, or with
+// [github.com/hairyhenderson/go-fsimpl.WithTLSConfigFS]). Use
+// [WithClientCertFiles] to present a different certificate for login only.
+//
+// Use [WithCertRole] to specify the name of the certificate role to log in
+// to. If not specified, Vault tries all roles which trust the certificate.
+//
+// Use [WithCertMountPath] to specify the mount path for the TLS certificate
+// auth method. If not specified, the default is "cert".
+//
+// See also https://developer.hashicorp.com/vault/docs/auth/cert
+func NewCertAuth(opts ...CertLoginOption) (api.AuthMethod, error) {
+	a := &certAuthMethod{mountPath: "cert"}
+
+	for _, opt := range opts {
+		if err := opt(a); err != nil {
+			return nil, fmt.Errorf("error from cert login option: %w", err)
+		}
+	}
+
+	return a, nil
+}
+
+type CertLoginOption func(a *certAuthMethod) error
+
+func WithCertMountPath(mountPath string) CertLoginOption {
+	return func(a *certAuthMethod) error {
+		a.mountPath = mountPath
+
+		return nil
+	}
+}
+
+// WithCertRole sets the name of the certificate role to log in to.
+func WithCertRole(name string) CertLoginOption {
+	return func(a *certAuthMethod) error {
+		a.role = name
+
+		return nil
+	}
+}
+
+// WithClientCertFiles sets the PEM-encoded client certificate and key files to
+// present when logging in. The files are read at each login, so that renewed
+// certificates are picked up.
+func WithClientCertFiles(certFile, keyFile string) CertLoginOption {
+	return func(a *certAuthMethod) error {
+		if certFile == "" || keyFile == "" {
+			return errors.New("both a certificate and a key file are required")
+		}
+
+		a.certFile = certFile
+		a.keyFile = keyFile
+
+		return nil
+	}
+}
+
+type certAuthMethod struct {
+	mountPath string
+	role      string
+	certFile  string
+	keyFile   string
+}
+
+func (a *certAuthMethod) Login(ctx context.Context, client *api.Client) (*api.Secret, error) {
+	loginClient := client
+
+	if a.certFile != "" {
+		c, err := a.clientWithCert(client)
+		if err != nil {
+			return nil, fmt.Errorf("cert login failed: %w", err)
+		}
+
+		loginClient = c
+	}
+
+	vars := map[string]any{}
+	if a.role != "" {
+		vars["name"] = a.role
+	}
+
+	secret, err := remoteAuth(ctx, loginClient, a.mountPath, "", vars)
+	if err != nil {
+		return nil, fmt.Errorf("cert login failed: %w", err)
+	}
+
+	return secret, nil
+}
+
+// clientWithCert returns a copy of client which presents the configured client
+// certificate, retaining the rest of the client's TLS configuration
+func (a *certAuthMethod) clientWithCert(client *api.Client) (*api.Client, error) {
+	cert, err := tls.LoadX509KeyPair(a.certFile, a.keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("load client certificate: %w", err)
+	}
+
+	config := client.CloneConfig()
+
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if tr, ok := config.HttpClient.Transport.(*http.Transport); ok && tr.TLSClientConfig != nil {
+		tlsConfig = tr.TLSClientConfig.Clone()
+	}
+
+	tlsConfig.Certificates = []tls.Certificate{cert}
+
+	config.HttpClient, err = internal.HTTPClientWithTLSConfig(config.HttpClient, tlsConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	c, err := api.NewClient(config)
+	if err != nil {
+		return nil, err
+	}
+
+	c.SetHeaders(client.Headers())
+	c.SetNamespace(client.Namespace())
+	c.ClearToken()
+
+	return c, nil
+}
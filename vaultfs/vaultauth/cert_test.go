@@ -0,0 +1,114 @@
+package vaultauth
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeClientCert generates a self-signed client certificate, and writes it
+// and its key to PEM files
+func writeClientCert(t *testing.T) (certFile, keyFile string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "client"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	certFile = filepath.Join(dir, "cert.pem")
+	keyFile = filepath.Join(dir, "key.pem")
+
+	require.NoError(t, os.WriteFile(certFile,
+		pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600))
+	require.NoError(t, os.WriteFile(keyFile,
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600))
+
+	return certFile, keyFile
+}
+
+func TestCertAuthMethod(t *testing.T) {
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/auth/cert/login", r.URL.Path)
+		assert.Empty(t, r.Header.Get("X-Vault-Token"))
+
+		in := map[string]any{}
+		_ = json.NewDecoder(r.Body).Decode(&in)
+
+		assert.Equal(t, "web", in["name"])
+
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"auth": map[string]any{
+				"client_token": r.TLS.PeerCertificates[0].Subject.CommonName + "-token",
+			},
+		})
+	}))
+	srv.TLS = &tls.Config{ClientAuth: tls.RequireAnyClientCert, MinVersion: tls.VersionTLS12}
+	srv.StartTLS()
+	t.Cleanup(srv.Close)
+
+	client, err := api.NewClient(&api.Config{Address: srv.URL, HttpClient: srv.Client()})
+	require.NoError(t, err)
+
+	client.SetToken("existing")
+
+	ctx := t.Context()
+
+	_, err = NewCertAuth(WithClientCertFiles("cert.pem", ""))
+	require.Error(t, err)
+
+	// the client doesn't present a certificate by default
+	a, err := NewCertAuth(WithCertRole("web"))
+	require.NoError(t, err)
+
+	_, err = a.Login(ctx, client)
+	require.Error(t, err)
+
+	certFile, keyFile := writeClientCert(t)
+
+	a, err = NewCertAuth(WithCertRole("web"), WithClientCertFiles(certFile, keyFile))
+	require.NoError(t, err)
+
+	s, err := a.Login(ctx, client)
+	require.NoError(t, err)
+	assert.Equal(t, "client-token", s.Auth.ClientToken)
+
+	// the original client is unchanged
+	assert.Equal(t, "existing", client.Token())
+	assert.Empty(t, srv.Client().Transport.(*http.Transport).TLSClientConfig.Certificates)
+
+	a, err = NewCertAuth(WithClientCertFiles(certFile, "/bogus/key.pem"))
+	require.NoError(t, err)
+
+	_, err = a.Login(ctx, client)
+	require.Error(t, err)
+}
@@ -2,6 +2,20 @@
 // for use with [github.com/hairyhenderson/go-fsimpl/vaultfs], but which can
 // also be used directly with a [*github.com/hashicorp/vault/api.Client].
 //
+// The auth methods provided here are:
+//   - [NewTokenAuth] - an existing token
+//   - [NewGitHubAuth] - a GitHub personal access token
+//   - [NewKubernetesAuth] - a Kubernetes service account token
+//   - [NewJWTAuth] - a JWT, such as an OIDC ID token from a CI system
+//   - [NewCertAuth] - a TLS client certificate
+//   - [NewWrappedTokenAuth] - a response-wrapped token
+//   - [NewTokenFileAuth] - a token from a file, such as a Vault Agent sink
+//   - [NewAgentAutoAuth] - a Vault Agent or Vault Proxy listener's auto-auth
+//     token
+//
+// [EnvAuthMethod] can be used to select and configure an auth method from
+// environment variables.
+//
 // See also these auth methods provided with the Vault API:
 //   - [github.com/hashicorp/vault/api/auth/approle]
 //   - [github.com/hashicorp/vault/api/auth/aws]
@@ -1,16 +1,25 @@
 package vaultauth
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"os"
+	"strings"
 
 	"github.com/hashicorp/vault/api"
 	"github.com/hashicorp/vault/api/auth/approle"
+	"github.com/hashicorp/vault/api/auth/aws"
 	"github.com/hashicorp/vault/api/auth/userpass"
 )
 
-// EnvAuthMethod configures the auth method based on environment variables. It
-// will attempt to authenticate with the following four methods, in order of
-// precedence:
+// EnvAuthMethod configures the auth method based on environment variables.
+//
+// If $VAULT_AUTH_METHOD is set, only the named auth method is used. Supported
+// values are "approle", "github", "userpass", "kubernetes", "jwt", "oidc",
+// "aws", "cert", "wrapped", "agent", and "token". Otherwise, it will attempt
+// to authenticate with the following methods, in order of precedence (except
+// for "cert", "wrapped", and "agent", which must be selected explicitly):
 //
 // # approle
 //
@@ -30,6 +39,51 @@ import (
 // $VAULT_AUTH_PASSWORD. The default mount path can be overridden with
 // $VAULT_AUTH_USERPASS_MOUNT.
 //
+// # kubernetes
+//
+// The [NewKubernetesAuth] is called, using the role from
+// $VAULT_AUTH_KUBERNETES_ROLE. The service account token is read from
+// $VAULT_AUTH_KUBERNETES_TOKEN_PATH if set, or from
+// [DefaultServiceAccountTokenPath]. The default mount path can be overridden
+// with $VAULT_AUTH_KUBERNETES_MOUNT.
+//
+// # jwt
+//
+// The [NewJWTAuth] is called, using the JWT read from the file named by
+// $VAULT_AUTH_JWT_FILE, or the JWT in $VAULT_AUTH_JWT, and the (optional) role
+// from $VAULT_AUTH_JWT_ROLE. The default mount path ("jwt", or "oidc" when
+// $VAULT_AUTH_METHOD is "oidc") can be overridden with $VAULT_AUTH_JWT_MOUNT.
+//
+// # aws
+//
+// The [github.com/hashicorp/vault/api/auth/aws.NewAWSAuth] is called with the
+// "iam" authentication type, using the role from $VAULT_AUTH_AWS_ROLE (which
+// is optional when $VAULT_AUTH_METHOD is "aws"), and the
+// X-Vault-AWS-IAM-Server-ID header value from $VAULT_AUTH_AWS_HEADER_VALUE. The
+// STS region can be set with $VAULT_AUTH_AWS_STS_REGION. The default mount
+// path can be overridden with $VAULT_AUTH_AWS_MOUNT.
+//
+// # cert
+//
+// The [NewCertAuth] is called, using the (optional) certificate role from
+// $VAULT_AUTH_CERT_ROLE. The client certificate is configured with
+// $VAULT_CLIENT_CERT and $VAULT_CLIENT_KEY, as usual. The default mount path
+// can be overridden with $VAULT_AUTH_CERT_MOUNT.
+//
+// # wrapped
+//
+// The [NewWrappedTokenAuth] is called, using the wrapping token read from the
+// file named by $VAULT_WRAPPING_TOKEN_FILE, or the wrapping token in
+// $VAULT_WRAPPING_TOKEN. The expected creation path must be set with
+// $VAULT_WRAPPING_CREATION_PATH (or set it to "*" to accept any).
+//
+// # agent
+//
+// The [NewTokenFileAuth] is called, using the Vault Agent sink file named by
+// $VAULT_AUTH_AGENT_SINK_FILE. If this isn't set, [NewAgentAutoAuth] is used
+// instead, for a Vault Agent or Vault Proxy listener with auto-auth (set
+// $VAULT_AGENT_ADDR to the listener's address).
+//
 // # token
 //
 // The [NewTokenAuth] is called, using the token from $VAULT_TOKEN, or the
@@ -39,14 +93,72 @@ import (
 // to be heavily depended upon. It is recommended that you use the auth methods
 // directly, and configure them with the appropriate options.
 func EnvAuthMethod() api.AuthMethod {
+	if name := os.Getenv("VAULT_AUTH_METHOD"); name != "" {
+		return envNamedAuthMethod(name)
+	}
+
 	return CompositeAuthMethod(
 		envAppRoleAdapter(),
 		envGitHubAdapter(),
 		envUserPassAdapter(),
+		envKubernetesAdapter(),
+		envJWTAdapter("jwt"),
+		envAWSIAMAdapter(true),
 		NewTokenAuth(""),
 	)
 }
 
+// envNamedAuthMethod returns the auth method with the given name, configured
+// from environment variables, for use only with [EnvAuthMethod]. If it can't
+// be configured, the returned auth method will fail to log in.
+func envNamedAuthMethod(name string) api.AuthMethod {
+	var (
+		a    api.AuthMethod
+		vars string
+	)
+
+	switch strings.ToLower(name) {
+	case "approle":
+		a, vars = envAppRoleAdapter(), "$VAULT_ROLE_ID"
+	case "github":
+		a, vars = envGitHubAdapter(), "$VAULT_AUTH_GITHUB_TOKEN"
+	case "userpass":
+		a, vars = envUserPassAdapter(), "$VAULT_AUTH_USERNAME"
+	case "kubernetes":
+		a, vars = envKubernetesAdapter(), "$VAULT_AUTH_KUBERNETES_ROLE"
+	case "jwt", "oidc":
+		a, vars = envJWTAdapter(strings.ToLower(name)), "$VAULT_AUTH_JWT_FILE or $VAULT_AUTH_JWT"
+	case "aws":
+		a = envAWSIAMAdapter(false)
+	case "cert":
+		a = envCertAdapter()
+	case "wrapped":
+		a, vars = envWrappedTokenAdapter(), "$VAULT_WRAPPING_TOKEN_FILE or $VAULT_WRAPPING_TOKEN"
+	case "agent":
+		a = envAgentAdapter()
+	case "token":
+		a = NewTokenAuth("")
+	default:
+		return &errAuthMethod{err: fmt.Errorf("unsupported auth method %q in $VAULT_AUTH_METHOD", name)}
+	}
+
+	if a == nil {
+		return &errAuthMethod{err: fmt.Errorf("%s auth method selected, but %s not set", name, vars)}
+	}
+
+	return a
+}
+
+// errAuthMethod is an auth method which always fails to log in with the given
+// error
+type errAuthMethod struct {
+	err error
+}
+
+func (m *errAuthMethod) Login(_ context.Context, _ *api.Client) (*api.Secret, error) {
+	return nil, m.err
+}
+
 // envAppRoleAdapter builds an AppRoleAuth from environment variables, for use
 // only with [EnvAuthMethod]
 func envAppRoleAdapter() api.AuthMethod {
@@ -116,3 +228,142 @@ func envUserPassAdapter() api.AuthMethod {
 
 	return a
 }
+
+// envKubernetesAdapter builds a KubernetesAuth from environment variables, for
+// use only with [EnvAuthMethod]
+func envKubernetesAdapter() api.AuthMethod {
+	role := os.Getenv("VAULT_AUTH_KUBERNETES_ROLE")
+	if role == "" {
+		return nil
+	}
+
+	var opts []KubernetesLoginOption
+
+	if mountPath := os.Getenv("VAULT_AUTH_KUBERNETES_MOUNT"); mountPath != "" {
+		opts = append(opts, WithKubernetesMountPath(mountPath))
+	}
+
+	if tokenPath := os.Getenv("VAULT_AUTH_KUBERNETES_TOKEN_PATH"); tokenPath != "" {
+		opts = append(opts, WithServiceAccountTokenPath(tokenPath))
+	}
+
+	a, err := NewKubernetesAuth(role, opts...)
+	if err != nil {
+		return nil
+	}
+
+	return a
+}
+
+// envJWTAdapter builds a JWTAuth from environment variables, for use only with
+// [EnvAuthMethod]
+func envJWTAdapter(defaultMount string) api.AuthMethod {
+	token := &JWTToken{}
+
+	switch {
+	case os.Getenv("VAULT_AUTH_JWT_FILE") != "":
+		token.FromFile = os.Getenv("VAULT_AUTH_JWT_FILE")
+	case os.Getenv("VAULT_AUTH_JWT") != "":
+		token.FromEnv = "VAULT_AUTH_JWT"
+	default:
+		return nil
+	}
+
+	mountPath := os.Getenv("VAULT_AUTH_JWT_MOUNT")
+	if mountPath == "" {
+		mountPath = defaultMount
+	}
+
+	a, err := NewJWTAuth(os.Getenv("VAULT_AUTH_JWT_ROLE"), token, WithJWTMountPath(mountPath))
+	if err != nil {
+		return nil
+	}
+
+	return a
+}
+
+// envAWSIAMAdapter builds an AWSAuth from environment variables, for use
+// only with [EnvAuthMethod]. When requireRole is true, nil is returned if no
+// role is set, since the AWS credentials can't otherwise be detected cheaply.
+func envAWSIAMAdapter(requireRole bool) api.AuthMethod {
+	role := os.Getenv("VAULT_AUTH_AWS_ROLE")
+	if role == "" && requireRole {
+		return nil
+	}
+
+	opts := []aws.LoginOption{aws.WithIAMAuth(), aws.WithRole(role)}
+
+	if mountPath := os.Getenv("VAULT_AUTH_AWS_MOUNT"); mountPath != "" {
+		opts = append(opts, aws.WithMountPath(mountPath))
+	}
+
+	if header := os.Getenv("VAULT_AUTH_AWS_HEADER_VALUE"); header != "" {
+		opts = append(opts, aws.WithIAMServerIDHeader(header))
+	}
+
+	if region := os.Getenv("VAULT_AUTH_AWS_STS_REGION"); region != "" {
+		opts = append(opts, aws.WithRegion(region))
+	}
+
+	a, err := aws.NewAWSAuth(opts...)
+	if err != nil {
+		return nil
+	}
+
+	return a
+}
+
+// envCertAdapter builds a CertAuth from environment variables, for use only
+// with [EnvAuthMethod]
+func envCertAdapter() api.AuthMethod {
+	opts := []CertLoginOption{WithCertRole(os.Getenv("VAULT_AUTH_CERT_ROLE"))}
+
+	if mountPath := os.Getenv("VAULT_AUTH_CERT_MOUNT"); mountPath != "" {
+		opts = append(opts, WithCertMountPath(mountPath))
+	}
+
+	a, err := NewCertAuth(opts...)
+	if err != nil {
+		return nil
+	}
+
+	return a
+}
+
+// envWrappedTokenAdapter builds a WrappedTokenAuth from environment variables,
+// for use only with [EnvAuthMethod]
+func envWrappedTokenAdapter() api.AuthMethod {
+	token := &WrappingToken{}
+
+	switch {
+	case os.Getenv("VAULT_WRAPPING_TOKEN_FILE") != "":
+		token.FromFile = os.Getenv("VAULT_WRAPPING_TOKEN_FILE")
+	case os.Getenv("VAULT_WRAPPING_TOKEN") != "":
+		token.FromEnv = "VAULT_WRAPPING_TOKEN"
+	default:
+		return nil
+	}
+
+	creationPath := os.Getenv("VAULT_WRAPPING_CREATION_PATH")
+	if creationPath == "" {
+		return &errAuthMethod{err: errors.New(
+			`wrapped auth method selected, but $VAULT_WRAPPING_CREATION_PATH not set (set it to "*" to accept any creation path)`)}
+	}
+
+	a, err := NewWrappedTokenAuth(token, WithWrappedTokenCreationPath(creationPath))
+	if err != nil {
+		return nil
+	}
+
+	return a
+}
+
+// envAgentAdapter builds a TokenFileAuth or an AgentAutoAuth from environment
+// variables, for use only with [EnvAuthMethod]
+func envAgentAdapter() api.AuthMethod {
+	if sink := os.Getenv("VAULT_AUTH_AGENT_SINK_FILE"); sink != "" {
+		return NewTokenFileAuth(sink)
+	}
+
+	return NewAgentAutoAuth()
+}
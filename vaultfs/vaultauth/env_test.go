@@ -4,6 +4,7 @@ import (
 	"testing"
 
 	"github.com/hairyhenderson/go-fsimpl/internal/tests/fakevault"
+	"github.com/hashicorp/vault/api/auth/aws"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -21,3 +22,75 @@ func TestEnvAuthLogin(t *testing.T) {
 	assert.Equal(t, "foo", s.Auth.ClientToken)
 	assert.NotNil(t, m.(*compositeAuthMethod).chosen)
 }
+
+func TestEnvAuthMethod_Named(t *testing.T) {
+	t.Setenv("VAULT_AUTH_METHOD", "bogus")
+
+	_, err := EnvAuthMethod().Login(t.Context(), nil)
+	require.ErrorContains(t, err, "unsupported auth method")
+
+	t.Setenv("VAULT_AUTH_METHOD", "kubernetes")
+
+	_, err = EnvAuthMethod().Login(t.Context(), nil)
+	require.ErrorContains(t, err, "$VAULT_AUTH_KUBERNETES_ROLE not set")
+
+	t.Setenv("VAULT_AUTH_KUBERNETES_ROLE", "myrole")
+	t.Setenv("VAULT_AUTH_KUBERNETES_MOUNT", "k8s")
+	t.Setenv("VAULT_AUTH_KUBERNETES_TOKEN_PATH", "/tmp/token")
+
+	m := EnvAuthMethod()
+	require.IsType(t, &kubernetesAuthMethod{}, m)
+	assert.Equal(t, "k8s", m.(*kubernetesAuthMethod).mountPath)
+	assert.Equal(t, "/tmp/token", m.(*kubernetesAuthMethod).tokenPath)
+
+	t.Setenv("VAULT_AUTH_METHOD", "OIDC")
+	t.Setenv("VAULT_AUTH_JWT", "header.payload.sig")
+
+	m = EnvAuthMethod()
+	require.IsType(t, &jwtAuthMethod{}, m)
+	assert.Equal(t, "oidc", m.(*jwtAuthMethod).mountPath)
+
+	t.Setenv("VAULT_AUTH_METHOD", "aws")
+
+	m = EnvAuthMethod()
+	require.IsType(t, &aws.AWSAuth{}, m)
+
+	t.Setenv("VAULT_AUTH_METHOD", "cert")
+	t.Setenv("VAULT_AUTH_CERT_ROLE", "web")
+
+	m = EnvAuthMethod()
+	require.IsType(t, &certAuthMethod{}, m)
+	assert.Equal(t, "web", m.(*certAuthMethod).role)
+
+	t.Setenv("VAULT_AUTH_METHOD", "wrapped")
+
+	_, err = EnvAuthMethod().Login(t.Context(), nil)
+	require.ErrorContains(t, err, "$VAULT_WRAPPING_TOKEN_FILE or $VAULT_WRAPPING_TOKEN not set")
+
+	t.Setenv("VAULT_WRAPPING_TOKEN", "hvs.wrapping")
+
+	_, err = EnvAuthMethod().Login(t.Context(), nil)
+	require.ErrorContains(t, err, "$VAULT_WRAPPING_CREATION_PATH not set")
+
+	t.Setenv("VAULT_WRAPPING_CREATION_PATH", "auth/token/create")
+
+	m = EnvAuthMethod()
+	require.IsType(t, &wrappedTokenAuthMethod{}, m)
+	assert.Equal(t, "auth/token/create", m.(*wrappedTokenAuthMethod).creationPath)
+
+	t.Setenv("VAULT_AUTH_METHOD", "agent")
+
+	m = EnvAuthMethod()
+	require.IsType(t, &agentAutoAuthMethod{}, m)
+
+	t.Setenv("VAULT_AUTH_AGENT_SINK_FILE", "/run/vault/token")
+
+	m = EnvAuthMethod()
+	require.IsType(t, &tokenFileAuthMethod{}, m)
+	assert.Equal(t, "/run/vault/token", m.(*tokenFileAuthMethod).path)
+
+	t.Setenv("VAULT_AUTH_METHOD", "token")
+
+	m = EnvAuthMethod()
+	require.IsType(t, &tokenAuthMethod{}, m)
+}
@@ -0,0 +1,138 @@
+package vaultauth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+
+	"github.com/hashicorp/vault/api"
+)
+
+// NewJWTAuth authenticates to Vault with the JWT/OIDC auth method, logging in
+// to the given role with a JWT (such as an OIDC ID token issued by a CI
+// system, or by a cloud provider's workload identity federation).
+//
+// If role is empty, the auth method's default role is used.
+//
+// Use [WithJWTMountPath] to specify the mount path for the JWT auth method. If
+// not specified, the default is "jwt".
+//
+// See also https://developer.hashicorp.com/vault/docs/auth/jwt
+func NewJWTAuth(role string, token *JWTToken, opts ...JWTLoginOption) (api.AuthMethod, error) {
+	err := token.validate()
+	if err != nil {
+		return nil, fmt.Errorf("invalid JWT: %w", err)
+	}
+
+	a := &jwtAuthMethod{
+		fsys:      os.DirFS("/"),
+		mountPath: "jwt",
+		role:      role,
+		token:     token,
+	}
+
+	// copied so that the caller's token isn't modified
+	if token.FromFile != "" {
+		t := *token
+		t.FromFile = absPath(t.FromFile)
+		a.token = &t
+	}
+
+	for _, opt := range opts {
+		if err := opt(a); err != nil {
+			return nil, fmt.Errorf("error from JWT login option: %w", err)
+		}
+	}
+
+	return a, nil
+}
+
+type JWTLoginOption func(a *jwtAuthMethod) error
+
+func WithJWTMountPath(mountPath string) JWTLoginOption {
+	return func(a *jwtAuthMethod) error {
+		a.mountPath = mountPath
+
+		return nil
+	}
+}
+
+// JWTToken is a struct that allows you to specify where your application is
+// storing the JWT required for login to the JWT auth method. Files are read
+// again at each login, so that rotated tokens are picked up.
+type JWTToken struct {
+	FromFile   string
+	FromString string
+	FromEnv    string
+}
+
+func (token *JWTToken) validate() error {
+	if token == nil {
+		return errors.New("jwt auth method requires a token")
+	}
+
+	return validateTokenSource("JWT", token.FromFile, token.FromString, token.FromEnv)
+}
+
+// validateTokenSource checks that exactly one of the given sources (a file,
+// a string, or an environment variable) is set
+func validateTokenSource(kind string, sources ...string) error {
+	n := 0
+
+	for _, s := range sources {
+		if s != "" {
+			n++
+		}
+	}
+
+	switch n {
+	case 0:
+		return fmt.Errorf("%s must be provided with a source file, environment variable, or plaintext string", kind)
+	case 1:
+		return nil
+	default:
+		return fmt.Errorf("only one source for the %s should be specified", kind)
+	}
+}
+
+type jwtAuthMethod struct {
+	fsys      fs.FS
+	token     *JWTToken
+	mountPath string
+	role      string
+}
+
+func (a *jwtAuthMethod) Login(ctx context.Context, client *api.Client) (*api.Secret, error) {
+	jwt := ""
+
+	switch {
+	case a.token.FromFile != "":
+		t, err := readTokenFile(a.fsys, a.token.FromFile)
+		if err != nil {
+			return nil, fmt.Errorf("error reading JWT from file: %w", err)
+		}
+
+		jwt = t
+	case a.token.FromEnv != "":
+		jwt = os.Getenv(a.token.FromEnv)
+		if jwt == "" {
+			return nil, fmt.Errorf("JWT environment variable %q not set", a.token.FromEnv)
+		}
+	default:
+		jwt = a.token.FromString
+	}
+
+	vars := map[string]any{"jwt": jwt}
+	if a.role != "" {
+		vars["role"] = a.role
+	}
+
+	secret, err := remoteAuth(ctx, client, a.mountPath, "", vars)
+	if err != nil {
+		return nil, fmt.Errorf("jwt login failed: %w", err)
+	}
+
+	return secret, nil
+}
@@ -0,0 +1,113 @@
+package vaultauth
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"runtime"
+	"testing"
+	"testing/fstest"
+
+	"github.com/hairyhenderson/go-fsimpl/internal/tests/fakevault"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJWTAuthMethod(t *testing.T) {
+	mount := "jwt"
+	role := "ci"
+
+	client := fakevault.FakeVault(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/auth/"+mount+"/login", r.URL.Path)
+
+		in := map[string]any{}
+		_ = json.NewDecoder(r.Body).Decode(&in)
+
+		assert.Equal(t, "header.payload.sig", in["jwt"])
+
+		if role == "" {
+			assert.NotContains(t, in, "role")
+		} else {
+			assert.Equal(t, role, in["role"])
+		}
+
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"auth": map[string]any{"client_token": "sometoken"},
+		})
+	}))
+
+	ctx := t.Context()
+
+	_, err := NewJWTAuth(role, nil)
+	require.Error(t, err)
+	_, err = NewJWTAuth(role, &JWTToken{})
+	require.Error(t, err)
+	_, err = NewJWTAuth(role, &JWTToken{FromFile: "foo", FromEnv: "bar"})
+	require.Error(t, err)
+
+	a, err := NewJWTAuth(role, &JWTToken{FromString: "header.payload.sig"})
+	require.NoError(t, err)
+
+	s, err := a.Login(ctx, client)
+	require.NoError(t, err)
+	assert.Equal(t, "sometoken", s.Auth.ClientToken)
+
+	t.Setenv("MY_JWT", "header.payload.sig")
+
+	mount = "oidc"
+	role = ""
+	a, err = NewJWTAuth(role, &JWTToken{FromEnv: "MY_JWT"}, WithJWTMountPath(mount))
+	require.NoError(t, err)
+
+	_, err = a.Login(ctx, client)
+	require.NoError(t, err)
+
+	a, err = NewJWTAuth(role, &JWTToken{FromFile: "/tmp/jwt"}, WithJWTMountPath(mount))
+	require.NoError(t, err)
+
+	a.(*jwtAuthMethod).fsys = fstest.MapFS{
+		"tmp/jwt": &fstest.MapFile{Data: []byte(" header.payload.sig\n")},
+	}
+
+	_, err = a.Login(ctx, client)
+	require.NoError(t, err)
+
+	a, err = NewJWTAuth(role, &JWTToken{FromEnv: "BOGUS_JWT"})
+	require.NoError(t, err)
+
+	_, err = a.Login(ctx, client)
+	require.Error(t, err)
+}
+
+func TestJWTAuthMethod_RelativePath(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("token files are read relative to the root, which isn't supported on Windows")
+	}
+
+	client := fakevault.FakeVault(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		in := map[string]any{}
+		_ = json.NewDecoder(r.Body).Decode(&in)
+
+		assert.Equal(t, "header.payload.sig", in["jwt"])
+
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"auth": map[string]any{"client_token": "sometoken"},
+		})
+	}))
+
+	t.Chdir(t.TempDir())
+
+	require.NoError(t, os.WriteFile("jwt", []byte("header.payload.sig\n"), 0o600))
+
+	token := &JWTToken{FromFile: "jwt"}
+
+	a, err := NewJWTAuth("ci", token)
+	require.NoError(t, err)
+
+	// the caller's token isn't modified
+	assert.Equal(t, "jwt", token.FromFile)
+
+	s, err := a.Login(t.Context(), client)
+	require.NoError(t, err)
+	assert.Equal(t, "sometoken", s.Auth.ClientToken)
+}
@@ -0,0 +1,113 @@
+package vaultauth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/hashicorp/vault/api/auth/kubernetes"
+)
+
+// DefaultServiceAccountTokenPath is the path where Kubernetes mounts the
+// (projected) service account token in a pod.
+const DefaultServiceAccountTokenPath = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+
+// NewKubernetesAuth authenticates to Vault with the Kubernetes auth method,
+// logging in to the given role with the pod's service account token, using
+// [github.com/hashicorp/vault/api/auth/kubernetes.NewKubernetesAuth].
+//
+// By default the token is read from [DefaultServiceAccountTokenPath] at each
+// login (rather than once, as the upstream auth method does), so that rotated
+// (projected) tokens are picked up. Use [WithServiceAccountTokenPath] or
+// [WithServiceAccountToken] to provide the token differently.
+//
+// Use [WithKubernetesMountPath] to specify the mount path for the Kubernetes
+// auth method. If not specified, the default is "kubernetes".
+//
+// See also https://developer.hashicorp.com/vault/docs/auth/kubernetes
+func NewKubernetesAuth(role string, opts ...KubernetesLoginOption) (api.AuthMethod, error) {
+	if role == "" {
+		return nil, errors.New("kubernetes auth method requires a role")
+	}
+
+	a := &kubernetesAuthMethod{
+		mountPath: "kubernetes",
+		role:      role,
+		tokenPath: DefaultServiceAccountTokenPath,
+	}
+
+	for _, opt := range opts {
+		if err := opt(a); err != nil {
+			return nil, fmt.Errorf("error from Kubernetes login option: %w", err)
+		}
+	}
+
+	return a, nil
+}
+
+type KubernetesLoginOption func(a *kubernetesAuthMethod) error
+
+func WithKubernetesMountPath(mountPath string) KubernetesLoginOption {
+	return func(a *kubernetesAuthMethod) error {
+		a.mountPath = mountPath
+
+		return nil
+	}
+}
+
+// WithServiceAccountTokenPath sets the path to read the service account token
+// from.
+func WithServiceAccountTokenPath(p string) KubernetesLoginOption {
+	return func(a *kubernetesAuthMethod) error {
+		if p == "" {
+			return errors.New("service account token path must not be empty")
+		}
+
+		a.tokenPath = absPath(p)
+
+		return nil
+	}
+}
+
+// WithServiceAccountToken sets the service account token directly, rather than
+// reading it from a file.
+func WithServiceAccountToken(jwt string) KubernetesLoginOption {
+	return func(a *kubernetesAuthMethod) error {
+		if jwt == "" {
+			return errors.New("service account token must not be empty")
+		}
+
+		a.token = jwt
+
+		return nil
+	}
+}
+
+type kubernetesAuthMethod struct {
+	mountPath string
+	role      string
+	tokenPath string
+	token     string
+}
+
+func (a *kubernetesAuthMethod) Login(ctx context.Context, client *api.Client) (*api.Secret, error) {
+	tokenOpt := kubernetes.WithServiceAccountTokenPath(a.tokenPath)
+	if a.token != "" {
+		tokenOpt = kubernetes.WithServiceAccountToken(a.token)
+	}
+
+	// the upstream auth method reads the token when it's created, so a new
+	// one is created for each login
+	auth, err := kubernetes.NewKubernetesAuth(a.role, kubernetes.WithMountPath(a.mountPath), tokenOpt)
+	if err != nil {
+		return nil, fmt.Errorf("kubernetes login failed: %w", err)
+	}
+
+	secret, err := auth.Login(ctx, client)
+	if err != nil {
+		return nil, fmt.Errorf("kubernetes login failed: %w", vaultFSError(err))
+	}
+
+	return secret, nil
+}
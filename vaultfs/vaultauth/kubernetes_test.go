@@ -0,0 +1,100 @@
+package vaultauth
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/hairyhenderson/go-fsimpl/internal/tests/fakevault"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKubernetesAuthMethod(t *testing.T) {
+	mount := "kubernetes"
+	jwt := "sa.jwt.one"
+
+	client := fakevault.FakeVault(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/auth/"+mount+"/login", r.URL.Path)
+
+		in := map[string]any{}
+		_ = json.NewDecoder(r.Body).Decode(&in)
+
+		assert.Equal(t, "myrole", in["role"])
+		assert.Equal(t, jwt, in["jwt"])
+
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"auth": map[string]any{"client_token": "sometoken"},
+		})
+	}))
+
+	ctx := t.Context()
+
+	_, err := NewKubernetesAuth("")
+	require.Error(t, err)
+	_, err = NewKubernetesAuth("myrole", WithServiceAccountTokenPath(""))
+	require.Error(t, err)
+	_, err = NewKubernetesAuth("myrole", WithServiceAccountToken(""))
+	require.Error(t, err)
+
+	tokenPath := filepath.Join(t.TempDir(), "token")
+	require.NoError(t, os.WriteFile(tokenPath, []byte(jwt), 0o600))
+
+	a, err := NewKubernetesAuth("myrole", WithServiceAccountTokenPath(tokenPath))
+	require.NoError(t, err)
+
+	s, err := a.Login(ctx, client)
+	require.NoError(t, err)
+	assert.Equal(t, "sometoken", s.Auth.ClientToken)
+
+	// the token is read again at each login
+	jwt = "sa.jwt.two"
+	require.NoError(t, os.WriteFile(tokenPath, []byte(jwt), 0o600))
+
+	_, err = a.Login(ctx, client)
+	require.NoError(t, err)
+
+	mount = "k8s"
+	a, err = NewKubernetesAuth("myrole", WithKubernetesMountPath(mount), WithServiceAccountToken(jwt))
+	require.NoError(t, err)
+
+	_, err = a.Login(ctx, client)
+	require.NoError(t, err)
+
+	a, err = NewKubernetesAuth("myrole", WithServiceAccountTokenPath(filepath.Join(t.TempDir(), "bogus")))
+	require.NoError(t, err)
+
+	_, err = a.Login(ctx, client)
+	require.Error(t, err)
+}
+
+func TestKubernetesAuthMethod_RelativePath(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("token files are read relative to the root, which isn't supported on Windows")
+	}
+
+	client := fakevault.FakeVault(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		in := map[string]any{}
+		_ = json.NewDecoder(r.Body).Decode(&in)
+
+		assert.Equal(t, "sa.jwt", in["jwt"])
+
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"auth": map[string]any{"client_token": "sometoken"},
+		})
+	}))
+
+	t.Chdir(t.TempDir())
+
+	require.NoError(t, os.WriteFile("token", []byte("sa.jwt"), 0o600))
+
+	a, err := NewKubernetesAuth("myrole", WithServiceAccountTokenPath("token"))
+	require.NoError(t, err)
+
+	s, err := a.Login(t.Context(), client)
+	require.NoError(t, err)
+	assert.Equal(t, "sometoken", s.Auth.ClientToken)
+}
@@ -0,0 +1,145 @@
+package vaultauth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"sync"
+
+	"github.com/hairyhenderson/go-fsimpl/internal/vaultwrap"
+	"github.com/hashicorp/vault/api"
+)
+
+// NewWrappedTokenAuth authenticates to Vault by unwrapping a response-wrapped
+// token (such as one created with "vault token create -wrap-ttl=5m", or an
+// AppRole login wrapped by a trusted orchestrator). The unwrapped response
+// must contain an auth token.
+//
+// Wrapping tokens can only be used once, so the unwrapped token is reused for
+// subsequent logins, and is not revoked by vaultfs. Use this with
+// [github.com/hairyhenderson/go-fsimpl/vaultfs.WithSessionFS] so that the
+// token is renewed for the lifetime of the filesystem.
+//
+// [WithWrappedTokenCreationPath] must be used to set the path that the
+// wrapping token must have been created by, to detect a token that has been
+// intercepted and replaced. Set it to "*" to accept tokens created by any path.
+//
+// See also https://developer.hashicorp.com/vault/docs/concepts/response-wrapping
+func NewWrappedTokenAuth(token *WrappingToken, opts ...WrappedTokenLoginOption) (api.AuthMethod, error) {
+	err := token.validate()
+	if err != nil {
+		return nil, fmt.Errorf("invalid wrapping token: %w", err)
+	}
+
+	a := &wrappedTokenAuthMethod{
+		fsys:  os.DirFS("/"),
+		token: token,
+	}
+
+	// copied so that the caller's token isn't modified
+	if token.FromFile != "" {
+		t := *token
+		t.FromFile = absPath(t.FromFile)
+		a.token = &t
+	}
+
+	for _, opt := range opts {
+		if err := opt(a); err != nil {
+			return nil, fmt.Errorf("error from wrapped token login option: %w", err)
+		}
+	}
+
+	if a.creationPath == "" {
+		return nil, fmt.Errorf("wrapped token auth method requires a creation path (or %q to accept any)",
+			vaultwrap.AnyCreationPath)
+	}
+
+	return a, nil
+}
+
+type WrappedTokenLoginOption func(a *wrappedTokenAuthMethod) error
+
+// WithWrappedTokenCreationPath sets the path that the wrapping token must have
+// been created by (for example "auth/token/create"). Patterns are supported,
+// with the same syntax as [path.Match]. Use "*" to accept tokens created by any
+// path, skipping the check.
+func WithWrappedTokenCreationPath(creationPath string) WrappedTokenLoginOption {
+	return func(a *wrappedTokenAuthMethod) error {
+		a.creationPath = creationPath
+
+		return nil
+	}
+}
+
+// WrappingToken is a struct that allows you to specify where your application
+// is storing the wrapping token.
+type WrappingToken struct {
+	FromFile   string
+	FromString string
+	FromEnv    string
+}
+
+func (token *WrappingToken) validate() error {
+	if token == nil {
+		return errors.New("wrapped token auth method requires a wrapping token")
+	}
+
+	return validateTokenSource("wrapping token", token.FromFile, token.FromString, token.FromEnv)
+}
+
+type wrappedTokenAuthMethod struct {
+	fsys         fs.FS
+	token        *WrappingToken
+	secret       *api.Secret
+	creationPath string
+	mu           sync.Mutex
+}
+
+func (a *wrappedTokenAuthMethod) Login(ctx context.Context, client *api.Client) (*api.Secret, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.secret != nil {
+		return a.secret, nil
+	}
+
+	wrappingToken := ""
+
+	switch {
+	case a.token.FromFile != "":
+		t, err := readTokenFile(a.fsys, a.token.FromFile)
+		if err != nil {
+			return nil, fmt.Errorf("error reading wrapping token from file: %w", err)
+		}
+
+		wrappingToken = t
+	case a.token.FromEnv != "":
+		wrappingToken = os.Getenv(a.token.FromEnv)
+		if wrappingToken == "" {
+			return nil, fmt.Errorf("wrapping token environment variable %q not set", a.token.FromEnv)
+		}
+	default:
+		wrappingToken = a.token.FromString
+	}
+
+	secret, err := vaultwrap.Unwrap(ctx, client, wrappingToken, a.creationPath)
+	if err != nil {
+		return nil, fmt.Errorf("wrapped token login failed: %w", vaultFSError(err))
+	}
+
+	if secret.Auth == nil || secret.Auth.ClientToken == "" {
+		return nil, errors.New("wrapped token login failed: wrapped response contains no auth token")
+	}
+
+	a.secret = secret
+
+	return secret, nil
+}
+
+// Logout implements the vaultfs.authLogouter interface, as the unwrapped token
+// can't be acquired again once revoked.
+func (a *wrappedTokenAuthMethod) Logout(_ context.Context, client *api.Client) {
+	client.ClearToken()
+}
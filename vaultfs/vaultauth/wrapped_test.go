@@ -0,0 +1,128 @@
+package vaultauth
+
+import (
+	"os"
+	"runtime"
+	"testing"
+	"testing/fstest"
+
+	"github.com/hairyhenderson/go-fsimpl/internal/tests/fakevault"
+	"github.com/hairyhenderson/go-fsimpl/internal/vaultwrap"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWrappedTokenAuthMethod(t *testing.T) {
+	client := fakevault.FakeVault(t, fakevault.WrappingHandler(t, "auth/token/create",
+		map[string]map[string]any{
+			"wrap1": {"auth": map[string]any{"client_token": "realtoken1", "renewable": true}},
+			"wrap2": {"auth": map[string]any{"client_token": "realtoken2"}},
+			"wrap3": {"data": map[string]any{"foo": "bar"}},
+			"wrap4": {"auth": map[string]any{"client_token": "realtoken4"}},
+		}))
+
+	ctx := t.Context()
+
+	_, err := NewWrappedTokenAuth(nil)
+	require.Error(t, err)
+	_, err = NewWrappedTokenAuth(&WrappingToken{})
+	require.Error(t, err)
+	_, err = NewWrappedTokenAuth(&WrappingToken{FromString: "wrap1", FromEnv: "WRAP"})
+	require.Error(t, err)
+
+	// the creation path must be given
+	_, err = NewWrappedTokenAuth(&WrappingToken{FromString: "wrap1"})
+	require.Error(t, err)
+
+	a, err := NewWrappedTokenAuth(&WrappingToken{FromString: "wrap1"},
+		WithWrappedTokenCreationPath("auth/token/create"))
+	require.NoError(t, err)
+
+	s, err := a.Login(ctx, client)
+	require.NoError(t, err)
+	assert.Equal(t, "realtoken1", s.Auth.ClientToken)
+
+	// the client's token isn't changed by the auth method
+	assert.Empty(t, client.Token())
+
+	// the wrapping token can only be used once, so the unwrapped token is
+	// reused
+	s, err = a.Login(ctx, client)
+	require.NoError(t, err)
+	assert.Equal(t, "realtoken1", s.Auth.ClientToken)
+
+	t.Run("creation path mismatch", func(t *testing.T) {
+		a, err := NewWrappedTokenAuth(&WrappingToken{FromString: "wrap2"},
+			WithWrappedTokenCreationPath("auth/approle/login"))
+		require.NoError(t, err)
+
+		_, err = a.Login(ctx, client)
+		require.ErrorIs(t, err, vaultwrap.ErrCreationPath)
+
+		// the token wasn't unwrapped, so it can still be used
+		a, err = NewWrappedTokenAuth(&WrappingToken{FromFile: "/run/wrap"},
+			WithWrappedTokenCreationPath("auth/token/*"))
+		require.NoError(t, err)
+
+		a.(*wrappedTokenAuthMethod).fsys = fstest.MapFS{
+			"run/wrap": &fstest.MapFile{Data: []byte("wrap2\n")},
+		}
+
+		s, err := a.Login(ctx, client)
+		require.NoError(t, err)
+		assert.Equal(t, "realtoken2", s.Auth.ClientToken)
+	})
+
+	t.Run("no auth in wrapped response", func(t *testing.T) {
+		t.Setenv("WRAP", "wrap3")
+
+		a, err := NewWrappedTokenAuth(&WrappingToken{FromEnv: "WRAP"},
+			WithWrappedTokenCreationPath("auth/token/create"))
+		require.NoError(t, err)
+
+		_, err = a.Login(ctx, client)
+		require.ErrorContains(t, err, "contains no auth token")
+	})
+
+	t.Run("already unwrapped", func(t *testing.T) {
+		a, err := NewWrappedTokenAuth(&WrappingToken{FromString: "wrap1"},
+			WithWrappedTokenCreationPath("auth/token/create"))
+		require.NoError(t, err)
+
+		_, err = a.Login(ctx, client)
+		require.Error(t, err)
+	})
+
+	t.Run("any creation path", func(t *testing.T) {
+		a, err := NewWrappedTokenAuth(&WrappingToken{FromString: "wrap4"},
+			WithWrappedTokenCreationPath(vaultwrap.AnyCreationPath))
+		require.NoError(t, err)
+
+		s, err := a.Login(ctx, client)
+		require.NoError(t, err)
+		assert.Equal(t, "realtoken4", s.Auth.ClientToken)
+	})
+}
+
+func TestWrappedTokenAuthMethod_RelativePath(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("token files are read relative to the root, which isn't supported on Windows")
+	}
+
+	client := fakevault.FakeVault(t, fakevault.WrappingHandler(t, "auth/token/create",
+		map[string]map[string]any{
+			"wrap1": {"auth": map[string]any{"client_token": "realtoken1"}},
+		}))
+
+	t.Chdir(t.TempDir())
+
+	require.NoError(t, os.WriteFile("wrap", []byte("wrap1\n"), 0o600))
+
+	a, err := NewWrappedTokenAuth(&WrappingToken{FromFile: "wrap"},
+		WithWrappedTokenCreationPath("auth/token/create"))
+	require.NoError(t, err)
+
+	s, err := a.Login(t.Context(), client)
+	require.NoError(t, err)
+	assert.Equal(t, "realtoken1", s.Auth.ClientToken)
+}
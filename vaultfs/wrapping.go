@@ -0,0 +1,97 @@
+package vaultfs
+
+import (
+	"fmt"
+	"io/fs"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/hairyhenderson/go-fsimpl/internal/vaultwrap"
+	"github.com/hashicorp/vault/api"
+)
+
+const (
+	// unwrapPath is the API path for unwrapping response-wrapped data
+	unwrapPath = "/v1/sys/wrapping/unwrap"
+
+	// wrappingTokenParam is the URL query parameter holding the wrapping
+	// token to unwrap
+	wrappingTokenParam = "token"
+
+	// creationPathParam is the URL query parameter holding the path that the
+	// wrapping token must have been created by (or "*" to accept any path)
+	creationPathParam = "creation_path"
+)
+
+// isUnwrap reports whether the file reads response-wrapped data
+func (f *vaultFile) isUnwrap() bool {
+	return path.Clean(f.u.Path) == unwrapPath
+}
+
+// takeWrappingToken moves the wrapping token out of the file's URL and name,
+// so that it isn't leaked in error messages
+func (f *vaultFile) takeWrappingToken() {
+	q := f.u.Query()
+
+	f.wrappingToken = q.Get(wrappingTokenParam)
+	if f.wrappingToken == "" {
+		return
+	}
+
+	q.Del(wrappingTokenParam)
+
+	u := *f.u
+	u.RawQuery = q.Encode()
+	f.u = &u
+
+	name, rest, ok := strings.Cut(f.name, "?")
+	if !ok {
+		return
+	}
+
+	rawQuery, fragment, hasFragment := strings.Cut(rest, "#")
+
+	nq, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		// the name can't be safely redacted, so omit its query entirely
+		f.name = name
+
+		return
+	}
+
+	nq.Del(wrappingTokenParam)
+
+	if enc := nq.Encode(); enc != "" {
+		name += "?" + enc
+	}
+
+	if hasFragment {
+		name += "#" + fragment
+	}
+
+	f.name = name
+}
+
+// unwrap reads the response wrapped by the wrapping token given in the URL,
+// after verifying the token's creation path. The wrapping token authenticates
+// the request, so no login is needed.
+func (f *vaultFile) unwrap() (*api.Secret, error) {
+	if f.wrappingToken == "" {
+		return nil, fmt.Errorf("a wrapping token must be provided with the %q parameter: %w",
+			wrappingTokenParam, fs.ErrInvalid)
+	}
+
+	creationPath := f.u.Query().Get(creationPathParam)
+	if creationPath == "" {
+		return nil, fmt.Errorf("the wrapping token's creation path must be provided with the %q parameter (or %q to accept any): %w",
+			creationPathParam, vaultwrap.AnyCreationPath, fs.ErrInvalid)
+	}
+
+	secret, err := vaultwrap.Unwrap(f.ctx, f.client.Client, f.wrappingToken, creationPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unwrap response: %w", err)
+	}
+
+	return secret, nil
+}
@@ -0,0 +1,62 @@
+package vaultfs
+
+import (
+	"io/fs"
+	"testing"
+
+	"github.com/hairyhenderson/go-fsimpl/internal/tests"
+	"github.com/hairyhenderson/go-fsimpl/internal/tests/fakevault"
+	"github.com/hairyhenderson/go-fsimpl/internal/vaultwrap"
+	"github.com/hairyhenderson/go-fsimpl/vaultfs/vaultauth"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUnwrap(t *testing.T) {
+	handler := fakevault.WrappingHandler(t, "secret/data/app/db", map[string]map[string]any{
+		"wrap1": {"data": map[string]any{"password": "hunter2"}},
+		"wrap2": {"data": map[string]any{"password": "swordfish"}},
+	})
+
+	client := newRefCountedClient(fakevault.FakeVault(t, handler))
+
+	// no login is needed, so the auth method must not be used
+	fsys := vaultauth.WithAuthMethod(vaultauth.NewTokenAuth("unused"),
+		newWithVaultClient(tests.MustURL("vault:///sys/wrapping/"), client))
+
+	f, err := fsys.Open("unwrap?token=wrap1&creation_path=secret/data/app/db#password")
+	require.NoError(t, err)
+
+	fi, err := f.Stat()
+	require.NoError(t, err)
+	assert.Equal(t, int64(7), fi.Size())
+
+	b := make([]byte, 7)
+	_, err = f.Read(b)
+	require.NoError(t, err)
+	assert.Equal(t, "hunter2", string(b))
+	require.NoError(t, f.Close())
+
+	assert.Empty(t, client.Token())
+
+	// wrapping tokens are single-use
+	_, err = fs.ReadFile(fsys, "unwrap?token=wrap1&creation_path=secret/data/app/db")
+	require.Error(t, err)
+	assert.NotContains(t, err.Error(), "wrap1")
+
+	_, err = fs.Stat(fsys, "unwrap?token=wrap2&creation_path=secret/data/other")
+	require.ErrorIs(t, err, vaultwrap.ErrCreationPath)
+	assert.NotContains(t, err.Error(), "wrap2")
+
+	// the creation path must be given, unless explicitly skipped
+	_, err = fs.ReadFile(fsys, "unwrap?token=wrap2")
+	require.ErrorIs(t, err, fs.ErrInvalid)
+	assert.NotContains(t, err.Error(), "wrap2")
+
+	b, err = fs.ReadFile(fsys, "unwrap?token=wrap2&creation_path=*")
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"password":"swordfish"}`, string(b))
+
+	_, err = fs.ReadFile(fsys, "unwrap?creation_path=*")
+	require.ErrorIs(t, err, fs.ErrInvalid)
+}